@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/lolwierd/weatherboy/be/internal/fetch"
+	"github.com/lolwierd/weatherboy/be/internal/logger"
+)
+
+var (
+	source = flag.String("source", "", "source to backfill or reprocess")
+	from   = flag.String("from", "", "start of the range, as a date (2006-01-02) or RFC3339 time")
+	to     = flag.String("to", "", "end of the range (exclusive), as a date or RFC3339 time; defaults to now")
+)
+
+// parseFlagTime parses a date or an RFC3339 time. Dates are midnight IST.
+func parseFlagTime(name, s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	ist, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, ist)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("-%s %q: want a date or RFC3339 time", name, s)
+	}
+	return t, nil
+}
+
+func runBackfill() error {
+	if *source == "" || *from == "" {
+		return fmt.Errorf("backfill needs -source and -from")
+	}
+	start, err := parseFlagTime("from", *from)
+	if err != nil {
+		return err
+	}
+	end := time.Now()
+	if *to != "" {
+		if end, err = parseFlagTime("to", *to); err != nil {
+			return err
+		}
+	}
+	logger.Info.Printf("backfilling %s from %s to %s", *source, start.Format(time.RFC3339), end.Format(time.RFC3339))
+	return fetch.Backfill(context.Background(), *source, start, end)
+}
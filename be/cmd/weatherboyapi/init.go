@@ -6,7 +6,8 @@ import (
 
 	"github.com/lolwierd/weatherboy/be/internal/config"
 	"github.com/lolwierd/weatherboy/be/internal/db"
-	"github.com/lolwierd/weatherboy/be/internal/scheduler"
+	"github.com/lolwierd/weatherboy/be/internal/geo"
+	"github.com/lolwierd/weatherboy/be/internal/logger"
 )
 
 func init() {
@@ -23,6 +24,16 @@ func init() {
 			os.Getenv("POSTGRES_DB"),
 			os.Getenv("POSTGRES_PORT")),
 	)
+}
 
-	scheduler.Start()
+// loadDistricts loads the district boundaries the API resolves coordinates
+// and serves district shapes from, and stops the API from starting without
+// them.
+func loadDistricts() {
+	if config.DistrictsFile == "" {
+		logger.Error.Fatalln("DISTRICTS_GEOJSON must name a GeoJSON file of IMD district boundaries")
+	}
+	if err := geo.LoadDistricts(config.DistrictsFile); err != nil {
+		logger.Error.Fatalln("load district boundaries:", err)
+	}
 }
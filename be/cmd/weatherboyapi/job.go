@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/lolwierd/weatherboy/be/internal/logger"
+	"github.com/lolwierd/weatherboy/be/internal/scheduler"
+)
+
+var dryRun = flag.Bool("dry-run", false, "with -run fetch_<job>_once, fetch and parse but store nothing and print the parsed rows; with -run retention, print the rows that would be removed")
+
+// jobFromRunMode returns the job a `fetch_<job>_once` run mode names.
+func jobFromRunMode(mode string) (string, bool) {
+	name, ok := strings.CutPrefix(mode, "fetch_")
+	if !ok {
+		return "", false
+	}
+	return strings.CutSuffix(name, "_once")
+}
+
+// runJobOnce runs a registered job once, as the admin API does.
+func runJobOnce(name string) error {
+	scheduler.Load()
+	if *dryRun {
+		results, err := scheduler.DryRun(context.Background(), name)
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if encErr := enc.Encode(results); encErr != nil {
+			return encErr
+		}
+		return err
+	}
+	run, err := scheduler.RunNow(context.Background(), name)
+	if err != nil {
+		return err
+	}
+	logger.Info.Printf("job %s %s rows=%d bytes=%d", name, run.Status, run.RowsWritten, run.Bytes)
+	return nil
+}
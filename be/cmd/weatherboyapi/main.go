@@ -1,32 +1,85 @@
 package main
 
 import (
-	"context"
 	"flag"
+	_ "time/tzdata" // location timezones and CRON_TZ specs on images without zoneinfo
 
-	"github.com/lolwierd/weatherboy/be/internal/fetch"
+	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/events"
 	"github.com/lolwierd/weatherboy/be/internal/healthcheck"
 	"github.com/lolwierd/weatherboy/be/internal/logger"
 	"github.com/lolwierd/weatherboy/be/internal/router"
+	"github.com/lolwierd/weatherboy/be/internal/scheduler"
 	"github.com/lolwierd/weatherboy/be/internal/shutdown"
 )
 
-var runMode = flag.String("run", "server", "run mode")
+var runMode = flag.String("run", "all", "run mode: all (API and scheduler), server (API only), worker (scheduler only), backfill, reprocess, retention, migrate up|down|status, fetch_<job>_once (e.g. fetch_nowcast_once)")
 
 func main() {
 	flag.Parse()
 
 	switch *runMode {
+	case "all":
+		ensureSchema()
+		loadDistricts()
+		logger.Info.Println("Starting API server and scheduler.")
+		healthcheck.Healthcheck()
+		scheduler.Start()
+		listenForEvents()
+		router.StartServer()
+		shutdown.GracefulStop()
 	case "server":
+		ensureSchema()
+		loadDistricts()
 		logger.Info.Println("Starting API server mode.")
 		healthcheck.Healthcheck()
+		scheduler.Load()
+		listenForEvents()
 		router.StartServer()
 		shutdown.GracefulStop()
-	case "fetch_bulletin_once":
-		if err := fetch.FetchBulletinOnce(context.Background()); err != nil {
-			logger.Error.Println("fetch bulletin:", err)
+	case "worker":
+		ensureSchema()
+		logger.Info.Println("Starting worker mode.")
+		healthcheck.Healthcheck()
+		scheduler.Start()
+		shutdown.GracefulStop()
+	case "migrate":
+		if err := runMigrate(flag.Arg(0)); err != nil {
+			logger.Error.Fatalln("migrate:", err)
+		}
+	case "backfill":
+		if err := runBackfill(); err != nil {
+			logger.Error.Println("backfill:", err)
+		}
+	case "reprocess":
+		if err := runReprocess(); err != nil {
+			logger.Error.Println("reprocess:", err)
+		}
+	case "retention":
+		if err := runRetention(); err != nil {
+			logger.Error.Println("retention:", err)
 		}
 	default:
-		logger.Error.Println("unknown run mode", *runMode)
+		name, ok := jobFromRunMode(*runMode)
+		if !ok {
+			logger.Error.Println("unknown run mode", *runMode)
+			return
+		}
+		if err := runJobOnce(name); err != nil {
+			logger.Error.Println("fetch", name+":", err)
+		}
+	}
+}
+
+// listenForEvents relays the stream events other replicas publish through
+// NOTIFY to the streams served here, until shutdown.
+func listenForEvents() {
+	if !config.EventsNotify {
+		return
 	}
+	shutdown.WG.Add(1)
+	go func() {
+		defer shutdown.WG.Done()
+		events.Listen(shutdown.Context())
+	}()
 }
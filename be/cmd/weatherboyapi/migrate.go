@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/lolwierd/weatherboy/be/internal/logger"
+	"github.com/lolwierd/weatherboy/be/internal/migrate"
+)
+
+var autoMigrate = flag.Bool("auto-migrate", false, "apply pending migrations before starting the API or scheduler")
+
+// runMigrate runs `-run migrate up|down|status`.
+func runMigrate(cmd string) error {
+	ctx := context.Background()
+	switch cmd {
+	case "up":
+		return migrate.Up(ctx)
+	case "down":
+		return migrate.Down(ctx)
+	case "status":
+		s, err := migrate.GetStatus(ctx)
+		if err != nil {
+			return err
+		}
+		logger.Info.Printf("schema version %d (dirty=%t), latest %d", s.Current, s.Dirty, s.Latest)
+		for _, m := range s.Pending {
+			logger.Info.Printf("pending %d_%s", m.Version, m.Name)
+		}
+		return nil
+	default:
+		return fmt.Errorf("usage: -run migrate up|down|status")
+	}
+}
+
+// ensureSchema applies pending migrations when -auto-migrate is set and
+// refuses to start when the schema is still behind.
+func ensureSchema() {
+	ctx := context.Background()
+	if *autoMigrate {
+		if err := migrate.Up(ctx); err != nil {
+			logger.Error.Fatalln("auto-migrate:", err)
+		}
+	}
+	if err := migrate.Check(ctx); err != nil {
+		logger.Error.Fatalln("schema check:", err)
+	}
+}
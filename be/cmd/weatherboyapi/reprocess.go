@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/lolwierd/weatherboy/be/internal/fetch"
+	"github.com/lolwierd/weatherboy/be/internal/logger"
+)
+
+var (
+	since = flag.String("since", "", "reprocess raw payloads fetched at or after this date (2006-01-02) or RFC3339 time")
+	force = flag.Bool("force", false, "reprocess payloads already parsed by the current parser version")
+)
+
+func runReprocess() error {
+	if *source == "" || *since == "" {
+		return fmt.Errorf("reprocess needs -source and -since")
+	}
+	start, err := parseFlagTime("since", *since)
+	if err != nil {
+		return err
+	}
+	logger.Info.Printf("reprocessing %s since %s", *source, start.Format(time.RFC3339))
+	return fetch.Reprocess(context.Background(), *source, start, *force)
+}
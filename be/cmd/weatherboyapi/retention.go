@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/logger"
+	"github.com/lolwierd/weatherboy/be/internal/retention"
+)
+
+// runRetention applies the retention policies once, even when the scheduled
+// job is disabled. With -dry-run it prints what would be removed instead.
+func runRetention() error {
+	r, err := config.LoadRetention(config.SourcesFile())
+	if err != nil {
+		return err
+	}
+	reports, err := retention.Run(context.Background(), r, time.Now(), *dryRun)
+	if *dryRun {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if encErr := enc.Encode(reports); encErr != nil {
+			return encErr
+		}
+		return err
+	}
+	for _, rep := range reports {
+		logger.Info.Printf("retention %s: removed %d rows before %s", rep.Table, rep.Rows, rep.Before.Format(time.RFC3339))
+	}
+	return err
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
 	"sync"
 
 	"github.com/joho/godotenv"
@@ -15,6 +16,37 @@ var (
 	DataDir = "data"
 	// OpenAIAPIKey is the API key for the OpenAI API.
 	OpenAIAPIKey = ""
+	// AdminToken is the bearer token the admin API requires. The admin API
+	// rejects every request while it is empty.
+	AdminToken = ""
+	// BulletinArchiveURL is the URL of an archived bulletin PDF, with `{date}`
+	// standing for the bulletin date. Bulletin backfill needs it set.
+	BulletinArchiveURL = ""
+	// BulletinArchiveDateFormat is the Go layout `{date}` is formatted with.
+	BulletinArchiveDateFormat = "2006-01-02"
+	// BlobStore selects where raw downloads are stored: "local" or "s3".
+	BlobStore = "local"
+	// BlobDir is the directory of the local blob store. Empty means
+	// DataDir/blobs.
+	BlobDir = ""
+	// S3Endpoint is the URL of the S3-compatible service of the s3 blob
+	// store, e.g. http://localhost:9000 for MinIO.
+	S3Endpoint = ""
+	// S3Region is the region requests to the s3 blob store are signed for.
+	S3Region = "us-east-1"
+	// S3Bucket is the bucket of the s3 blob store.
+	S3Bucket = ""
+	// S3AccessKeyID and S3SecretAccessKey are the credentials of the s3 blob
+	// store.
+	S3AccessKeyID     = ""
+	S3SecretAccessKey = ""
+	// DistrictsFile is the GeoJSON FeatureCollection of IMD district
+	// boundaries, keyed by IMD district id. The API does not start without
+	// it.
+	DistrictsFile = ""
+	// EventsNotify sends stream events through Postgres NOTIFY so every
+	// replica's subscribers receive them, not just the publishing replica's.
+	EventsNotify = false
 )
 
 // LoadEnv loads environment variables from a .env file.
@@ -30,4 +62,44 @@ func LoadEnv() {
 	if k := os.Getenv("OPENAI_API_KEY"); k != "" {
 		OpenAIAPIKey = k
 	}
+	if t := os.Getenv("ADMIN_TOKEN"); t != "" {
+		AdminToken = t
+	}
+	if u := os.Getenv("BULLETIN_ARCHIVE_URL"); u != "" {
+		BulletinArchiveURL = u
+	}
+	if f := os.Getenv("BULLETIN_ARCHIVE_DATE_FORMAT"); f != "" {
+		BulletinArchiveDateFormat = f
+	}
+	if s := os.Getenv("BLOB_STORE"); s != "" {
+		BlobStore = s
+	}
+	if d := os.Getenv("BLOB_DIR"); d != "" {
+		BlobDir = d
+	}
+	if e := os.Getenv("S3_ENDPOINT"); e != "" {
+		S3Endpoint = e
+	}
+	if r := os.Getenv("S3_REGION"); r != "" {
+		S3Region = r
+	}
+	if b := os.Getenv("S3_BUCKET"); b != "" {
+		S3Bucket = b
+	}
+	if k := os.Getenv("S3_ACCESS_KEY_ID"); k != "" {
+		S3AccessKeyID = k
+	}
+	if k := os.Getenv("S3_SECRET_ACCESS_KEY"); k != "" {
+		S3SecretAccessKey = k
+	}
+	if f := os.Getenv("DISTRICTS_GEOJSON"); f != "" {
+		DistrictsFile = f
+	}
+	if n := os.Getenv("EVENTS_NOTIFY"); n != "" {
+		b, err := strconv.ParseBool(n)
+		if err != nil {
+			log.Println("Invalid EVENTS_NOTIFY, want true or false:", n)
+		}
+		EventsNotify = b
+	}
 }
@@ -1,27 +1,283 @@
 package config
 
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultAWSNearestK is the number of nearby AWS/ARG stations used when a
+	// location does not set AWSNearestK.
+	DefaultAWSNearestK = 3
+	// DefaultAWSRadiusKM bounds the nearby AWS/ARG station search when a
+	// location does not set AWSRadiusKM.
+	DefaultAWSRadiusKM = 50.0
+)
+
 type Location struct {
-	Name       string
-	Lat, Lon   float64
+	// Name is the slug the API and source targets address the location by.
+	Name string
+	// DisplayName is the human-readable name of the location.
+	DisplayName string
+	Lat, Lon    float64
+	// DistrictID is the IMD district id used for nowcasts and district
+	// warnings. Zero means the location has neither.
 	DistrictID int
+	// PdfSlug is the file name of the IMD state bulletin covering the
+	// location.
 	PdfSlug    string
 	RadarCodes []string
+	// RiverBasinID is the id passed to the IMD basin QPF API.
+	RiverBasinID int
+	// SubBasinID selects a single sub-basin (the QPF row `Obj_Id`) within
+	// RiverBasinID. Zero means any sub-basin of the basin.
+	SubBasinID int
+	// AWSStationIDs lists AWS/ARG station ids always observed for the
+	// location, in addition to the nearest catalogued stations.
+	AWSStationIDs []string
+	// AWSNearestK and AWSRadiusKM bound the nearest active AWS/ARG stations
+	// used for the location. Zero values fall back to the defaults.
+	AWSNearestK int
+	AWSRadiusKM float64
+	// AWSScope is the state or district swept in bulk to build the observation
+	// network around the location.
+	AWSScope AWSScope
+	// Timezone is the IANA zone the location's local day is reckoned in.
+	Timezone string
 }
 
-// Locations lists the supported cities for Weather Boy.
-var Locations = []Location{
-	{Name: "vadodara", Lat: 22.30, Lon: 73.20, DistrictID: 244, PdfSlug: "gujarat.pdf", RadarCodes: []string{"baroda", "ahmedabad"}},
-	{Name: "mumbai", Lat: 19.08, Lon: 72.88, DistrictID: 0, PdfSlug: "maharashtra.pdf", RadarCodes: []string{"mumbai"}},
-	{Name: "thane", Lat: 19.22, Lon: 72.97, DistrictID: 0, PdfSlug: "maharashtra.pdf", RadarCodes: []string{"mumbai"}},
-	{Name: "pune", Lat: 18.52, Lon: 73.85, DistrictID: 0, PdfSlug: "maharashtra.pdf", RadarCodes: []string{"mumbai"}},
+// AWSScope names a state and/or district to fetch every AWS/ARG station of.
+type AWSScope struct {
+	State    string
+	District string
+}
+
+func (s AWSScope) String() string {
+	if s.District == "" {
+		return s.State
+	}
+	return s.State + "/" + s.District
+}
+
+// NearestK returns the number of nearby AWS/ARG stations to use.
+func (l Location) NearestK() int {
+	if l.AWSNearestK > 0 {
+		return l.AWSNearestK
+	}
+	return DefaultAWSNearestK
+}
+
+// RadiusKM returns the search radius for nearby AWS/ARG stations.
+func (l Location) RadiusKM() float64 {
+	if l.AWSRadiusKM > 0 {
+		return l.AWSRadiusKM
+	}
+	return DefaultAWSRadiusKM
+}
+
+// DefaultTimezone is the timezone of a location that does not set one.
+const DefaultTimezone = "Asia/Kolkata"
+
+// Zone returns the time zone of l, falling back to DefaultTimezone and then
+// UTC when Timezone is unset or unknown.
+func (l Location) Zone() *time.Location {
+	for _, name := range []string{l.Timezone, DefaultTimezone} {
+		if name == "" {
+			continue
+		}
+		if z, err := time.LoadLocation(name); err == nil {
+			return z
+		}
+	}
+	return time.UTC
+}
+
+// DefaultLocations are the locations served until the location table is
+// loaded. The table is seeded with them.
+var DefaultLocations = []Location{
+	{Name: "vadodara", DisplayName: "Vadodara", Lat: 22.30, Lon: 73.20, DistrictID: 244, PdfSlug: "gujarat.pdf", RadarCodes: []string{"baroda", "ahmedabad"}, RiverBasinID: 1, AWSStationIDs: []string{"NDL"}, AWSScope: AWSScope{State: "GUJARAT"}, Timezone: DefaultTimezone},
+	{Name: "mumbai", DisplayName: "Mumbai", Lat: 19.08, Lon: 72.88, DistrictID: 0, PdfSlug: "maharashtra.pdf", RadarCodes: []string{"mumbai"}, AWSScope: AWSScope{State: "MAHARASHTRA"}, Timezone: DefaultTimezone},
+	{Name: "thane", DisplayName: "Thane", Lat: 19.22, Lon: 72.97, DistrictID: 0, PdfSlug: "maharashtra.pdf", RadarCodes: []string{"mumbai"}, AWSScope: AWSScope{State: "MAHARASHTRA"}, Timezone: DefaultTimezone},
+	{Name: "pune", DisplayName: "Pune", Lat: 18.52, Lon: 73.85, DistrictID: 0, PdfSlug: "maharashtra.pdf", RadarCodes: []string{"mumbai"}, AWSScope: AWSScope{State: "MAHARASHTRA"}, Timezone: DefaultTimezone},
+}
+
+var (
+	locationsMu sync.RWMutex
+	locations   = DefaultLocations
+)
+
+// Locations returns the supported locations.
+func Locations() []Location {
+	locationsMu.RLock()
+	defer locationsMu.RUnlock()
+	return locations
+}
+
+// SetLocations replaces the supported locations, e.g. with the rows of the
+// location table. It reports whether they changed.
+func SetLocations(ls []Location) bool {
+	locationsMu.Lock()
+	defer locationsMu.Unlock()
+	if slices.EqualFunc(locations, ls, equalLocation) {
+		return false
+	}
+	locations = ls
+	return true
+}
+
+func equalLocation(a, b Location) bool {
+	return a.Name == b.Name && a.DisplayName == b.DisplayName &&
+		a.Lat == b.Lat && a.Lon == b.Lon &&
+		a.DistrictID == b.DistrictID && a.PdfSlug == b.PdfSlug &&
+		slices.Equal(a.RadarCodes, b.RadarCodes) &&
+		a.RiverBasinID == b.RiverBasinID && a.SubBasinID == b.SubBasinID &&
+		slices.Equal(a.AWSStationIDs, b.AWSStationIDs) &&
+		a.AWSNearestK == b.AWSNearestK && a.AWSRadiusKM == b.AWSRadiusKM &&
+		a.AWSScope == b.AWSScope && a.Timezone == b.Timezone
 }
 
 // LocationByName returns the Location matching name.
 func LocationByName(name string) (Location, bool) {
-	for _, l := range Locations {
+	for _, l := range Locations() {
 		if l.Name == name {
 			return l, true
 		}
 	}
 	return Location{}, false
 }
+
+// locationSlug is the form of a location name.
+var locationSlug = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// bulletinProduct is the form of an IMD state bulletin file name.
+var bulletinProduct = regexp.MustCompile(`^[a-z_]+\.pdf$`)
+
+// India's bounding box; every IMD product covers a point inside it.
+const (
+	minLat, maxLat = 6.0, 38.0
+	minLon, maxLon = 68.0, 98.0
+)
+
+// ValidateLocation checks the fields of l that can be checked without
+// calling IMD.
+func ValidateLocation(l Location) error {
+	var errs []error
+	if len(l.Name) > 64 || !locationSlug.MatchString(l.Name) {
+		errs = append(errs, fmt.Errorf("invalid slug %q: want lowercase letters and digits separated by dashes", l.Name))
+	}
+	if l.DisplayName == "" {
+		errs = append(errs, fmt.Errorf("missing name"))
+	}
+	if l.Lat < minLat || l.Lat > maxLat || l.Lon < minLon || l.Lon > maxLon {
+		errs = append(errs, fmt.Errorf("lat/lon %g,%g is outside India", l.Lat, l.Lon))
+	}
+	if l.DistrictID < 0 {
+		errs = append(errs, fmt.Errorf("negative district id %d", l.DistrictID))
+	}
+	if l.PdfSlug != "" && !bulletinProduct.MatchString(l.PdfSlug) {
+		errs = append(errs, fmt.Errorf("invalid bulletin product %q: want e.g. gujarat.pdf", l.PdfSlug))
+	}
+	for _, code := range l.RadarCodes {
+		if code == "" {
+			errs = append(errs, fmt.Errorf("empty radar code"))
+		}
+	}
+	if l.RiverBasinID < 0 || l.SubBasinID < 0 {
+		errs = append(errs, fmt.Errorf("negative river basin id"))
+	}
+	if l.SubBasinID != 0 && l.RiverBasinID == 0 {
+		errs = append(errs, fmt.Errorf("sub-basin %d without a river basin", l.SubBasinID))
+	}
+	for _, id := range l.AWSStationIDs {
+		if id == "" {
+			errs = append(errs, fmt.Errorf("empty aws/arg station id"))
+		}
+	}
+	if l.AWSNearestK < 0 || l.AWSRadiusKM < 0 {
+		errs = append(errs, fmt.Errorf("negative aws/arg nearest k or radius"))
+	}
+	if l.AWSScope.District != "" && l.AWSScope.State == "" {
+		errs = append(errs, fmt.Errorf("aws/arg district scope without a state"))
+	}
+	if l.Timezone == "" {
+		errs = append(errs, fmt.Errorf("missing timezone"))
+	} else if _, err := time.LoadLocation(l.Timezone); err != nil {
+		errs = append(errs, fmt.Errorf("timezone %q: %w", l.Timezone, err))
+	}
+	return errors.Join(errs...)
+}
+
+// NowcastLocations returns the names of the locations with an IMD district,
+// the default targets of the nowcast and district warning sources.
+func NowcastLocations() []string {
+	names := make([]string, 0)
+	for _, l := range Locations() {
+		if l.DistrictID != 0 {
+			names = append(names, l.Name)
+		}
+	}
+	return names
+}
+
+// RadarLocations returns the names of the locations covered by a radar, the
+// default targets of the radar source.
+func RadarLocations() []string {
+	names := make([]string, 0)
+	for _, l := range Locations() {
+		if len(l.RadarCodes) > 0 {
+			names = append(names, l.Name)
+		}
+	}
+	return names
+}
+
+// RiverBasinIDs returns the distinct river basin ids configured across all
+// locations.
+func RiverBasinIDs() []int {
+	seen := map[int]bool{}
+	var ids []int
+	for _, l := range Locations() {
+		if l.RiverBasinID == 0 || seen[l.RiverBasinID] {
+			continue
+		}
+		seen[l.RiverBasinID] = true
+		ids = append(ids, l.RiverBasinID)
+	}
+	return ids
+}
+
+// AWSStationIDs returns the distinct AWS/ARG station ids configured across
+// all locations.
+func AWSStationIDs() []string {
+	seen := map[string]bool{}
+	var ids []string
+	for _, l := range Locations() {
+		for _, id := range l.AWSStationIDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// AWSScopes returns the distinct AWS/ARG sweep scopes configured across all
+// locations.
+func AWSScopes() []AWSScope {
+	seen := map[AWSScope]bool{}
+	var scopes []AWSScope
+	for _, l := range Locations() {
+		if l.AWSScope == (AWSScope{}) || seen[l.AWSScope] {
+			continue
+		}
+		seen[l.AWSScope] = true
+		scopes = append(scopes, l.AWSScope)
+	}
+	return scopes
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func validLocation() Location {
+	return Location{Name: "surat", DisplayName: "Surat", Lat: 21.17, Lon: 72.83, DistrictID: 250, PdfSlug: "gujarat.pdf", Timezone: DefaultTimezone}
+}
+
+func TestValidateLocation(t *testing.T) {
+	if err := ValidateLocation(validLocation()); err != nil {
+		t.Fatalf("valid location: %v", err)
+	}
+
+	cases := map[string]func(l *Location){
+		"slug":      func(l *Location) { l.Name = "Surat City" },
+		"name":      func(l *Location) { l.DisplayName = "" },
+		"outside":   func(l *Location) { l.Lat, l.Lon = 51.5, -0.1 },
+		"product":   func(l *Location) { l.PdfSlug = "gujarat" },
+		"sub-basin": func(l *Location) { l.SubBasinID = 3 },
+		"aws scope": func(l *Location) { l.AWSScope = AWSScope{District: "SURAT"} },
+		"timezone":  func(l *Location) { l.Timezone = "Mars/Olympus" },
+	}
+	for name, mutate := range cases {
+		l := validLocation()
+		mutate(&l)
+		if err := ValidateLocation(l); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}
+
+func TestSetLocationsDerivesTargets(t *testing.T) {
+	defer SetLocations(DefaultLocations)
+
+	if SetLocations(DefaultLocations) {
+		t.Error("same locations reported as changed")
+	}
+	ls := append(append([]Location{}, DefaultLocations...), validLocation())
+	if !SetLocations(ls) {
+		t.Fatal("added location not reported as changed")
+	}
+	if _, ok := LocationByName("surat"); !ok {
+		t.Error("surat not served")
+	}
+	if got := strings.Join(NowcastLocations(), ","); got != "vadodara,surat" {
+		t.Errorf("nowcast targets = %s", got)
+	}
+	if got := DefaultSources()["district_warning"].Targets; len(got) != 2 {
+		t.Errorf("district warning targets = %v", got)
+	}
+}
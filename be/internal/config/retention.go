@@ -0,0 +1,184 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// Retention configures the job pruning old ingested rows.
+type Retention struct {
+	Enabled bool `yaml:"enabled"`
+	// Spec is the cron spec the job runs on.
+	Spec string `yaml:"spec"`
+	// Timeout bounds a single run. Zero means no timeout.
+	Timeout time.Duration `yaml:"timeout"`
+	// BatchSize bounds the rows removed by a single delete so a run never
+	// holds long locks.
+	BatchSize int `yaml:"batch_size"`
+	// Tables maps a table to its policy. Tables left out are kept forever.
+	Tables map[string]TablePolicy `yaml:"tables"`
+}
+
+// TablePolicy is the retention policy of one table.
+type TablePolicy struct {
+	// Keep is how long rows are kept at full resolution. Zero keeps them
+	// forever.
+	Keep time.Duration `yaml:"keep"`
+	// Downsample, when set, rolls rows older than Keep up to one row per
+	// interval holding the interval's highest readings instead of deleting
+	// them.
+	Downsample time.Duration `yaml:"downsample"`
+}
+
+// retentionTables maps every table with a retention policy to whether it can
+// be downsampled.
+var retentionTables = map[string]bool{
+	"nowcast_raw":          false,
+	"district_warning_raw": false,
+	"imd_api_log":          false,
+	"job_run":              false,
+	"aws_arg":              false,
+	"nowcast":              true,
+	"radar_snapshot":       true,
+	// Blobs are kept as long as a bulletin references them; the policy only
+	// removes those left unreferenced by a failed fetch.
+	"blob": false,
+}
+
+// DefaultRetention returns the policy used for settings missing from the
+// config file.
+func DefaultRetention() Retention {
+	const day = 24 * time.Hour
+	return Retention{
+		Enabled:   true,
+		Spec:      "CRON_TZ=Asia/Kolkata 15 3 * * *",
+		Timeout:   30 * time.Minute,
+		BatchSize: 5000,
+		Tables: map[string]TablePolicy{
+			"nowcast_raw":          {Keep: 90 * day},
+			"district_warning_raw": {Keep: 90 * day},
+			"imd_api_log":          {Keep: 90 * day},
+			"job_run":              {Keep: 90 * day},
+			"nowcast":              {Keep: 30 * day, Downsample: time.Hour},
+			"radar_snapshot":       {Keep: 30 * day, Downsample: time.Hour},
+			"blob":                 {Keep: day},
+		},
+	}
+}
+
+// TableNames returns the tables with a policy, sorted by name.
+func (r Retention) TableNames() []string {
+	names := make([]string, 0, len(r.Tables))
+	for name := range r.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadRetention reads the `retention` section of the config file at path over
+// DefaultRetention and validates the result. Fields left out, including
+// those of a single table, keep their default. A missing file yields the
+// defaults.
+func LoadRetention(path string) (Retention, error) {
+	r := DefaultRetention()
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return Retention{}, err
+	}
+
+	var file struct {
+		Retention yaml.Node `yaml:"retention"`
+	}
+	if err := yaml.Unmarshal(b, &file); err != nil {
+		return Retention{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if !file.Retention.IsZero() {
+		if err := decodeRetention(&file.Retention, &r); err != nil {
+			return Retention{}, fmt.Errorf("%s: retention: %w", path, err)
+		}
+	}
+
+	if err := ValidateRetention(r); err != nil {
+		return Retention{}, fmt.Errorf("%s: retention: %w", path, err)
+	}
+	return r, nil
+}
+
+// decodeRetention decodes node over r, merging each table's policy over its
+// default.
+func decodeRetention(node *yaml.Node, r *Retention) error {
+	tables := r.Tables
+	r.Tables = nil
+	if err := node.Decode(r); err != nil {
+		return err
+	}
+	var raw struct {
+		Tables map[string]yaml.Node `yaml:"tables"`
+	}
+	if err := node.Decode(&raw); err != nil {
+		return err
+	}
+	r.Tables = tables
+	for name, n := range raw.Tables {
+		p := tables[name]
+		if err := n.Decode(&p); err != nil {
+			return fmt.Errorf("table %s: %w", name, err)
+		}
+		tables[name] = p
+	}
+	return nil
+}
+
+// ValidateRetention checks the cron spec, the batch size and every table
+// policy.
+func ValidateRetention(r Retention) error {
+	var errs []error
+	if _, err := cron.ParseStandard(r.Spec); err != nil {
+		errs = append(errs, fmt.Errorf("invalid spec %q: %w", r.Spec, err))
+	}
+	if r.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("negative timeout %s", r.Timeout))
+	}
+	if r.BatchSize <= 0 {
+		errs = append(errs, fmt.Errorf("batch_size must be positive, got %d", r.BatchSize))
+	}
+	for _, name := range r.TableNames() {
+		if err := validateTablePolicy(name, r.Tables[name]); err != nil {
+			errs = append(errs, fmt.Errorf("table %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validateTablePolicy(name string, p TablePolicy) error {
+	downsamples, ok := retentionTables[name]
+	if !ok {
+		return fmt.Errorf("unknown table")
+	}
+	if p.Keep < 0 {
+		return fmt.Errorf("negative keep %s", p.Keep)
+	}
+	if p.Downsample < 0 {
+		return fmt.Errorf("negative downsample %s", p.Downsample)
+	}
+	if p.Downsample > 0 {
+		if !downsamples {
+			return fmt.Errorf("cannot be downsampled")
+		}
+		if p.Keep == 0 {
+			return fmt.Errorf("downsample needs keep")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadRetentionMissingFile(t *testing.T) {
+	got, err := LoadRetention(filepath.Join(t.TempDir(), "none.yaml"))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if !got.Enabled || got.BatchSize != 5000 || len(got.Tables) != len(DefaultRetention().Tables) {
+		t.Fatalf("got %+v, want the defaults", got)
+	}
+}
+
+func TestLoadRetentionMergesTables(t *testing.T) {
+	p := writeSources(t, `
+retention:
+  batch_size: 100
+  tables:
+    nowcast:
+      keep: 240h
+    aws_arg:
+      keep: 720h
+`)
+	got, err := LoadRetention(p)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got.BatchSize != 100 || got.Spec != DefaultRetention().Spec {
+		t.Errorf("retention = %+v", got)
+	}
+	if n := got.Tables["nowcast"]; n.Keep != 240*time.Hour || n.Downsample != time.Hour {
+		t.Errorf("nowcast = %+v, want keep 240h and the default downsample", n)
+	}
+	if a := got.Tables["aws_arg"]; a.Keep != 720*time.Hour {
+		t.Errorf("aws_arg = %+v", a)
+	}
+	if _, ok := got.Tables["nowcast_raw"]; !ok {
+		t.Error("nowcast_raw lost its default policy")
+	}
+}
+
+func TestLoadRetentionInvalid(t *testing.T) {
+	cases := map[string]string{
+		"unknown table":         "retention:\n  tables:\n    satellite:\n      keep: 1h\n",
+		"bad spec":              "retention:\n  spec: \"nightly\"\n",
+		"zero batch":            "retention:\n  batch_size: 0\n",
+		"negative keep":         "retention:\n  tables:\n    imd_api_log:\n      keep: -1h\n",
+		"downsample raw":        "retention:\n  tables:\n    nowcast_raw:\n      downsample: 1h\n",
+		"downsample needs keep": "retention:\n  tables:\n    nowcast:\n      keep: 0s\n",
+	}
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadRetention(writeSources(t, body)); err == nil {
+				t.Fatal("want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultSourcesFile is read by LoadSources when SOURCES_CONFIG is not set.
+const DefaultSourcesFile = "sources.yaml"
+
+// Source configures how the scheduler fetches one upstream source.
+type Source struct {
+	Enabled bool `yaml:"enabled"`
+	// Spec is the cron spec the fetch runs on.
+	Spec string `yaml:"spec"`
+	// Jitter is the upper bound of the random delay added to each scheduled
+	// run so fetches do not hit IMD on the exact minute.
+	Jitter time.Duration `yaml:"jitter"`
+	// Timeout bounds a single run. Zero means no timeout.
+	Timeout time.Duration `yaml:"timeout"`
+	// Targets lists what the source fetches: location names for nowcast,
+	// district_warning and radar, basin ids for river_basin and station ids
+	// for aws_arg. Empty means the targets derived from Locations.
+	Targets []string `yaml:"targets"`
+}
+
+// targetKind is the kind of target a source accepts.
+type targetKind int
+
+const (
+	noTargets targetKind = iota
+	locationTargets
+	riverBasinTargets
+	awsStationTargets
+)
+
+// sourceTargets maps every known source to the kind of target it accepts.
+var sourceTargets = map[string]targetKind{
+	"bulletin":         noTargets,
+	"nowcast":          locationTargets,
+	"district_warning": locationTargets,
+	"radar":            locationTargets,
+	"river_basin":      riverBasinTargets,
+	"aws_arg":          awsStationTargets,
+}
+
+// DefaultSources returns the schedule used for sources missing from the
+// config file. Default targets are derived from the current Locations, so a
+// reload picks up locations added since.
+func DefaultSources() map[string]Source {
+	basins := make([]string, 0)
+	for _, id := range RiverBasinIDs() {
+		basins = append(basins, strconv.Itoa(id))
+	}
+	return map[string]Source{
+		"bulletin":         {Enabled: true, Spec: "CRON_TZ=Asia/Kolkata 30 18 * * *", Jitter: 30 * time.Second, Timeout: 5 * time.Minute},
+		"nowcast":          {Enabled: true, Spec: "CRON_TZ=Asia/Kolkata */15 * * * *", Jitter: 30 * time.Second, Timeout: 2 * time.Minute, Targets: NowcastLocations()},
+		"district_warning": {Enabled: true, Spec: "CRON_TZ=Asia/Kolkata 0 18 * * *", Jitter: 30 * time.Second, Timeout: 2 * time.Minute, Targets: NowcastLocations()},
+		"radar":            {Enabled: true, Spec: "CRON_TZ=Asia/Kolkata */5 * * * *", Jitter: 30 * time.Second, Timeout: 4 * time.Minute, Targets: RadarLocations()},
+		"river_basin":      {Enabled: true, Spec: "CRON_TZ=Asia/Kolkata 0 19 * * *", Jitter: 30 * time.Second, Timeout: 2 * time.Minute, Targets: basins},
+		"aws_arg":          {Enabled: true, Spec: "CRON_TZ=Asia/Kolkata */30 * * * *", Jitter: 30 * time.Second, Timeout: 10 * time.Minute, Targets: AWSStationIDs()},
+	}
+}
+
+// SourcesFile returns the path of the sources config file.
+func SourcesFile() string {
+	if p := os.Getenv("SOURCES_CONFIG"); p != "" {
+		return p
+	}
+	return DefaultSourcesFile
+}
+
+// LoadSources reads the `sources` section of the config file at path over
+// DefaultSources and validates the result. Fields left out of a source keep
+// their default. A missing file yields the defaults.
+func LoadSources(path string) (map[string]Source, error) {
+	sources := DefaultSources()
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return sources, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Sources map[string]yaml.Node `yaml:"sources"`
+	}
+	if err := yaml.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for name, node := range file.Sources {
+		s, ok := sources[name]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown source %q", path, name)
+		}
+		targets := s.Targets
+		s.Targets = nil
+		if err := node.Decode(&s); err != nil {
+			return nil, fmt.Errorf("%s: source %s: %w", path, name, err)
+		}
+		if len(s.Targets) == 0 {
+			s.Targets = targets
+		}
+		sources[name] = s
+	}
+
+	if err := ValidateSources(sources); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return sources, nil
+}
+
+// ValidateSources checks every source's cron spec, durations and targets.
+func ValidateSources(sources map[string]Source) error {
+	var errs []error
+	for name, s := range sources {
+		if err := validateSource(name, s); err != nil {
+			errs = append(errs, fmt.Errorf("source %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validateSource(name string, s Source) error {
+	kind, ok := sourceTargets[name]
+	if !ok {
+		return fmt.Errorf("unknown source")
+	}
+	if _, err := cron.ParseStandard(s.Spec); err != nil {
+		return fmt.Errorf("invalid spec %q: %w", s.Spec, err)
+	}
+	if s.Jitter < 0 {
+		return fmt.Errorf("negative jitter %s", s.Jitter)
+	}
+	if s.Timeout < 0 {
+		return fmt.Errorf("negative timeout %s", s.Timeout)
+	}
+
+	for _, t := range s.Targets {
+		switch kind {
+		case noTargets:
+			return fmt.Errorf("takes no targets")
+		case locationTargets:
+			if _, ok := LocationByName(t); !ok {
+				return fmt.Errorf("unknown location %q", t)
+			}
+		case riverBasinTargets:
+			if id, err := strconv.Atoi(t); err != nil || id <= 0 {
+				return fmt.Errorf("invalid river basin id %q", t)
+			}
+		case awsStationTargets:
+			if t == "" {
+				return fmt.Errorf("empty aws/arg station id")
+			}
+		}
+	}
+	return nil
+}
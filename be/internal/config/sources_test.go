@@ -0,0 +1,68 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeSources(t *testing.T, body string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "sources.yaml")
+	if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLoadSourcesMissingFile(t *testing.T) {
+	got, err := LoadSources(filepath.Join(t.TempDir(), "none.yaml"))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(got) != len(sourceTargets) {
+		t.Fatalf("got %d sources, want %d", len(got), len(sourceTargets))
+	}
+}
+
+func TestLoadSourcesOverridesDefaults(t *testing.T) {
+	p := writeSources(t, `
+sources:
+  nowcast:
+    spec: "*/10 * * * *"
+    timeout: 30s
+  radar:
+    enabled: false
+`)
+	got, err := LoadSources(p)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	n := got["nowcast"]
+	if n.Spec != "*/10 * * * *" || n.Timeout != 30*time.Second {
+		t.Errorf("nowcast = %+v", n)
+	}
+	if !n.Enabled || n.Jitter != 30*time.Second || len(n.Targets) != 1 || n.Targets[0] != "vadodara" {
+		t.Errorf("nowcast lost its defaults: %+v", n)
+	}
+	if got["radar"].Enabled {
+		t.Error("radar should be disabled")
+	}
+}
+
+func TestLoadSourcesInvalid(t *testing.T) {
+	cases := map[string]string{
+		"unknown source":   "sources:\n  satellite:\n    enabled: true\n",
+		"bad spec":         "sources:\n  nowcast:\n    spec: \"every minute\"\n",
+		"negative jitter":  "sources:\n  nowcast:\n    jitter: -1s\n",
+		"unknown location": "sources:\n  radar:\n    targets: [atlantis]\n",
+		"bad basin id":     "sources:\n  river_basin:\n    targets: [one]\n",
+		"bulletin targets": "sources:\n  bulletin:\n    targets: [vadodara]\n",
+	}
+	for name, body := range cases {
+		if _, err := LoadSources(writeSources(t, body)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/lolwierd/weatherboy/be/internal/jobstats"
 	"github.com/lolwierd/weatherboy/be/internal/utils"
 	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
 	"go.opentelemetry.io/otel/trace"
@@ -41,5 +42,9 @@ func (t *tracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQ
 
 	span.SetAttributes(RowsAffectedKey.Int(int(data.CommandTag.RowsAffected())))
 
+	if data.Err == nil && (data.CommandTag.Insert() || data.CommandTag.Update() || data.CommandTag.Delete()) {
+		jobstats.AddRows(ctx, data.CommandTag.RowsAffected())
+	}
+
 	span.End()
 }
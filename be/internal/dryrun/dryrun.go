@@ -0,0 +1,57 @@
+// Package dryrun lets jobs run end to end without storing anything: code
+// running under a dry run context collects what it would have written.
+package dryrun
+
+import (
+	"context"
+	"sync"
+)
+
+// Run collects what jobs run with its context produced instead of storing
+// it.
+type Run struct {
+	mu      sync.Mutex
+	results []any
+}
+
+type key struct{}
+
+// Start returns a context under which jobs fetch and parse as usual but store
+// nothing, collecting the parsed rows in the returned Run.
+func Start(ctx context.Context) (context.Context, *Run) {
+	r := &Run{}
+	return context.WithValue(ctx, key{}, r), r
+}
+
+// From returns the Run of ctx, or nil when results should be stored.
+func From(ctx context.Context) *Run {
+	r, _ := ctx.Value(key{}).(*Run)
+	return r
+}
+
+// Enabled reports whether ctx belongs to a dry run.
+func Enabled(ctx context.Context) bool {
+	return From(ctx) != nil
+}
+
+// Collect adds v to the results of the dry run ctx belongs to. It does
+// nothing outside of a dry run.
+func Collect(ctx context.Context, v any) {
+	if r := From(ctx); r != nil {
+		r.Add(v)
+	}
+}
+
+// Add adds v to the results.
+func (r *Run) Add(v any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results = append(r.results, v)
+}
+
+// Results returns the parsed rows collected so far.
+func (r *Run) Results() []any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]any(nil), r.results...)
+}
@@ -0,0 +1,176 @@
+// Package events fans out notices of newly ingested data and risk level
+// changes to stream subscribers. With EVENTS_NOTIFY set, events travel
+// through Postgres NOTIFY so subscribers of every replica receive them.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/logger"
+	"github.com/lolwierd/weatherboy/be/internal/repository"
+)
+
+// TypeRisk is the type of the event published when a location's risk level
+// changes. Other events are typed by the source that landed new data.
+const TypeRisk = "risk"
+
+// Event notifies subscribers of new data.
+type Event struct {
+	Type string `json:"type"`
+	// Location is the location the data is for, empty when it is not tied to
+	// one, e.g. AWS/ARG observations. Such events reach every subscriber.
+	Location string         `json:"location,omitempty"`
+	At       time.Time      `json:"at"`
+	Data     map[string]any `json:"data,omitempty"`
+}
+
+// subscriberBuffer is the number of events a subscriber may lag behind
+// before further events are dropped for it.
+const subscriberBuffer = 16
+
+type subscriber struct {
+	loc string
+	ch  chan Event
+}
+
+var (
+	mu     sync.Mutex
+	subs   = map[*subscriber]struct{}{}
+	closed bool
+)
+
+// Subscribe returns a channel receiving the events of loc and the events tied
+// to no location, and a function ending the subscription. The channel is
+// closed when the subscription ends or on Close. Events are dropped for a
+// subscriber that falls behind rather than slowing down publishers.
+func Subscribe(loc string) (<-chan Event, func()) {
+	s := &subscriber{loc: loc, ch: make(chan Event, subscriberBuffer)}
+	mu.Lock()
+	defer mu.Unlock()
+	if closed {
+		close(s.ch)
+		return s.ch, func() {}
+	}
+	subs[s] = struct{}{}
+	return s.ch, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, ok := subs[s]; ok {
+			delete(subs, s)
+			close(s.ch)
+		}
+	}
+}
+
+// Close ends every subscription, so open streams finish and the server can
+// shut down. Later subscriptions end at once.
+func Close() {
+	mu.Lock()
+	defer mu.Unlock()
+	closed = true
+	for s := range subs {
+		delete(subs, s)
+		close(s.ch)
+	}
+}
+
+// deliver hands e to the subscribers of this replica.
+func deliver(e Event) {
+	mu.Lock()
+	defer mu.Unlock()
+	for s := range subs {
+		if e.Location != "" && s.loc != e.Location {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+			logger.Warn.Println("event subscriber lagging, dropped", e.Type, "event")
+		}
+	}
+}
+
+// Publish sends e to the subscribers of every replica, through NOTIFY when
+// EVENTS_NOTIFY is set and directly otherwise. An event NOTIFY cannot carry
+// is still delivered to this replica. Call it after the data is committed.
+func Publish(ctx context.Context, e Event) {
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+	if !config.EventsNotify {
+		deliver(e)
+		return
+	}
+	if err := notify(ctx, e); err != nil {
+		logger.Error.Println("notify", e.Type, "event:", err)
+		deliver(e)
+	}
+}
+
+// notifyChannel is the Postgres channel events are sent on.
+const notifyChannel = "weatherboy_events"
+
+// maxNotifyPayload stays under the 8000 byte limit of a NOTIFY payload.
+const maxNotifyPayload = 7900
+
+func notify(ctx context.Context, e Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if len(b) > maxNotifyPayload {
+		// Subscribers can fetch the data; the notice itself must get through.
+		e.Data = nil
+		if b, err = json.Marshal(e); err != nil {
+			return err
+		}
+	}
+	return repository.Notify(ctx, notifyChannel, string(b))
+}
+
+// listenRetry is how long Listen waits before reconnecting.
+const listenRetry = 5 * time.Second
+
+// Listen delivers the events published through NOTIFY, by any replica, to
+// the subscribers of this replica until ctx is done. It reconnects when the
+// connection is lost.
+func Listen(ctx context.Context) {
+	for {
+		err := listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Error.Println("listen for events:", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetry):
+		}
+	}
+}
+
+func listen(ctx context.Context) error {
+	conn, err := repository.Listen(ctx, notifyChannel)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	logger.Info.Println("listening for events on", notifyChannel)
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var e Event
+		if err := json.Unmarshal([]byte(n.Payload), &e); err != nil {
+			logger.Warn.Println("malformed event:", err)
+			continue
+		}
+		deliver(e)
+	}
+}
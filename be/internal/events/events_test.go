@@ -0,0 +1,75 @@
+package events
+
+import (
+	"context"
+	"testing"
+)
+
+func received(ch <-chan Event) []Event {
+	var got []Event
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				return got
+			}
+			got = append(got, e)
+		default:
+			return got
+		}
+	}
+}
+
+func TestSubscribeFiltersByLocation(t *testing.T) {
+	ch, unsubscribe := Subscribe("vadodara")
+	defer unsubscribe()
+
+	Publish(context.Background(), Event{Type: "nowcast", Location: "vadodara"})
+	Publish(context.Background(), Event{Type: "nowcast", Location: "mumbai"})
+	Publish(context.Background(), Event{Type: "aws_arg"})
+
+	got := received(ch)
+	if len(got) != 2 || got[0].Location != "vadodara" || got[1].Type != "aws_arg" {
+		t.Fatalf("got %+v, want the vadodara and the aws_arg event", got)
+	}
+	if got[0].At.IsZero() {
+		t.Error("published event not timestamped")
+	}
+}
+
+func TestLaggingSubscriberDropsEvents(t *testing.T) {
+	ch, unsubscribe := Subscribe("pune")
+	defer unsubscribe()
+
+	for i := 0; i < subscriberBuffer+5; i++ {
+		deliver(Event{Type: "nowcast", Location: "pune"})
+	}
+	if got := len(received(ch)); got != subscriberBuffer {
+		t.Fatalf("received %d events, want %d", got, subscriberBuffer)
+	}
+}
+
+func TestUnsubscribeAndClose(t *testing.T) {
+	ch, unsubscribe := Subscribe("pune")
+	unsubscribe()
+	unsubscribe()
+	if _, ok := <-ch; ok {
+		t.Fatal("channel open after unsubscribe")
+	}
+
+	ch, unsubscribe = Subscribe("pune")
+	defer unsubscribe()
+	Close()
+	t.Cleanup(func() {
+		mu.Lock()
+		closed = false
+		mu.Unlock()
+	})
+	if _, ok := <-ch; ok {
+		t.Fatal("channel open after Close")
+	}
+	late, _ := Subscribe("pune")
+	if _, ok := <-late; ok {
+		t.Fatal("subscription after Close not ended")
+	}
+}
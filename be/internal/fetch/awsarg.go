@@ -3,14 +3,21 @@ package fetch
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/dryrun"
+	"github.com/lolwierd/weatherboy/be/internal/events"
 	"github.com/lolwierd/weatherboy/be/internal/logger"
 	"github.com/lolwierd/weatherboy/be/internal/model"
+	"github.com/lolwierd/weatherboy/be/internal/qc"
 	"github.com/lolwierd/weatherboy/be/internal/repository"
 )
 
@@ -41,13 +48,70 @@ type awsArgResp struct {
 	Rainfall      string `json:"RAINFALL"`
 }
 
-// FetchAWSARGOnce fetches AWS/ARG data from the IMD API and stores it.
+// FetchAWSARG sweeps every configured state/district and then each of the
+// given stations.
+func FetchAWSARG(ctx context.Context, stationIDs []string) error {
+	var errs []error
+	for _, s := range config.AWSScopes() {
+		if err := FetchAWSARGBulk(ctx, s); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := FetchAWSARGStations(ctx, stationIDs); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// FetchAWSARGStations fetches each of the given AWS/ARG stations.
+func FetchAWSARGStations(ctx context.Context, stationIDs []string) error {
+	var errs []error
+	for _, id := range stationIDs {
+		if err := FetchAWSARGOnce(ctx, id); err != nil {
+			errs = append(errs, fmt.Errorf("station %s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FetchAWSARGOnce fetches AWS/ARG data for a single station from the IMD API
+// and stores it.
 func FetchAWSARGOnce(ctx context.Context, stationID string) error {
-	url := fmt.Sprintf("%s?id=%s", imdAWSARGBaseURL, stationID)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	u := fmt.Sprintf("%s?id=%s", imdAWSARGBaseURL, url.QueryEscape(stationID))
+	return fetchAndStoreAWSARG(ctx, u)
+}
+
+// FetchAWSARGBulk fetches every AWS/ARG station of a state or district in one
+// request and stores them. Each record is validated on its own so one bad
+// station does not drop the rest of the sweep.
+func FetchAWSARGBulk(ctx context.Context, scope config.AWSScope) error {
+	q := url.Values{}
+	if scope.State != "" {
+		q.Set("state", scope.State)
+	}
+	if scope.District != "" {
+		q.Set("district", scope.District)
+	}
+	if len(q) == 0 {
+		return fmt.Errorf("aws/arg bulk fetch needs a state or district")
+	}
+	if err := fetchAndStoreAWSARG(ctx, imdAWSARGBaseURL+"?"+q.Encode()); err != nil {
+		return fmt.Errorf("aws/arg sweep %s: %w", scope, err)
+	}
+	return nil
+}
+
+// fetchAndStoreAWSARG requests u and stores every valid record in the
+// response. It fails only when the request fails or no record could be
+// stored.
+func fetchAndStoreAWSARG(ctx context.Context, u string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
 	if err != nil {
 		return err
 	}
+	if err := waitIMD(ctx); err != nil {
+		return err
+	}
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
@@ -71,105 +135,169 @@ func FetchAWSARGOnce(ctx context.Context, stationID string) error {
 		return fmt.Errorf("empty aws/arg response")
 	}
 
-	// Assuming the API returns a single object in the array for the station
-	awsArgData := arr[0]
-
-	date, err := time.Parse("2006-01-02", awsArgData.Date)
-	if err != nil {
-		return fmt.Errorf("invalid date in AWS/ARG response: %w", err)
-	}
-	timeOfDay, err := time.Parse("15:04:05", awsArgData.Time)
-	if err != nil {
-		return fmt.Errorf("invalid time in AWS/ARG response: %w", err)
+	stored := 0
+	var landed []string
+	for _, r := range arr {
+		a, err := parseAWSARG(r)
+		if err != nil {
+			logger.Warn.Printf("skipping aws/arg station %s: %v", r.ID, err)
+			continue
+		}
+		if d := dryrun.From(ctx); d != nil {
+			checkAWSARG(ctx, &a)
+			d.Add(a)
+			stored++
+			continue
+		}
+		inserted, err := storeAWSARG(ctx, &a)
+		if err != nil {
+			logger.Error.Printf("store aws/arg station %s: %v", r.ID, err)
+			continue
+		}
+		stored++
+		if inserted {
+			landed = append(landed, a.StationID)
+		}
 	}
 
-	currentTemp, err := strconv.ParseFloat(awsArgData.CurrTemp, 64)
-	if err != nil {
-		return fmt.Errorf("invalid current_temp in AWS/ARG response: %w", err)
+	if !dryrun.Enabled(ctx) {
+		call := model.IMDAPICall{
+			Endpoint:    u,
+			Bytes:       int64(len(body)),
+			RequestedAt: time.Now(),
+		}
+		if err := repository.InsertIMDAPICall(ctx, &call); err != nil {
+			logger.Error.Println("repository insert api log:", err)
+		} else {
+			logger.Info.Printf("IMD API call %s bytes=%d stations=%d/%d", u, len(body), stored, len(arr))
+		}
 	}
-	dewPointTemp, err := strconv.ParseFloat(awsArgData.DewPointTemp, 64)
-	if err != nil {
-		return fmt.Errorf("invalid dew_point_temp in AWS/ARG response: %w", err)
+
+	if stored == 0 {
+		return fmt.Errorf("no valid aws/arg records in %d returned", len(arr))
 	}
-	rh, err := strconv.ParseFloat(awsArgData.RH, 64)
-	if err != nil {
-		return fmt.Errorf("invalid RH in AWS/ARG response: %w", err)
+	// Only observations not stored before are news to subscribers.
+	if len(landed) > 0 && !dryrun.Enabled(ctx) {
+		publishAWSARG(ctx, landed)
 	}
-	windDirection, err := strconv.ParseFloat(awsArgData.WindDirection, 64)
+	return nil
+}
+
+// publishAWSARG tells the streams of each location served by any of the
+// given stations how many of its stations have new observations.
+func publishAWSARG(ctx context.Context, stationIDs []string) {
+	locs, err := repository.AWSStationLocations(ctx, stationIDs)
 	if err != nil {
-		return fmt.Errorf("invalid wind_direction in AWS/ARG response: %w", err)
+		logger.Error.Println("resolve aws/arg event locations:", err)
+		return
 	}
-	windSpeed, err := strconv.ParseFloat(awsArgData.WindSpeed, 64)
-	if err != nil {
-		return fmt.Errorf("invalid wind_speed in AWS/ARG response: %w", err)
+	for loc, ids := range locs {
+		events.Publish(ctx, events.Event{Type: "aws_arg", Location: loc, Data: map[string]any{"stations": len(ids)}})
 	}
-	mslp, err := strconv.ParseFloat(awsArgData.MSLP, 64)
-	if err != nil {
-		return fmt.Errorf("invalid MSLP in AWS/ARG response: %w", err)
+}
+
+// missingValues are the placeholders IMD uses for a reading a station did
+// not report.
+var missingValues = map[string]bool{"": true, "NA": true, "N/A": true, "NULL": true, "-": true, "--": true}
+
+// parseOptionalFloat parses an AWS/ARG numeric field. Placeholders and
+// unparseable values are treated as missing and returned as nil; quality
+// control flags them later.
+func parseOptionalFloat(s string) *float64 {
+	s = strings.TrimSpace(s)
+	if missingValues[strings.ToUpper(s)] {
+		return nil
 	}
-	minTemp, err := strconv.ParseFloat(awsArgData.MinTemp, 64)
+	v, err := strconv.ParseFloat(s, 64)
 	if err != nil {
-		return fmt.Errorf("invalid min_temp in AWS/ARG response: %w", err)
+		return nil
 	}
-	maxTemp, err := strconv.ParseFloat(awsArgData.MaxTemp, 64)
-	if err != nil {
-		return fmt.Errorf("invalid max_temp in AWS/ARG response: %w", err)
+	return &v
+}
+
+// parseAWSARG validates a single AWS/ARG record and converts it to the model.
+// Only the station id, observation time and coordinates are required.
+func parseAWSARG(r awsArgResp) (model.AWSARG, error) {
+	if r.ID == "" {
+		return model.AWSARG{}, fmt.Errorf("missing station id in AWS/ARG response")
 	}
-	latitude, err := strconv.ParseFloat(awsArgData.Latitude, 64)
+	date, err := time.Parse("2006-01-02", r.Date)
 	if err != nil {
-		return fmt.Errorf("invalid latitude in AWS/ARG response: %w", err)
+		return model.AWSARG{}, fmt.Errorf("invalid date in AWS/ARG response: %w", err)
 	}
-	longitude, err := strconv.ParseFloat(awsArgData.Longitude, 64)
+	timeOfDay, err := time.Parse("15:04:05", r.Time)
 	if err != nil {
-		return fmt.Errorf("invalid longitude in AWS/ARG response: %w", err)
+		return model.AWSARG{}, fmt.Errorf("invalid time in AWS/ARG response: %w", err)
 	}
-	nebulosity, err := strconv.ParseFloat(awsArgData.Nebulosity, 64)
+	latitude, err := strconv.ParseFloat(strings.TrimSpace(r.Latitude), 64)
 	if err != nil {
-		return fmt.Errorf("invalid nebulosity in AWS/ARG response: %w", err)
+		return model.AWSARG{}, fmt.Errorf("invalid latitude in AWS/ARG response: %w", err)
 	}
-	rainfall, err := strconv.ParseFloat(awsArgData.Rainfall, 64)
+	longitude, err := strconv.ParseFloat(strings.TrimSpace(r.Longitude), 64)
 	if err != nil {
-		return fmt.Errorf("invalid rainfall in AWS/ARG response: %w", err)
+		return model.AWSARG{}, fmt.Errorf("invalid longitude in AWS/ARG response: %w", err)
 	}
 
-	awsArg := model.AWSARG{
-		StationID:     awsArgData.ID,
-		CallSign:      awsArgData.CallSign,
-		District:      awsArgData.District,
-		State:         awsArgData.State,
-		StationName:   awsArgData.Station,
+	return model.AWSARG{
+		StationID:     r.ID,
+		CallSign:      r.CallSign,
+		District:      r.District,
+		State:         r.State,
+		StationName:   r.Station,
 		Date:          date,
 		Time:          timeOfDay,
-		CurrentTemp:   currentTemp,
-		DewPointTemp:  dewPointTemp,
-		RH:            rh,
-		WindDirection: windDirection,
-		WindSpeed:     windSpeed,
-		MSLP:          mslp,
-		MinTemp:       minTemp,
-		MaxTemp:       maxTemp,
+		CurrentTemp:   parseOptionalFloat(r.CurrTemp),
+		DewPointTemp:  parseOptionalFloat(r.DewPointTemp),
+		RH:            parseOptionalFloat(r.RH),
+		WindDirection: parseOptionalFloat(r.WindDirection),
+		WindSpeed:     parseOptionalFloat(r.WindSpeed),
+		MSLP:          parseOptionalFloat(r.MSLP),
+		MinTemp:       parseOptionalFloat(r.MinTemp),
+		MaxTemp:       parseOptionalFloat(r.MaxTemp),
 		Latitude:      latitude,
 		Longitude:     longitude,
-		WeatherCode:   awsArgData.WeatherCode,
-		Nebulosity:    nebulosity,
-		FeelLike:      0, // This field is not directly available in the API response, setting to 0 for now
-		RainfallSel:   awsArgData.RainfallSel,
-		Rainfall:      rainfall,
-	}
+		WeatherCode:   r.WeatherCode,
+		Nebulosity:    parseOptionalFloat(r.Nebulosity),
+		FeelLike:      parseOptionalFloat(r.FeelLike),
+		RainfallSel:   r.RainfallSel,
+		Rainfall:      parseOptionalFloat(r.Rainfall),
+	}, nil
+}
 
-	if err := repository.InsertAWSARG(ctx, &awsArg); err != nil {
-		return err
+// checkAWSARG quality-checks an observation against the station's recent
+// readings.
+func checkAWSARG(ctx context.Context, a *model.AWSARG) {
+	history, err := repository.RecentAWSARG(ctx, a.StationID, a.ObservedAt().Add(-qc.HistoryWindow))
+	if err != nil {
+		logger.Error.Println("repository recent aws/arg:", err)
 	}
+	qc.Check(a, history)
+}
 
-	call := model.IMDAPICall{
-		Endpoint:    url,
-		Bytes:       int64(len(body)),
-		RequestedAt: time.Now(),
-	}
-	if err := repository.InsertIMDAPICall(ctx, &call); err != nil {
-		logger.Error.Println("repository insert api log:", err)
-	} else {
-		logger.Info.Printf("IMD API call %s bytes=%d", url, len(body))
-	}
-	return nil
+// storeAWSARG quality-checks an observation against the station's recent
+// readings, then upserts it and refreshes its station in the catalogue in one
+// transaction. It reports whether the observation is new.
+func storeAWSARG(ctx context.Context, a *model.AWSARG) (inserted bool, err error) {
+	checkAWSARG(ctx, a)
+
+	err = repository.InTx(ctx, func(ctx context.Context) error {
+		if inserted, err = repository.UpsertAWSARG(ctx, a); err != nil {
+			return err
+		}
+		station := model.AWSStation{
+			StationID:   a.StationID,
+			CallSign:    a.CallSign,
+			StationName: a.StationName,
+			District:    a.District,
+			State:       a.State,
+			Latitude:    a.Latitude,
+			Longitude:   a.Longitude,
+			LastSeenAt:  a.FetchedAt,
+		}
+		if err := repository.UpsertAWSStation(ctx, &station); err != nil {
+			return fmt.Errorf("upsert aws station: %w", err)
+		}
+		return nil
+	})
+	return inserted, err
 }
@@ -0,0 +1,34 @@
+package fetch
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseAWSARGPerRecord(t *testing.T) {
+	data := `[
+		{"ID":"A1","DATE":"2024-06-20","TIME":"12:00:00","CURR_TEMP":"31.2","DEW_POINT_TEMP":"24","RH":"70","WIND_DIRECTION":"270","WIND_SPEED":"12","MSLP":"1002","MIN_TEMP":"26","MAX_TEMP":"33","Latitude":"22.3","Longitude":"73.2","NEBULOSITY":"4","RAINFALL":"1.5"},
+		{"ID":"A2","DATE":"2024-06-20","TIME":"12:00:00","CURR_TEMP":"bad","DEW_POINT_TEMP":"24","RH":"70","WIND_DIRECTION":"270","WIND_SPEED":"12","MSLP":"1002","MIN_TEMP":"26","MAX_TEMP":"33","Latitude":"22.3","Longitude":"73.2","NEBULOSITY":"4","RAINFALL":"0"}
+	]`
+	var arr []awsArgResp
+	if err := json.Unmarshal([]byte(data), &arr); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	a, err := parseAWSARG(arr[0])
+	if err != nil {
+		t.Fatalf("parse valid record: %v", err)
+	}
+	if a.StationID != "A1" || *a.CurrentTemp != 31.2 || *a.Rainfall != 1.5 {
+		t.Fatalf("unexpected record: %+v", a)
+	}
+	b, err := parseAWSARG(arr[1])
+	if err != nil {
+		t.Fatalf("parse record with bad reading: %v", err)
+	}
+	if b.CurrentTemp != nil || b.FeelLike != nil || b.RH == nil {
+		t.Fatalf("expected bad and absent readings to be nil: %+v", b)
+	}
+	if _, err := parseAWSARG(awsArgResp{ID: "A3", Date: "2024-06-20", Time: "12:00:00", Latitude: "NA", Longitude: "73.2"}); err == nil {
+		t.Fatalf("expected error for missing latitude")
+	}
+}
@@ -0,0 +1,129 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/logger"
+	"github.com/lolwierd/weatherboy/be/internal/repository"
+)
+
+// BackfillSources lists the sources Backfill supports. IMD only serves the
+// current nowcast and district warning, so those are rebuilt from the raw
+// payloads already stored; bulletins are re-downloaded from the archive.
+var BackfillSources = []string{"bulletin", "nowcast", "district_warning"}
+
+// Backfill fills the gaps of a source within [from, to). Rows that are
+// already stored are skipped, so it is safe to re-run over the same range.
+func Backfill(ctx context.Context, source string, from, to time.Time) error {
+	if !to.After(from) {
+		return fmt.Errorf("backfill range %s - %s is empty", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+	switch source {
+	case "bulletin":
+		return backfillBulletins(ctx, from, to)
+	case "nowcast":
+		return backfillNowcasts(ctx, from, to)
+	case "district_warning":
+		return backfillDistrictWarnings(ctx, from, to)
+	default:
+		return fmt.Errorf("unknown backfill source %q, want one of %s", source, strings.Join(BackfillSources, ", "))
+	}
+}
+
+// backfillBulletins downloads the archived bulletin of every IST day within
+// [from, to) that has no stored bulletin.
+func backfillBulletins(ctx context.Context, from, to time.Time) error {
+	config.LoadEnv()
+	if config.BulletinArchiveURL == "" {
+		return fmt.Errorf("BULLETIN_ARCHIVE_URL not set")
+	}
+	ist, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	f := from.In(ist)
+	for day := time.Date(f.Year(), f.Month(), f.Day(), 0, 0, 0, 0, ist); day.Before(to); day = day.AddDate(0, 0, 1) {
+		exists, err := repository.BulletinRawExists(ctx, bulletinPath(day))
+		if err != nil {
+			return err
+		}
+		if exists {
+			logger.Info.Println("backfill bulletin", day.Format("2006-01-02"), "already stored")
+			continue
+		}
+		url := strings.ReplaceAll(config.BulletinArchiveURL, "{date}", day.Format(config.BulletinArchiveDateFormat))
+		if err := fetchBulletin(ctx, url, day); err != nil {
+			errs = append(errs, fmt.Errorf("bulletin %s: %w", day.Format("2006-01-02"), err))
+			continue
+		}
+		logger.Info.Println("backfilled bulletin", day.Format("2006-01-02"))
+	}
+	return errors.Join(errs...)
+}
+
+// backfillNowcasts parses the raw nowcast payloads fetched within [from, to)
+// that are not marked as parsed. They are stored the way a live fetch stores
+// them, so a payload whose nowcast is already stored is marked as parsed into
+// it.
+func backfillNowcasts(ctx context.Context, from, to time.Time) error {
+	raws, err := repository.NowcastRawRange(ctx, from, to)
+	if err != nil {
+		return err
+	}
+	stored := 0
+	for _, r := range raws {
+		n, cats, err := parseNowcast(r.Location, r.Data, r.FetchedAt)
+		if err != nil {
+			logger.Warn.Printf("skipping nowcast raw %d: %v", r.ID, err)
+			continue
+		}
+		n.NowcastRawID = &r.ID
+		inserted, err := storeNowcast(ctx, &n, cats)
+		if err != nil {
+			return err
+		}
+		if inserted {
+			stored++
+		}
+	}
+	logger.Info.Printf("backfilled %d nowcasts from %d raw payloads", stored, len(raws))
+	return nil
+}
+
+// backfillDistrictWarnings parses the raw district warning payloads fetched
+// within [from, to) whose district warning row is missing.
+func backfillDistrictWarnings(ctx context.Context, from, to time.Time) error {
+	raws, err := repository.DistrictWarningRawRange(ctx, from, to)
+	if err != nil {
+		return err
+	}
+	stored := 0
+	for _, r := range raws {
+		dw, err := parseDistrictWarning(r.Location, r.Data, r.FetchedAt)
+		if err != nil {
+			logger.Warn.Printf("skipping district warning raw %d: %v", r.ID, err)
+			continue
+		}
+		exists, err := repository.DistrictWarningExists(ctx, dw.Location, dw.IssuedAt)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		dw.DistrictWarningRawID = &r.ID
+		if _, err := repository.UpsertDistrictWarning(ctx, &dw); err != nil {
+			return err
+		}
+		stored++
+	}
+	logger.Info.Printf("backfilled %d district warnings from %d raw payloads", stored, len(raws))
+	return nil
+}
@@ -10,17 +10,40 @@ import (
 	"time"
 
 	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/dryrun"
+	"github.com/lolwierd/weatherboy/be/internal/events"
 	"github.com/lolwierd/weatherboy/be/internal/logger"
 	"github.com/lolwierd/weatherboy/be/internal/model"
 	"github.com/lolwierd/weatherboy/be/internal/parse"
 	"github.com/lolwierd/weatherboy/be/internal/repository"
+	"github.com/lolwierd/weatherboy/be/internal/storage"
 )
 
+const imdBulletinURL = "https://mausam.imd.gov.in/ahmedabad/mcdata/state.pdf"
+
 // FetchBulletinOnce downloads today's Gujarat bulletin PDF, parses it, and stores it.
 func FetchBulletinOnce(ctx context.Context) error {
-	const url = "https://mausam.imd.gov.in/ahmedabad/mcdata/state.pdf"
+	return fetchBulletin(ctx, imdBulletinURL, time.Now())
+}
+
+// bulletinPath returns the path identifying the Gujarat bulletin of a date.
+// Bulletins fetched before the blob store were kept on local disk there.
+func bulletinPath(date time.Time) string {
+	config.LoadEnv()
+	return filepath.Join(config.DataDir, "pdf", fmt.Sprintf("%s-gujarat.pdf", date.Format("2006-01-02")))
+}
 
-	resp, err := http.Get(url)
+// fetchBulletin downloads the bulletin PDF at url as the bulletin of date,
+// parses it, and stores it.
+func fetchBulletin(ctx context.Context, url string, date time.Time) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if err := waitIMD(ctx); err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -30,58 +53,107 @@ func FetchBulletinOnce(ctx context.Context) error {
 		return fmt.Errorf("unexpected status %s", resp.Status)
 	}
 
-	config.LoadEnv()
-	dir := filepath.Join(config.DataDir, "pdf")
-	if err := os.MkdirAll(dir, 0o755); err != nil {
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
 		return err
 	}
 
-	dateStr := time.Now().Format("2006-01-02")
-	path := filepath.Join(dir, fmt.Sprintf("%s-gujarat.pdf", dateStr))
+	// Parse before opening the transaction; a bulletin that fails to parse is
+	// still recorded so Reprocess can retry it.
+	forecast, parseErr := parseBulletin(ctx, data)
+	if d := dryrun.From(ctx); d != nil {
+		if parseErr != nil {
+			return parseErr
+		}
+		d.Add(model.BulletinParsed{Location: "vadodara", Forecast: forecast, ParserVersion: BulletinParserVersion})
+		return nil
+	}
 
-	f, err := os.Create(path)
+	store, err := storage.Default()
 	if err != nil {
 		return err
 	}
-	n, err := io.Copy(f, resp.Body)
-	if err != nil {
-		f.Close()
-		return err
+	// The blob is recorded before it is uploaded, so one whose bulletin is
+	// never recorded is still known to retention, which removes the blobs no
+	// bulletin references.
+	blob := storage.NewBlob("bulletin", ".pdf", "application/pdf", data)
+	if err := repository.InsertBlob(ctx, &blob); err != nil {
+		return fmt.Errorf("insert blob: %w", err)
 	}
-	if err := f.Close(); err != nil {
-		return err
+	if err := storage.PutContent(ctx, store, blob, data); err != nil {
+		return fmt.Errorf("store bulletin blob: %w", err)
 	}
 
-	br := model.BulletinRaw{Path: path, FetchedAt: time.Now()}
-	if err := repository.InsertBulletinRaw(ctx, &br); err != nil {
-		logger.Error.Println("repository insert bulletin raw:", err)
+	br := model.BulletinRaw{Path: bulletinPath(date), BlobKey: &blob.Key, FetchedAt: time.Now()}
+	var changed bool
+	err = repository.InTx(ctx, func(ctx context.Context) error {
+		if err := repository.InsertBulletinRaw(ctx, &br); err != nil {
+			return fmt.Errorf("insert bulletin raw: %w", err)
+		}
+		if parseErr != nil {
+			return nil
+		}
+		bp := model.BulletinParsed{
+			BulletinRawID: br.ID,
+			Location:      "vadodara",
+			Forecast:      forecast,
+			ParserVersion: BulletinParserVersion,
+			FetchedAt:     time.Now(),
+		}
+		changed, err = repository.UpsertParsedBulletin(ctx, &bp)
 		return err
-	}
-
-	forecast, err := parse.ParseBulletinPDF(ctx, path, "Vadodara")
+	})
 	if err != nil {
 		return err
 	}
-
-	bp := model.BulletinParsed{
-		BulletinRawID: br.ID,
-		Location:      "vadodara",
-		Forecast:      forecast,
-		FetchedAt:     time.Now(),
+	if parseErr != nil {
+		return parseErr
 	}
-	if err := repository.InsertParsedBulletin(ctx, &bp); err != nil {
-		return err
+	// Fetching the same bulletin again is no news to the streams.
+	if changed {
+		events.Publish(ctx, events.Event{Type: "bulletin", Location: "vadodara", Data: map[string]any{"date": date.Format("2006-01-02")}})
 	}
 
 	call := model.IMDAPICall{
 		Endpoint:    url,
-		Bytes:       n,
+		Bytes:       int64(len(data)),
 		RequestedAt: time.Now(),
 	}
 	if err := repository.InsertIMDAPICall(ctx, &call); err != nil {
 		logger.Error.Println("repository insert api log:", err)
 	} else {
-		logger.Info.Printf("IMD API call %s bytes=%d", url, n)
+		logger.Info.Printf("IMD API call %s bytes=%d", url, len(data))
 	}
 	return nil
 }
+
+// loadBulletin returns the PDF of a stored bulletin, from the blob store or,
+// for bulletins fetched before it, from local disk.
+func loadBulletin(ctx context.Context, r model.BulletinRaw) ([]byte, error) {
+	if r.BlobKey == nil {
+		return os.ReadFile(r.Path)
+	}
+	store, err := storage.Default()
+	if err != nil {
+		return nil, err
+	}
+	return store.Get(ctx, *r.BlobKey)
+}
+
+// parseBulletin extracts the Vadodara forecast from a bulletin PDF. The
+// parser reads files, so the PDF goes through a temporary one.
+func parseBulletin(ctx context.Context, data []byte) (string, error) {
+	f, err := os.CreateTemp("", "bulletin-*.pdf")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return parse.ParseBulletinPDF(ctx, f.Name(), "Vadodara")
+}
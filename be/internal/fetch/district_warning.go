@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/dryrun"
+	"github.com/lolwierd/weatherboy/be/internal/events"
 	"github.com/lolwierd/weatherboy/be/internal/logger"
 	"github.com/lolwierd/weatherboy/be/internal/model"
 	"github.com/lolwierd/weatherboy/be/internal/repository"
@@ -33,17 +35,19 @@ type districtWarningResp struct {
 	Day5Color string `json:"Day5_Color"`
 }
 
-// FetchDistrictWarnings fetches district-wise warning data from the IMD API for Vadodara and stores it.
-func FetchDistrictWarnings(ctx context.Context) error {
-	loc, ok := config.LocationByName("vadodara")
-	if !ok || loc.DistrictID == 0 {
-		return fmt.Errorf("district id for vadodara not set")
+// FetchDistrictWarnings fetches district-wise warning data from the IMD API for a location and stores it.
+func FetchDistrictWarnings(ctx context.Context, loc config.Location) error {
+	if loc.DistrictID == 0 {
+		return fmt.Errorf("district id for %s not set", loc.Name)
 	}
 	url := fmt.Sprintf("%s?id=%d", imdDistrictWarningBaseURL, loc.DistrictID)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return err
 	}
+	if err := waitIMD(ctx); err != nil {
+		return err
+	}
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
@@ -59,50 +63,35 @@ func FetchDistrictWarnings(ctx context.Context) error {
 		return err
 	}
 
-	var arr []districtWarningResp
-	if err := json.Unmarshal(body, &arr); err != nil {
+	fetchedAt := time.Now()
+	dw, err := parseDistrictWarning(loc.Name, body, fetchedAt)
+	if err != nil {
 		return err
 	}
-	if len(arr) == 0 {
-		return fmt.Errorf("empty district warning response")
-	}
-
-	raw := model.DistrictWarningRaw{
-		Location:  "vadodara",
-		Data:      body,
-		FetchedAt: time.Now(),
+	if d := dryrun.From(ctx); d != nil {
+		d.Add(dw)
+		return nil
 	}
-	if err := repository.InsertDistrictWarningRaw(ctx, &raw); err != nil {
-		logger.Error.Println("insert district warning raw:", err)
-	}
-
-	// Assuming the API returns a single object in the array for the district
-	dwResp := arr[0]
 
-	issuedAt := time.Now()
-	if dwResp.Date != "" && dwResp.UTC != "" {
-		t, err := time.Parse("2006-01-02 15:04:05", dwResp.Date+" "+dwResp.UTC)
-		if err == nil {
-			issuedAt = t
+	var landed bool
+	stored := repository.InTx(ctx, func(ctx context.Context) error {
+		raw := model.DistrictWarningRaw{
+			Location:  loc.Name,
+			Data:      body,
+			FetchedAt: fetchedAt,
 		}
-	}
-
-	dw := model.DistrictWarning{
-		Location:    "vadodara",
-		IssuedAt:    issuedAt,
-		Day1Warning: dwResp.Day1,
-		Day2Warning: dwResp.Day2,
-		Day3Warning: dwResp.Day3,
-		Day4Warning: dwResp.Day4,
-		Day5Warning: dwResp.Day5,
-		Day1Color:   dwResp.Day1Color,
-		Day2Color:   dwResp.Day2Color,
-		Day3Color:   dwResp.Day3Color,
-		Day4Color:   dwResp.Day4Color,
-		Day5Color:   dwResp.Day5Color,
-	}
-	if err := repository.InsertDistrictWarning(ctx, &dw); err != nil {
-		logger.Error.Println("insert district warning:", err)
+		if err := repository.InsertDistrictWarningRaw(ctx, &raw); err != nil {
+			return fmt.Errorf("insert district warning raw: %w", err)
+		}
+		dw.DistrictWarningRawID = &raw.ID
+		if landed, err = repository.UpsertDistrictWarning(ctx, &dw); err != nil {
+			return fmt.Errorf("upsert district warning: %w", err)
+		}
+		return nil
+	})
+	// Re-fetching a warning already stored is no news to subscribers.
+	if stored == nil && landed {
+		events.Publish(ctx, events.Event{Type: "district_warning", Location: loc.Name, Data: map[string]any{"issued_at": dw.IssuedAt, "day1_color": dw.Day1Color}})
 	}
 
 	call := model.IMDAPICall{
@@ -115,5 +104,45 @@ func FetchDistrictWarnings(ctx context.Context) error {
 	} else {
 		logger.Info.Printf("IMD API call %s bytes=%d", url, len(body))
 	}
-	return nil
+	return stored
+}
+
+// parseDistrictWarning converts a raw IMD district warning payload of a
+// location into a district warning row. fetchedAt is used as the issue time
+// when the payload carries none.
+func parseDistrictWarning(loc string, body []byte, fetchedAt time.Time) (model.DistrictWarning, error) {
+	var arr []districtWarningResp
+	if err := json.Unmarshal(body, &arr); err != nil {
+		return model.DistrictWarning{}, err
+	}
+	if len(arr) == 0 {
+		return model.DistrictWarning{}, fmt.Errorf("empty district warning response")
+	}
+
+	// Assuming the API returns a single object in the array for the district
+	dwResp := arr[0]
+
+	issuedAt := fetchedAt
+	if dwResp.Date != "" && dwResp.UTC != "" {
+		t, err := time.Parse("2006-01-02 15:04:05", dwResp.Date+" "+dwResp.UTC)
+		if err == nil {
+			issuedAt = t
+		}
+	}
+
+	return model.DistrictWarning{
+		Location:      loc,
+		IssuedAt:      issuedAt,
+		Day1Warning:   dwResp.Day1,
+		Day2Warning:   dwResp.Day2,
+		Day3Warning:   dwResp.Day3,
+		Day4Warning:   dwResp.Day4,
+		Day5Warning:   dwResp.Day5,
+		Day1Color:     dwResp.Day1Color,
+		Day2Color:     dwResp.Day2Color,
+		Day3Color:     dwResp.Day3Color,
+		Day4Color:     dwResp.Day4Color,
+		Day5Color:     dwResp.Day5Color,
+		ParserVersion: DistrictWarningParserVersion,
+	}, nil
 }
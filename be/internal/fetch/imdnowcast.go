@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/dryrun"
+	"github.com/lolwierd/weatherboy/be/internal/events"
 	"github.com/lolwierd/weatherboy/be/internal/logger"
 	"github.com/lolwierd/weatherboy/be/internal/model"
 	"github.com/lolwierd/weatherboy/be/internal/repository"
@@ -78,17 +80,19 @@ func colorToPOP(c int) float64 {
 	}
 }
 
-// FetchIMDNowcast fetches nowcast data from the IMD API for Vadodara and stores it.
-func FetchIMDNowcast(ctx context.Context) error {
-	loc, ok := config.LocationByName("vadodara")
-	if !ok || loc.DistrictID == 0 {
-		return fmt.Errorf("district id for vadodara not set")
+// FetchIMDNowcast fetches nowcast data from the IMD API for a location and stores it.
+func FetchIMDNowcast(ctx context.Context, loc config.Location) error {
+	if loc.DistrictID == 0 {
+		return fmt.Errorf("district id for %s not set", loc.Name)
 	}
 	url := fmt.Sprintf("%s?id=%d", imdNowcastBaseURL, loc.DistrictID)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return err
 	}
+	if err := waitIMD(ctx); err != nil {
+		return err
+	}
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
@@ -104,21 +108,61 @@ func FetchIMDNowcast(ctx context.Context) error {
 		return err
 	}
 
-	var arr []districtNowcastResp
-	if err := json.Unmarshal(body, &arr); err != nil {
+	fetchedAt := time.Now()
+	n, cats, err := parseNowcast(loc.Name, body, fetchedAt)
+	if err != nil {
 		return err
 	}
-	if len(arr) == 0 {
-		return fmt.Errorf("empty nowcast response")
+	if d := dryrun.From(ctx); d != nil {
+		d.Add(map[string]any{"nowcast": n, "categories": cats})
+		return nil
+	}
+
+	// The raw payload, the nowcast and its categories are stored together or
+	// not at all.
+	var landed bool
+	stored := repository.InTx(ctx, func(ctx context.Context) error {
+		raw := model.NowcastRaw{
+			Location:  loc.Name,
+			Data:      body,
+			FetchedAt: fetchedAt,
+		}
+		if err := repository.InsertNowcastRaw(ctx, &raw); err != nil {
+			return fmt.Errorf("insert nowcast raw: %w", err)
+		}
+		n.NowcastRawID = &raw.ID
+		landed, err = storeNowcast(ctx, &n, cats)
+		return err
+	})
+	// A nowcast already stored is only refreshed, which is no news to
+	// subscribers.
+	if stored == nil && landed {
+		events.Publish(ctx, events.Event{Type: "nowcast", Location: loc.Name, Data: map[string]any{"captured_at": n.CapturedAt}})
 	}
 
-	raw := model.NowcastRaw{
-		Location:  "vadodara",
-		Data:      body,
-		FetchedAt: time.Now(),
+	call := model.IMDAPICall{
+		Endpoint:    url,
+		Bytes:       int64(len(body)),
+		RequestedAt: time.Now(),
 	}
-	if err := repository.InsertNowcastRaw(ctx, &raw); err != nil {
-		logger.Error.Println("insert nowcast raw:", err)
+	if err := repository.InsertIMDAPICall(ctx, &call); err != nil {
+		logger.Error.Println("repository insert api log:", err)
+	} else {
+		logger.Info.Printf("IMD API call %s bytes=%d", url, len(body))
+	}
+	return stored
+}
+
+// parseNowcast converts a raw IMD nowcast payload of a location into a
+// nowcast row and its category flags. fetchedAt is used as the capture time
+// when the payload carries none.
+func parseNowcast(loc string, body []byte, fetchedAt time.Time) (model.Nowcast, []model.NowcastCategory, error) {
+	var arr []districtNowcastResp
+	if err := json.Unmarshal(body, &arr); err != nil {
+		return model.Nowcast{}, nil, err
+	}
+	if len(arr) == 0 {
+		return model.Nowcast{}, nil, fmt.Errorf("empty nowcast response")
 	}
 
 	col, err := strconv.Atoi(arr[0].Color)
@@ -131,7 +175,7 @@ func FetchIMDNowcast(ctx context.Context) error {
 		pi = 0
 	}
 
-	captured := time.Now()
+	captured := fetchedAt
 	if arr[0].Date != "" && arr[0].TOI != "" {
 		t, err := time.Parse("2006-01-02 1504", arr[0].Date+" "+arr[0].TOI)
 		if err == nil {
@@ -140,24 +184,23 @@ func FetchIMDNowcast(ctx context.Context) error {
 	}
 
 	n := model.Nowcast{
-		Location:   "vadodara",
-		CapturedAt: captured,
-		LeadMin:    0,
-		POP:        colorToPOP(col),
-		MMPerHr:    bucketToMMPerHr(pi),
-	}
-	if err := repository.InsertNowcast(ctx, &n); err != nil {
-		logger.Error.Println("insert nowcast:", err)
+		Location:      loc,
+		CapturedAt:    captured,
+		LeadMin:       0,
+		POP:           colorToPOP(col),
+		MMPerHr:       bucketToMMPerHr(pi),
+		ParserVersion: NowcastParserVersion,
 	}
 
-	// store category flags
-	cats := []string{
+	// category flags
+	var cats []model.NowcastCategory
+	flags := []string{
 		arr[0].Cat1, arr[0].Cat2, arr[0].Cat3, arr[0].Cat4, arr[0].Cat5,
 		arr[0].Cat6, arr[0].Cat7, arr[0].Cat8, arr[0].Cat9, arr[0].Cat10,
 		arr[0].Cat11, arr[0].Cat12, arr[0].Cat13, arr[0].Cat14,
 		arr[0].Cat15, arr[0].Cat16, arr[0].Cat17, arr[0].Cat18, arr[0].Cat19,
 	}
-	for i, v := range cats {
+	for i, v := range flags {
 		if v == "" {
 			continue
 		}
@@ -165,25 +208,28 @@ func FetchIMDNowcast(ctx context.Context) error {
 		if err != nil {
 			continue
 		}
-		cat := model.NowcastCategory{
-			NowcastID: n.ID,
-			Category:  i + 1,
-			Value:     int16(val),
-		}
-		if err := repository.InsertNowcastCategory(ctx, &cat); err != nil {
-			logger.Error.Println("insert nowcast category:", err)
-		}
+		cats = append(cats, model.NowcastCategory{Category: i + 1, Value: int16(val)})
 	}
+	return n, cats, nil
+}
 
-	call := model.IMDAPICall{
-		Endpoint:    url,
-		Bytes:       int64(len(body)),
-		RequestedAt: time.Now(),
-	}
-	if err := repository.InsertIMDAPICall(ctx, &call); err != nil {
-		logger.Error.Println("repository insert api log:", err)
-	} else {
-		logger.Info.Printf("IMD API call %s bytes=%d", url, len(body))
-	}
-	return nil
+// storeNowcast upserts a parsed nowcast row and replaces its category flags
+// in one transaction. It reports whether the nowcast is new.
+func storeNowcast(ctx context.Context, n *model.Nowcast, cats []model.NowcastCategory) (inserted bool, err error) {
+	err = repository.InTx(ctx, func(ctx context.Context) error {
+		if inserted, err = repository.UpsertNowcast(ctx, n); err != nil {
+			return fmt.Errorf("upsert nowcast: %w", err)
+		}
+		if err := repository.DeleteNowcastCategories(ctx, n.ID); err != nil {
+			return fmt.Errorf("delete nowcast categories: %w", err)
+		}
+		for _, cat := range cats {
+			cat.NowcastID = n.ID
+			if err := repository.InsertNowcastCategory(ctx, &cat); err != nil {
+				return fmt.Errorf("insert nowcast category %d: %w", cat.Category, err)
+			}
+		}
+		return nil
+	})
+	return inserted, err
 }
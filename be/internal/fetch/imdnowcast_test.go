@@ -3,6 +3,7 @@ package fetch
 import (
 	"encoding/json"
 	"testing"
+	"time"
 )
 
 func TestBucketToMMPerHr(t *testing.T) {
@@ -25,3 +26,33 @@ func TestNowcastRespCategories(t *testing.T) {
 		t.Fatalf("unexpected fields: %+v", arr[0])
 	}
 }
+
+func TestParseNowcast(t *testing.T) {
+	fetched := time.Date(2024, 6, 20, 9, 0, 0, 0, time.UTC)
+	body := []byte(`[{"Date":"2024-06-20","toi":"0830","color":"3","precip_intensity":"4","cat1":"1","cat2":"","cat3":"x","cat4":"2"}]`)
+	n, cats, err := parseNowcast("vadodara", body, fetched)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if n.Location != "vadodara" || n.POP != 0.8 || n.MMPerHr != 2 {
+		t.Fatalf("unexpected nowcast: %+v", n)
+	}
+	if want := time.Date(2024, 6, 20, 8, 30, 0, 0, time.UTC); !n.CapturedAt.Equal(want) {
+		t.Fatalf("captured at %s, want %s", n.CapturedAt, want)
+	}
+	if len(cats) != 2 || cats[0].Category != 1 || cats[1].Category != 4 || cats[1].Value != 2 {
+		t.Fatalf("unexpected categories: %+v", cats)
+	}
+
+	n, _, err = parseNowcast("vadodara", []byte(`[{"color":"1"}]`), fetched)
+	if err != nil {
+		t.Fatalf("parse without time: %v", err)
+	}
+	if !n.CapturedAt.Equal(fetched) {
+		t.Fatalf("captured at %s, want fetch time %s", n.CapturedAt, fetched)
+	}
+
+	if _, _, err := parseNowcast("vadodara", []byte(`[]`), fetched); err == nil {
+		t.Fatal("expected error for empty payload")
+	}
+}
@@ -0,0 +1,58 @@
+package fetch
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/lolwierd/weatherboy/be/internal/logger"
+)
+
+// defaultIMDRequestInterval is the minimum spacing between two IMD requests
+// when IMD_REQUEST_INTERVAL is not set.
+const defaultIMDRequestInterval = time.Second
+
+var (
+	imdMu       sync.Mutex
+	imdNext     time.Time
+	imdOnce     sync.Once
+	imdInterval time.Duration
+)
+
+func loadIMDRequestInterval() time.Duration {
+	s := os.Getenv("IMD_REQUEST_INTERVAL")
+	if s == "" {
+		return defaultIMDRequestInterval
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		logger.Warn.Printf("invalid IMD_REQUEST_INTERVAL %q, using %s", s, defaultIMDRequestInterval)
+		return defaultIMDRequestInterval
+	}
+	return d
+}
+
+// waitIMD blocks until the next IMD request may be sent, so scheduled
+// fetches and backfills together stay under IMD's rate limit.
+func waitIMD(ctx context.Context) error {
+	imdOnce.Do(func() { imdInterval = loadIMDRequestInterval() })
+
+	imdMu.Lock()
+	now := time.Now()
+	at := imdNext
+	if at.Before(now) {
+		at = now
+	}
+	imdNext = at.Add(imdInterval)
+	imdMu.Unlock()
+
+	t := time.NewTimer(time.Until(at))
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
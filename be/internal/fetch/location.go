@@ -0,0 +1,99 @@
+package fetch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/repository"
+)
+
+// ErrInvalidLocation is returned by CheckLocation when a location refers to
+// IMD identifiers that do not exist.
+var ErrInvalidLocation = errors.New("invalid location")
+
+// CheckLocation checks the IMD identifiers of l against IMD and the AWS/ARG
+// station catalogue: its district must have district warnings, its river
+// basin a QPF listing its sub-basin, and its stations must be catalogued.
+// Unknown identifiers are reported wrapped in ErrInvalidLocation; other errors
+// mean IMD could not be asked.
+func CheckLocation(ctx context.Context, l config.Location) error {
+	var problems []string
+	if l.DistrictID != 0 {
+		var arr []districtWarningResp
+		if err := getIMDJSON(ctx, fmt.Sprintf("%s?id=%d", imdDistrictWarningBaseURL, l.DistrictID), &arr); err != nil {
+			return fmt.Errorf("check district %d: %w", l.DistrictID, err)
+		}
+		if len(arr) == 0 || arr[0].ObjID != strconv.Itoa(l.DistrictID) {
+			problems = append(problems, fmt.Sprintf("unknown IMD district id %d", l.DistrictID))
+		}
+	}
+	if l.RiverBasinID != 0 {
+		var arr []riverBasinResp
+		if err := getIMDJSON(ctx, fmt.Sprintf("%s?id=%d", imdRiverBasinBaseURL, l.RiverBasinID), &arr); err != nil {
+			return fmt.Errorf("check river basin %d: %w", l.RiverBasinID, err)
+		}
+		switch {
+		case len(arr) == 0:
+			problems = append(problems, fmt.Sprintf("unknown IMD river basin id %d", l.RiverBasinID))
+		case l.SubBasinID != 0 && !hasSubBasin(arr, l.SubBasinID):
+			problems = append(problems, fmt.Sprintf("river basin %d has no sub-basin %d", l.RiverBasinID, l.SubBasinID))
+		}
+	}
+	if len(l.AWSStationIDs) > 0 {
+		unknown, err := repository.UnknownAWSStations(ctx, l.AWSStationIDs)
+		if err != nil {
+			return fmt.Errorf("check aws/arg stations: %w", err)
+		}
+		if len(unknown) > 0 {
+			problems = append(problems, "unknown aws/arg stations "+strings.Join(unknown, ", "))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidLocation, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func hasSubBasin(arr []riverBasinResp, id int) bool {
+	for _, r := range arr {
+		if r.ObjID == strconv.Itoa(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// getIMDJSON decodes the JSON served by an IMD API at url into v. An empty
+// body decodes as no rows.
+func getIMDJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if err := waitIMD(ctx); err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("imd status %s: %s", resp.Status, string(body))
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return nil
+	}
+	return json.Unmarshal(body, v)
+}
@@ -0,0 +1,99 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/lolwierd/weatherboy/be/internal/logger"
+	"github.com/lolwierd/weatherboy/be/internal/model"
+	"github.com/lolwierd/weatherboy/be/internal/repository"
+)
+
+// Parser versions stored with every parsed row. Bump a version whenever its
+// parser changes so Reprocess picks up the rows parsed by older code.
+const (
+	NowcastParserVersion         = 1
+	DistrictWarningParserVersion = 1
+	BulletinParserVersion        = 1
+)
+
+// ReprocessSources lists the sources Reprocess supports.
+var ReprocessSources = []string{"bulletin", "nowcast", "district_warning"}
+
+// Reprocess reruns the current parser of a source over the raw payloads
+// fetched at or after since and upserts the parsed rows. Only payloads that
+// were never parsed or were parsed by an older parser version are reprocessed
+// unless force is set.
+func Reprocess(ctx context.Context, source string, since time.Time, force bool) error {
+	var err error
+	n := 0
+	switch source {
+	case "nowcast":
+		err = repository.StreamNowcastRaw(ctx, since, reprocessBelow(NowcastParserVersion, force), func(r model.NowcastRaw) error {
+			nc, cats, err := parseNowcast(r.Location, r.Data, r.FetchedAt)
+			if err != nil {
+				logger.Warn.Printf("skipping nowcast raw %d: %v", r.ID, err)
+				return nil
+			}
+			nc.NowcastRawID = &r.ID
+			if _, err := storeNowcast(ctx, &nc, cats); err != nil {
+				return fmt.Errorf("nowcast raw %d: %w", r.ID, err)
+			}
+			n++
+			return nil
+		})
+	case "district_warning":
+		err = repository.StreamDistrictWarningRaw(ctx, since, reprocessBelow(DistrictWarningParserVersion, force), func(r model.DistrictWarningRaw) error {
+			dw, err := parseDistrictWarning(r.Location, r.Data, r.FetchedAt)
+			if err != nil {
+				logger.Warn.Printf("skipping district warning raw %d: %v", r.ID, err)
+				return nil
+			}
+			dw.DistrictWarningRawID = &r.ID
+			if _, err := repository.UpsertDistrictWarning(ctx, &dw); err != nil {
+				return fmt.Errorf("district warning raw %d: %w", r.ID, err)
+			}
+			n++
+			return nil
+		})
+	case "bulletin":
+		err = repository.StreamBulletinRaw(ctx, since, "vadodara", reprocessBelow(BulletinParserVersion, force), func(r model.BulletinRaw) error {
+			data, err := loadBulletin(ctx, r)
+			if err != nil {
+				logger.Warn.Printf("skipping bulletin raw %d: load: %v", r.ID, err)
+				return nil
+			}
+			forecast, err := parseBulletin(ctx, data)
+			if err != nil {
+				logger.Warn.Printf("skipping bulletin raw %d: %v", r.ID, err)
+				return nil
+			}
+			bp := model.BulletinParsed{
+				BulletinRawID: r.ID,
+				Location:      "vadodara",
+				Forecast:      forecast,
+				ParserVersion: BulletinParserVersion,
+			}
+			if _, err := repository.UpsertParsedBulletin(ctx, &bp); err != nil {
+				return fmt.Errorf("bulletin raw %d: %w", r.ID, err)
+			}
+			n++
+			return nil
+		})
+	default:
+		return fmt.Errorf("unknown reprocess source %q, want one of %s", source, strings.Join(ReprocessSources, ", "))
+	}
+	logger.Info.Printf("reprocessed %d %s payloads", n, source)
+	return err
+}
+
+// reprocessBelow returns the parser version below which rows are reprocessed.
+func reprocessBelow(version int, force bool) int {
+	if force {
+		return math.MaxInt32
+	}
+	return version
+}
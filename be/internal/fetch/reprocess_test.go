@@ -0,0 +1,107 @@
+package fetch
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	pgxmock "github.com/pashagolub/pgxmock/v4"
+
+	"github.com/lolwierd/weatherboy/be/internal/db"
+)
+
+func setupMock(t *testing.T) pgxmock.PgxPoolIface {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetDBDriver(&db.Driver{ConnPool: mock})
+	return mock
+}
+
+var rawColumns = []string{"id", "location", "data", "fetched_at"}
+
+func TestReprocessNowcast(t *testing.T) {
+	mock := setupMock(t)
+	defer mock.Close()
+
+	since := time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC)
+	fetched := since.Add(9 * time.Hour)
+	captured := time.Date(2024, 6, 20, 8, 30, 0, 0, time.UTC)
+	mock.ExpectQuery("FROM nowcast_raw").
+		WithArgs(since, NowcastParserVersion).
+		WillReturnRows(pgxmock.NewRows(rawColumns).
+			AddRow(4, "vadodara", []byte(`[{"Date":"2024-06-20","toi":"0830","color":"3","precip_intensity":"4","cat1":"1"}]`), fetched).
+			AddRow(5, "vadodara", []byte(`not json`), fetched))
+	rawID := 4
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO nowcast").
+		WithArgs("vadodara", captured, 0, 0.8, 2.0, &rawID, NowcastParserVersion).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "created_at", "inserted"}).AddRow(9, fetched, false))
+	mock.ExpectExec("DELETE FROM nowcast_category").
+		WithArgs(9).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectQuery("INSERT INTO nowcast_category").
+		WithArgs(9, 1, int16(1)).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	// The unparseable payload is skipped rather than failing the run.
+	if err := Reprocess(context.Background(), "nowcast", since, false); err != nil {
+		t.Fatalf("reprocess: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestReprocessForceSelectsEveryVersion(t *testing.T) {
+	mock := setupMock(t)
+	defer mock.Close()
+
+	since := time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("FROM district_warning_raw").
+		WithArgs(since, math.MaxInt32).
+		WillReturnRows(pgxmock.NewRows(rawColumns))
+
+	if err := Reprocess(context.Background(), "district_warning", since, true); err != nil {
+		t.Fatalf("reprocess: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestReprocessDistrictWarning(t *testing.T) {
+	mock := setupMock(t)
+	defer mock.Close()
+
+	since := time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC)
+	fetched := since.Add(9 * time.Hour)
+	issued := time.Date(2024, 6, 20, 6, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("FROM district_warning_raw").
+		WithArgs(since, DistrictWarningParserVersion).
+		WillReturnRows(pgxmock.NewRows(rawColumns).
+			AddRow(2, "vadodara", []byte(`[{"Date":"2024-06-20","UTC":"06:00:00","Day_1":"Heavy Rain","Day1_Color":"Orange"}]`), fetched))
+	rawID := 2
+	mock.ExpectQuery("INSERT INTO district_warning").
+		WithArgs("vadodara", issued, "Heavy Rain", "", "", "", "", "Orange", "", "", "", "", &rawID, DistrictWarningParserVersion).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "created_at", "inserted"}).AddRow(3, fetched, false))
+
+	if err := Reprocess(context.Background(), "district_warning", since, false); err != nil {
+		t.Fatalf("reprocess: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestReprocessUnknownSource(t *testing.T) {
+	if err := Reprocess(context.Background(), "radar", time.Now(), false); err == nil {
+		t.Fatal("expected error for unknown source")
+	}
+}
@@ -3,12 +3,16 @@ package fetch
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/dryrun"
+	"github.com/lolwierd/weatherboy/be/internal/events"
 	"github.com/lolwierd/weatherboy/be/internal/logger"
 	"github.com/lolwierd/weatherboy/be/internal/model"
 	"github.com/lolwierd/weatherboy/be/internal/repository"
@@ -31,6 +35,17 @@ type riverBasinResp struct {
 	AAP      string `json:"AAP"`
 }
 
+// FetchRiverBasins fetches the QPF for each of the given river basins.
+func FetchRiverBasins(ctx context.Context, basinIDs []int) error {
+	var errs []error
+	for _, id := range basinIDs {
+		if err := FetchRiverBasinOnce(ctx, id); err != nil {
+			errs = append(errs, fmt.Errorf("basin %d: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // FetchRiverBasinOnce fetches river basin data from the IMD API and stores it.
 func FetchRiverBasinOnce(ctx context.Context, basinID int) error {
 	url := fmt.Sprintf("%s?id=%d", imdRiverBasinBaseURL, basinID)
@@ -38,6 +53,9 @@ func FetchRiverBasinOnce(ctx context.Context, basinID int) error {
 	if err != nil {
 		return err
 	}
+	if err := waitIMD(ctx); err != nil {
+		return err
+	}
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
@@ -61,8 +79,9 @@ func FetchRiverBasinOnce(ctx context.Context, basinID int) error {
 		return fmt.Errorf("empty river basin response")
 	}
 
+	qpfs := make([]model.RiverBasinQPF, 0, len(arr))
 	for _, r := range arr {
-		basinID, err := strconv.Atoi(r.ObjID)
+		subBasinID, err := strconv.Atoi(r.ObjID)
 		if err != nil {
 			return err
 		}
@@ -71,21 +90,47 @@ func FetchRiverBasinOnce(ctx context.Context, basinID int) error {
 			return err
 		}
 		qpf := model.RiverBasinQPF{
-			BasinID:  basinID,
-			Date:     date,
-			FMO:      r.FMO,
-			Basin:    r.Basin,
-			SubBasin: r.SubBasin,
-			Area:     r.Area,
-			Day1:     r.Day1,
-			Day2:     r.Day2,
-			Day3:     r.Day3,
-			Day4:     r.Day4,
-			Day5:     r.Day5,
-			AAP:      r.AAP,
+			BasinID:    basinID,
+			SubBasinID: subBasinID,
+			Date:       date,
+			FMO:        r.FMO,
+			Basin:      r.Basin,
+			SubBasin:   r.SubBasin,
+			Area:       r.Area,
+			Day1:       r.Day1,
+			Day2:       r.Day2,
+			Day3:       r.Day3,
+			Day4:       r.Day4,
+			Day5:       r.Day5,
+			AAP:        r.AAP,
 		}
-		if err := repository.InsertRiverBasinQPF(ctx, &qpf); err != nil {
-			return err
+		qpfs = append(qpfs, qpf)
+	}
+	if d := dryrun.From(ctx); d != nil {
+		d.Add(qpfs)
+		return nil
+	}
+	var landed bool
+	err = repository.InTx(ctx, func(ctx context.Context) error {
+		for i := range qpfs {
+			inserted, err := repository.UpsertRiverBasinQPF(ctx, &qpfs[i])
+			if err != nil {
+				return err
+			}
+			landed = landed || inserted
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// A forecast date already stored is only refreshed, which is no news to
+	// the streams.
+	if landed {
+		for _, l := range config.Locations() {
+			if l.RiverBasinID == basinID {
+				events.Publish(ctx, events.Event{Type: "river_basin", Location: l.Name, Data: map[string]any{"basin_id": basinID, "date": qpfs[0].Date}})
+			}
 		}
 	}
 
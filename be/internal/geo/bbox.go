@@ -0,0 +1,72 @@
+package geo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BBox is a latitude/longitude bounding box.
+type BBox struct {
+	MinLat, MinLon float64
+	MaxLat, MaxLon float64
+}
+
+// ParseBBox parses a box given as "minLon,minLat,maxLon,maxLat", the order
+// of a GeoJSON bbox.
+func ParseBBox(s string) (BBox, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return BBox{}, fmt.Errorf("bbox %q: want minLon,minLat,maxLon,maxLat", s)
+	}
+	var v [4]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return BBox{}, fmt.Errorf("bbox %q: %w", s, err)
+		}
+		v[i] = f
+	}
+	b := BBox{MinLon: v[0], MinLat: v[1], MaxLon: v[2], MaxLat: v[3]}
+	if b.MinLat > b.MaxLat || b.MinLon > b.MaxLon {
+		return BBox{}, fmt.Errorf("bbox %q: min exceeds max", s)
+	}
+	return b, nil
+}
+
+// Contains reports whether the point lies inside the box, edges included.
+func (b BBox) Contains(lat, lon float64) bool {
+	return lat >= b.MinLat && lat <= b.MaxLat && lon >= b.MinLon && lon <= b.MaxLon
+}
+
+// Intersects reports whether the boxes overlap.
+func (b BBox) Intersects(o BBox) bool {
+	return b.MinLat <= o.MaxLat && o.MinLat <= b.MaxLat && b.MinLon <= o.MaxLon && o.MinLon <= b.MaxLon
+}
+
+// GeoJSON returns the box in GeoJSON bbox order.
+func (b BBox) GeoJSON() []float64 {
+	return []float64{b.MinLon, b.MinLat, b.MaxLon, b.MaxLat}
+}
+
+// bboxOf returns the box bounding every outer ring of polygons.
+func bboxOf(polygons []Polygon) BBox {
+	first := true
+	var b BBox
+	for _, p := range polygons {
+		if len(p) == 0 {
+			continue
+		}
+		for _, pt := range p[0] {
+			lon, lat := pt[0], pt[1]
+			if first {
+				b = BBox{MinLat: lat, MinLon: lon, MaxLat: lat, MaxLon: lon}
+				first = false
+				continue
+			}
+			b.MinLat, b.MaxLat = min(b.MinLat, lat), max(b.MaxLat, lat)
+			b.MinLon, b.MaxLon = min(b.MinLon, lon), max(b.MaxLon, lon)
+		}
+	}
+	return b
+}
@@ -0,0 +1,47 @@
+package geo
+
+import (
+	"math"
+	"sort"
+)
+
+// earthRadiusKM is the mean Earth radius used for great-circle distances.
+const earthRadiusKM = 6371.0
+
+// HaversineKM returns the great-circle distance in kilometres between two
+// points given in decimal degrees.
+func HaversineKM(lat1, lon1, lat2, lon2 float64) float64 {
+	rlat1 := lat1 * math.Pi / 180
+	rlat2 := lat2 * math.Pi / 180
+	dlat := (lat2 - lat1) * math.Pi / 180
+	dlon := (lon2 - lon1) * math.Pi / 180
+
+	a := math.Sin(dlat/2)*math.Sin(dlat/2) + math.Cos(rlat1)*math.Cos(rlat2)*math.Sin(dlon/2)*math.Sin(dlon/2)
+	return 2 * earthRadiusKM * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// Neighbor is a point found by Nearest, identified by its index in the
+// searched collection.
+type Neighbor struct {
+	Index      int
+	DistanceKM float64
+}
+
+// Nearest returns up to k of the n points given by pos that lie within
+// radiusKM of (lat, lon), closest first. A non-positive k returns every point
+// within the radius.
+func Nearest(n int, pos func(i int) (lat, lon float64), lat, lon float64, k int, radiusKM float64) []Neighbor {
+	var out []Neighbor
+	for i := 0; i < n; i++ {
+		plat, plon := pos(i)
+		d := HaversineKM(lat, lon, plat, plon)
+		if d <= radiusKM {
+			out = append(out, Neighbor{Index: i, DistanceKM: d})
+		}
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].DistanceKM < out[b].DistanceKM })
+	if k > 0 && len(out) > k {
+		out = out[:k]
+	}
+	return out
+}
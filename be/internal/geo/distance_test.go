@@ -0,0 +1,34 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHaversineKM(t *testing.T) {
+	if d := HaversineKM(22.30, 73.20, 22.30, 73.20); d != 0 {
+		t.Fatalf("expected zero distance, got %f", d)
+	}
+	// Vadodara to Mumbai is roughly 360km as the crow flies.
+	d := HaversineKM(22.30, 73.20, 19.08, 72.88)
+	if math.Abs(d-359) > 5 {
+		t.Fatalf("unexpected vadodara-mumbai distance %f", d)
+	}
+}
+
+func TestNearest(t *testing.T) {
+	pts := [][2]float64{
+		{19.08, 72.88}, // mumbai
+		{22.31, 73.18}, // vadodara
+		{22.99, 72.60}, // ahmedabad
+	}
+	pos := func(i int) (float64, float64) { return pts[i][0], pts[i][1] }
+
+	got := Nearest(len(pts), pos, 22.30, 73.20, 2, 150)
+	if len(got) != 2 || got[0].Index != 1 || got[1].Index != 2 {
+		t.Fatalf("unexpected neighbours: %+v", got)
+	}
+	if got := Nearest(len(pts), pos, 22.30, 73.20, 0, 10); len(got) != 1 {
+		t.Fatalf("expected only vadodara within 10km, got %+v", got)
+	}
+}
@@ -0,0 +1,160 @@
+package geo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// ErrNoDistricts is returned when no district boundaries are loaded.
+var ErrNoDistricts = errors.New("no district boundaries loaded")
+
+// District is the boundary of an IMD district.
+type District struct {
+	// ID is the IMD district id, zero when not known.
+	ID       int
+	Name     string
+	State    string
+	Polygons []Polygon
+	// BBox bounds the district.
+	BBox BBox
+}
+
+// Contains reports whether the point lies inside the district.
+func (d District) Contains(lat, lon float64) bool {
+	if !d.BBox.Contains(lat, lon) {
+		return false
+	}
+	for _, p := range d.Polygons {
+		if p.Contains(lat, lon) {
+			return true
+		}
+	}
+	return false
+}
+
+type featureCollection struct {
+	Features []struct {
+		Properties struct {
+			ID       *int   `json:"id"`
+			District string `json:"district"`
+			State    string `json:"state"`
+		} `json:"properties"`
+		Geometry struct {
+			Type        string          `json:"type"`
+			Coordinates json.RawMessage `json:"coordinates"`
+		} `json:"geometry"`
+	} `json:"features"`
+}
+
+// parseDistricts reads districts from a GeoJSON FeatureCollection of Polygon
+// and MultiPolygon features.
+func parseDistricts(b []byte) ([]District, error) {
+	var fc featureCollection
+	if err := json.Unmarshal(b, &fc); err != nil {
+		return nil, err
+	}
+	districts := make([]District, 0, len(fc.Features))
+	for i, f := range fc.Features {
+		d := District{Name: f.Properties.District, State: f.Properties.State}
+		if f.Properties.ID != nil {
+			d.ID = *f.Properties.ID
+		}
+		switch f.Geometry.Type {
+		case "Polygon":
+			var p Polygon
+			if err := json.Unmarshal(f.Geometry.Coordinates, &p); err != nil {
+				return nil, fmt.Errorf("feature %d: %w", i, err)
+			}
+			d.Polygons = []Polygon{p}
+		case "MultiPolygon":
+			if err := json.Unmarshal(f.Geometry.Coordinates, &d.Polygons); err != nil {
+				return nil, fmt.Errorf("feature %d: %w", i, err)
+			}
+		default:
+			return nil, fmt.Errorf("feature %d: unsupported geometry %q", i, f.Geometry.Type)
+		}
+		d.BBox = bboxOf(d.Polygons)
+		districts = append(districts, d)
+	}
+	return districts, nil
+}
+
+var (
+	districtsMu     sync.RWMutex
+	loadedDistricts []District
+)
+
+// LoadDistricts loads the district boundaries from the GeoJSON
+// FeatureCollection at path, replacing any loaded before. Features are
+// Polygons or MultiPolygons whose properties carry the IMD district id
+// ("id"), the district ("district") and state ("state") names.
+func LoadDistricts(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	ds, err := parseDistricts(b)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	districtsMu.Lock()
+	loadedDistricts = ds
+	districtsMu.Unlock()
+	return nil
+}
+
+// Districts returns the loaded district boundaries.
+func Districts() ([]District, error) {
+	districtsMu.RLock()
+	defer districtsMu.RUnlock()
+	if loadedDistricts == nil {
+		return nil, ErrNoDistricts
+	}
+	return loadedDistricts, nil
+}
+
+// DistrictAt returns the district containing the point.
+func DistrictAt(lat, lon float64) (District, bool, error) {
+	ds, err := Districts()
+	if err != nil {
+		return District{}, false, err
+	}
+	for _, d := range ds {
+		if d.Contains(lat, lon) {
+			return d, true, nil
+		}
+	}
+	return District{}, false, nil
+}
+
+// DistrictByID returns the district with the given IMD district id.
+func DistrictByID(id int) (District, bool, error) {
+	ds, err := Districts()
+	if err != nil || id == 0 {
+		return District{}, false, err
+	}
+	for _, d := range ds {
+		if d.ID == id {
+			return d, true, nil
+		}
+	}
+	return District{}, false, nil
+}
+
+// DistrictsIn returns the districts whose bounding box overlaps b.
+func DistrictsIn(b BBox) ([]District, error) {
+	ds, err := Districts()
+	if err != nil {
+		return nil, err
+	}
+	var in []District
+	for _, d := range ds {
+		if d.BBox.Intersects(b) {
+			in = append(in, d)
+		}
+	}
+	return in, nil
+}
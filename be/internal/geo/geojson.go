@@ -0,0 +1,57 @@
+package geo
+
+// Feature is a GeoJSON feature.
+type Feature struct {
+	Type       string         `json:"type"`
+	BBox       []float64      `json:"bbox,omitempty"`
+	Geometry   Geometry       `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+// Geometry is a GeoJSON geometry.
+type Geometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// FeatureCollection is a GeoJSON feature collection.
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// NewFeatureCollection returns a collection of features, never encoded as
+// null.
+func NewFeatureCollection(features []Feature) FeatureCollection {
+	if features == nil {
+		features = []Feature{}
+	}
+	return FeatureCollection{Type: "FeatureCollection", Features: features}
+}
+
+// PointFeature returns a point feature at (lat, lon).
+func PointFeature(lat, lon float64, props map[string]any) Feature {
+	return Feature{
+		Type:       "Feature",
+		Geometry:   Geometry{Type: "Point", Coordinates: [2]float64{lon, lat}},
+		Properties: props,
+	}
+}
+
+// Feature returns the district boundary as a MultiPolygon feature. Its id,
+// name and state are added to props.
+func (d District) Feature(props map[string]any) Feature {
+	p := map[string]any{"id": nil, "district": d.Name, "state": d.State}
+	if d.ID != 0 {
+		p["id"] = d.ID
+	}
+	for k, v := range props {
+		p[k] = v
+	}
+	return Feature{
+		Type:       "Feature",
+		BBox:       d.BBox.GeoJSON(),
+		Geometry:   Geometry{Type: "MultiPolygon", Coordinates: d.Polygons},
+		Properties: p,
+	}
+}
@@ -0,0 +1,35 @@
+package geo
+
+// Ring is a closed ring of [lon, lat] positions, in GeoJSON order.
+type Ring [][2]float64
+
+// Polygon is an outer ring followed by the rings of its holes.
+type Polygon []Ring
+
+// Contains reports whether the point lies inside the ring, by casting a ray
+// east of it and counting the edges it crosses.
+func (r Ring) Contains(lat, lon float64) bool {
+	in := false
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		xi, yi := r[i][0], r[i][1]
+		xj, yj := r[j][0], r[j][1]
+		if (yi > lat) != (yj > lat) && lon < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			in = !in
+		}
+	}
+	return in
+}
+
+// Contains reports whether the point lies inside the outer ring and outside
+// every hole.
+func (p Polygon) Contains(lat, lon float64) bool {
+	if len(p) == 0 || !p[0].Contains(lat, lon) {
+		return false
+	}
+	for _, hole := range p[1:] {
+		if hole.Contains(lat, lon) {
+			return false
+		}
+	}
+	return true
+}
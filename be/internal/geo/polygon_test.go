@@ -0,0 +1,100 @@
+package geo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPolygonContains(t *testing.T) {
+	outer := Ring{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}
+	hole := Ring{{4, 4}, {6, 4}, {6, 6}, {4, 6}, {4, 4}}
+	p := Polygon{outer, hole}
+
+	cases := []struct {
+		lat, lon float64
+		want     bool
+	}{
+		{2, 2, true},
+		{5, 5, false},  // in the hole
+		{5, 11, false}, // east of the polygon
+		{-1, 5, false}, // south of the polygon
+	}
+	for _, tc := range cases {
+		if got := p.Contains(tc.lat, tc.lon); got != tc.want {
+			t.Errorf("Contains(%g, %g) = %v, want %v", tc.lat, tc.lon, got, tc.want)
+		}
+	}
+}
+
+// loadTestDistricts loads hand-drawn outlines of the districts around the
+// default locations. Only Vadodara's carries an IMD id (244).
+func loadTestDistricts(t *testing.T) {
+	t.Helper()
+	if err := LoadDistricts("testdata/districts.geojson"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { loadedDistricts = nil })
+}
+
+func TestDistrictAt(t *testing.T) {
+	loadTestDistricts(t)
+	d, ok, err := DistrictAt(22.30, 73.20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || d.ID != 244 || d.Name != "Vadodara" {
+		t.Fatalf("vadodara resolved to %+v, %v", d, ok)
+	}
+	if _, ok, _ := DistrictAt(28.61, 77.21); ok {
+		t.Error("delhi should lie outside the loaded districts")
+	}
+}
+
+func TestDistrictsInBBox(t *testing.T) {
+	loadTestDistricts(t)
+	b, err := ParseBBox("72.7,18.8,73.0,19.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds, err := DistrictsIn(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, d := range ds {
+		names[d.Name] = true
+	}
+	if !names["Mumbai City"] || !names["Mumbai Suburban"] || !names["Thane"] || names["Vadodara"] {
+		t.Fatalf("districts in mumbai bbox = %v", names)
+	}
+	if _, err := ParseBBox("73,19,72,18"); err == nil {
+		t.Error("inverted bbox should not parse")
+	}
+}
+
+func TestLoadDistricts(t *testing.T) {
+	if _, err := Districts(); !errors.Is(err, ErrNoDistricts) {
+		t.Fatalf("districts before loading: %v, want %v", err, ErrNoDistricts)
+	}
+	loadTestDistricts(t)
+
+	p := filepath.Join(t.TempDir(), "districts.geojson")
+	body := `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{"id":7,"district":"Square","state":"Nowhere"},
+"geometry":{"type":"MultiPolygon","coordinates":[[[[10,10],[11,10],[11,11],[10,11],[10,10]]]]}}]}`
+	if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadDistricts(p); err != nil {
+		t.Fatal(err)
+	}
+
+	d, ok, err := DistrictByID(7)
+	if err != nil || !ok || d.BBox != (BBox{MinLat: 10, MinLon: 10, MaxLat: 11, MaxLon: 11}) {
+		t.Fatalf("district 7 = %+v, %v, %v", d, ok, err)
+	}
+	if _, ok, _ := DistrictByID(244); ok {
+		t.Error("loaded set should replace the one loaded before")
+	}
+}
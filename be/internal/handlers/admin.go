@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/logger"
+	"github.com/lolwierd/weatherboy/be/internal/model"
+	"github.com/lolwierd/weatherboy/be/internal/repository"
+	"github.com/lolwierd/weatherboy/be/internal/scheduler"
+)
+
+// AdminAuth rejects requests that do not carry the admin token as a bearer
+// token.
+func AdminAuth(c *fiber.Ctx) error {
+	token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	if !ok || config.AdminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(config.AdminToken)) != 1 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	return c.Next()
+}
+
+// defaultJobRuns is the number of runs GetAdminJobRuns returns by default.
+const defaultJobRuns = 20
+
+// GetAdminJobs lists the registered scheduler jobs with their next run and
+// their last successful and failed runs, and whether this replica is the
+// scheduler leader.
+func GetAdminJobs(c *fiber.Ctx) error {
+	successes, err := repository.LatestJobRuns(c.Context(), model.JobRunSuccess)
+	if err != nil {
+		logger.Error.Println("job run fetch:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	failures, err := repository.LatestJobRuns(c.Context(), model.JobRunFailure)
+	if err != nil {
+		logger.Error.Println("job run fetch:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	jobs := make([]fiber.Map, 0)
+	for _, j := range scheduler.Jobs() {
+		job := fiber.Map{
+			"name":         j.Name,
+			"spec":         j.Spec,
+			"next_run":     j.NextRun,
+			"last_success": nil,
+			"last_failure": nil,
+		}
+		if r, ok := successes[j.Name]; ok {
+			job["last_success"] = r
+		}
+		if r, ok := failures[j.Name]; ok {
+			job["last_failure"] = r
+		}
+		jobs = append(jobs, job)
+	}
+	return c.JSON(fiber.Map{"leader": scheduler.IsLeader(), "jobs": jobs})
+}
+
+// GetAdminJobRuns returns the latest runs of a job, newest first. `limit`
+// defaults to 20.
+func GetAdminJobRuns(c *fiber.Ctx) error {
+	name := c.Params("name")
+	if _, ok := scheduler.Lookup(name); !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "unknown job " + name})
+	}
+	limit := c.QueryInt("limit", defaultJobRuns)
+	if limit <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "limit must be positive"})
+	}
+
+	runs, err := repository.JobRuns(c.Context(), name, limit)
+	if err != nil {
+		logger.Error.Println("job run fetch:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"name": name, "runs": runs})
+}
+
+// PostAdminJobRun runs a job now, or answers 409 while a run of it is in
+// flight. With `dry_run=true` the job fetches and parses but stores nothing,
+// and the parsed rows are returned instead. The run does not stop when the
+// client goes away, so a job is never cut off halfway through its writes.
+func PostAdminJobRun(c *fiber.Ctx) error {
+	name := c.Params("name")
+	if c.QueryBool("dry_run") {
+		results, err := scheduler.DryRun(context.Background(), name)
+		if errors.Is(err, scheduler.ErrUnknownJob) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			logger.Error.Println("dry run", name+":", err)
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"name": name, "error": err.Error(), "results": results})
+		}
+		return c.JSON(fiber.Map{"name": name, "dry_run": true, "results": results})
+	}
+
+	run, err := scheduler.RunNow(context.Background(), name)
+	if errors.Is(err, scheduler.ErrUnknownJob) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if errors.Is(err, scheduler.ErrJobRunning) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"name": name, "error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"name": name, "error": err.Error(), "run": run})
+	}
+	return c.JSON(fiber.Map{"name": name, "run": run})
+}
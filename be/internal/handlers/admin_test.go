@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/lolwierd/weatherboy/be/internal/config"
+)
+
+func TestAdminAuth(t *testing.T) {
+	app := fiber.New()
+	app.Get("/admin", AdminAuth, func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) })
+
+	defer func(token string) { config.AdminToken = token }(config.AdminToken)
+	cases := []struct {
+		name, token, header string
+		want                int
+	}{
+		{"valid", "secret", "Bearer secret", fiber.StatusNoContent},
+		{"missing", "secret", "", fiber.StatusUnauthorized},
+		{"wrong", "secret", "Bearer guess", fiber.StatusUnauthorized},
+		{"not bearer", "secret", "secret", fiber.StatusUnauthorized},
+		{"unset token", "", "Bearer ", fiber.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		config.AdminToken = tc.token
+		req := httptest.NewRequest("GET", "/admin", nil)
+		if tc.header != "" {
+			req.Header.Set(fiber.HeaderAuthorization, tc.header)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, resp.StatusCode, tc.want)
+		}
+	}
+}
@@ -1,17 +1,125 @@
 package handlers
 
 import (
+	"strings"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/lolwierd/weatherboy/be/internal/logger"
+	"github.com/lolwierd/weatherboy/be/internal/model"
+	"github.com/lolwierd/weatherboy/be/internal/rainfall"
 	"github.com/lolwierd/weatherboy/be/internal/repository"
+	"github.com/lolwierd/weatherboy/be/internal/score"
 )
 
+// GetAWSARG returns the latest observation of each AWS/ARG station resolved
+// for a location, each with its QC flags, along with their aggregate. `agg`
+// is one of max, mean or idw (default).
 func GetAWSARG(c *fiber.Ctx) error {
-	loc := c.Params("loc")
-	r, err := repository.LatestAWSARG(c.Context(), loc)
+	loc := locParam(c)
+	agg, err := score.ParseAggregation(c.Query("agg", string(score.AggIDW)))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	obs, err := repository.AWSARGForLocation(c.Context(), loc)
 	if err != nil {
 		logger.Error.Println("aws/arg fetch:", err)
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not found"})
 	}
-	return c.JSON(r)
+	a, err := score.AggregateAWSARG(obs, agg)
+	if err != nil {
+		logger.Error.Println("aws/arg aggregate:", err)
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not found"})
+	}
+	return c.JSON(withResolution(c, fiber.Map{
+		"location":    loc,
+		"aggregation": agg,
+		"aggregate":   a,
+		"stations":    obs,
+	}))
+}
+
+// GetAWSARGRainfall returns the latest rainfall accumulation over `window`
+// (1h, 3h, 24h or since_0830; default 24h) of each AWS/ARG station resolved
+// for a location along with their aggregate.
+func GetAWSARGRainfall(c *fiber.Ctx) error {
+	loc := locParam(c)
+	window, err := rainfall.ParseWindow(c.Query("window", string(rainfall.Window24H)))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	agg, err := score.ParseAggregation(c.Query("agg", string(score.AggIDW)))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	rs, err := repository.AWSRainfallForLocation(c.Context(), loc, string(window))
+	if err != nil {
+		logger.Error.Println("aws/arg rainfall fetch:", err)
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not found"})
+	}
+	mm, err := score.AggregateRainfall(rs, agg)
+	if err != nil {
+		logger.Error.Println("aws/arg rainfall aggregate:", err)
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not found"})
+	}
+	return c.JSON(withResolution(c, fiber.Map{
+		"location":    loc,
+		"window":      window,
+		"aggregation": agg,
+		"mm":          mm,
+		"stations":    rs,
+	}))
+}
+
+// maxSeriesPoints caps the buckets returned per field by GetAWSARGSeries.
+const maxSeriesPoints = 500
+
+// GetAWSARGSeries returns min/max/mean/last series of the requested `fields`
+// (comma separated, default temp,rh,wind_speed) over the AWS/ARG stations
+// resolved for a location, or only `station` when given. Buckets are `step`
+// wide (default 1h) and widened when the range would exceed maxSeriesPoints.
+func GetAWSARGSeries(c *fiber.Ctx) error {
+	loc := locParam(c)
+	from, to, err := parseTimeRange(c, 24*time.Hour)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	step, err := time.ParseDuration(c.Query("step", "1h"))
+	if err != nil || step < time.Minute {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "step must be a duration of at least 1m"})
+	}
+	step = fitStep(from, to, step, maxSeriesPoints)
+
+	fields := strings.Split(c.Query("fields", "temp,rh,wind_speed"), ",")
+	for _, f := range fields {
+		if _, ok := repository.AWSARGSeriesFields[f]; !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "unknown field " + f})
+		}
+	}
+
+	var ids []string
+	if s := c.Query("station"); s != "" {
+		ids = []string{s}
+	} else if ids, err = repository.AWSStationIDsForLocation(c.Context(), loc); err != nil {
+		logger.Error.Println("aws/arg series stations:", err)
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not found"})
+	}
+
+	series := make(map[string][]model.SeriesPoint, len(fields))
+	for _, f := range fields {
+		points, err := repository.AWSARGSeries(c.Context(), ids, f, from, to, step)
+		if err != nil {
+			logger.Error.Println("aws/arg series fetch:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		series[f] = points
+	}
+	return c.JSON(withResolution(c, fiber.Map{
+		"location": loc,
+		"from":     from,
+		"to":       to,
+		"step":     step.String(),
+		"stations": ids,
+		"series":   series,
+	}))
 }
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/geo"
+	"github.com/lolwierd/weatherboy/be/internal/logger"
+	"github.com/lolwierd/weatherboy/be/internal/model"
+	"github.com/lolwierd/weatherboy/be/internal/repository"
+)
+
+// GetDistrict returns the boundary of an IMD district as a GeoJSON feature
+// carrying the colour and hazards of its latest district warning for `day`,
+// 1 (today, the default) to 5, counted in the timezone of the warning's
+// location. Both are null when no warning stored for the district covers
+// that day. Only districts whose IMD id is in the loaded boundary set are
+// found.
+func GetDistrict(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid district id"})
+	}
+	day, err := warningDayParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	d, ok, err := geo.DistrictByID(id)
+	if err != nil {
+		logger.Error.Println("district boundaries:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no boundary for district"})
+	}
+
+	dw, err := repository.LatestDistrictWarningForDistrict(c.Context(), id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		logger.Error.Println("district warning fetch:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(d.Feature(warningProperties(dw, day, time.Now())))
+}
+
+// warningDayParam reads the forecast day of a district warning, 1 to 5.
+func warningDayParam(c *fiber.Ctx) (int, error) {
+	day := c.QueryInt("day", 1)
+	if day < 1 || day > 5 {
+		return 0, errors.New("day must be between 1 and 5")
+	}
+	return day, nil
+}
+
+// warningProperties returns the feature properties of dw for day, counted
+// from the local date of now in the zone of dw's location. A nil dw, or one
+// whose forecast days do not reach that far, yields null properties.
+func warningProperties(dw *model.DistrictWarning, day int, now time.Time) map[string]any {
+	props := map[string]any{"day": day, "color": nil, "hazards": nil, "warning": nil, "issued_at": nil}
+	if dw == nil {
+		return props
+	}
+	l, _ := config.LocationByName(dw.Location)
+	props["issued_at"] = dw.IssuedAt
+	day += dw.ForecastDay(now, l.Zone()) - 1
+	if day < 1 || day > 5 {
+		return props
+	}
+	warning, color := warningForDay(dw, day)
+	props["color"] = strings.ToLower(color)
+	props["hazards"] = hazards(warning)
+	props["warning"] = warning
+	return props
+}
+
+// warningForDay returns the warning text and colour of dw for day.
+func warningForDay(dw *model.DistrictWarning, day int) (warning, color string) {
+	switch day {
+	case 1:
+		return dw.Day1Warning, dw.Day1Color
+	case 2:
+		return dw.Day2Warning, dw.Day2Color
+	case 3:
+		return dw.Day3Warning, dw.Day3Color
+	case 4:
+		return dw.Day4Warning, dw.Day4Color
+	default:
+		return dw.Day5Warning, dw.Day5Color
+	}
+}
+
+// hazards splits the comma-separated hazards of a warning, dropping IMD's
+// "No Warning".
+func hazards(warning string) []string {
+	list := make([]string, 0)
+	for _, h := range strings.Split(warning, ",") {
+		h = strings.TrimSpace(h)
+		if h == "" || strings.EqualFold(h, "no warning") || strings.EqualFold(h, "nil") {
+			continue
+		}
+		list = append(list, h)
+	}
+	return list
+}
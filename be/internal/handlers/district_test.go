@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	pgxmock "github.com/pashagolub/pgxmock/v4"
+
+	"github.com/lolwierd/weatherboy/be/internal/geo"
+	"github.com/lolwierd/weatherboy/be/internal/model"
+)
+
+var districtWarningColumns = []string{"id", "location", "issued_at", "day1_warning", "day2_warning", "day3_warning", "day4_warning", "day5_warning",
+	"day1_color", "day2_color", "day3_color", "day4_color", "day5_color", "created_at"}
+
+// loadDistricts loads the geo package's test outlines of the districts
+// around the default locations. Only Vadodara's carries an IMD id (244).
+func loadDistricts(t *testing.T) {
+	t.Helper()
+	if err := geo.LoadDistricts("../geo/testdata/districts.geojson"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// districtWarningRow is a warning for loc issued at issued, orange with heavy
+// rain on its first day and green afterwards.
+func districtWarningRow(rows *pgxmock.Rows, id int, loc string, issued time.Time) *pgxmock.Rows {
+	return rows.AddRow(id, loc, issued, "Heavy Rain, Thunderstorm & Lightning", "No Warning", "No Warning", "No Warning", "No Warning",
+		"Orange", "Green", "Green", "Green", "Green", issued)
+}
+
+func TestWarningPropertiesLocalDay(t *testing.T) {
+	// Issued at 13:00 IST on 20 June; 19:00 UTC the same day is already
+	// 21 June in Vadodara.
+	dw := &model.DistrictWarning{
+		Location:    "vadodara",
+		IssuedAt:    time.Date(2024, 6, 20, 7, 30, 0, 0, time.UTC),
+		Day1Warning: "Heavy Rain", Day1Color: "Orange",
+		Day2Warning: "Thunderstorm & Lightning", Day2Color: "Yellow",
+		Day5Warning: "No Warning", Day5Color: "Green",
+	}
+	cases := []struct {
+		name  string
+		now   time.Time
+		day   int
+		color any
+	}{
+		{"issue day", time.Date(2024, 6, 20, 12, 0, 0, 0, time.UTC), 1, "orange"},
+		{"next local day", time.Date(2024, 6, 20, 19, 0, 0, 0, time.UTC), 1, "yellow"},
+		{"last day", time.Date(2024, 6, 21, 0, 0, 0, 0, time.UTC), 4, "green"},
+		{"past the forecast", time.Date(2024, 6, 21, 0, 0, 0, 0, time.UTC), 5, nil},
+	}
+	for _, tc := range cases {
+		props := warningProperties(dw, tc.day, tc.now)
+		if props["color"] != tc.color || props["day"] != tc.day {
+			t.Errorf("%s: props = %v, want color %v", tc.name, props, tc.color)
+		}
+		if props["issued_at"] != dw.IssuedAt {
+			t.Errorf("%s: issued_at = %v", tc.name, props["issued_at"])
+		}
+	}
+	if props := warningProperties(nil, 1, time.Now()); props["color"] != nil || props["issued_at"] != nil {
+		t.Errorf("nil warning: props = %v", props)
+	}
+}
+
+func TestGetDistrict(t *testing.T) {
+	loadDistricts(t)
+	mock := setupMock(t)
+	defer mock.Close()
+
+	app := fiber.New()
+	app.Get("/districts/:id", GetDistrict)
+
+	issued := time.Now().Add(-time.Minute)
+	mock.ExpectQuery("FROM district_warning").WithArgs([]string{"vadodara"}).
+		WillReturnRows(districtWarningRow(pgxmock.NewRows(districtWarningColumns), 1, "vadodara", issued))
+	resp, err := app.Test(httptest.NewRequest("GET", "/districts/244", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status %d, want %d", resp.StatusCode, fiber.StatusOK)
+	}
+	var f struct {
+		Type     string    `json:"type"`
+		BBox     []float64 `json:"bbox"`
+		Geometry struct {
+			Type        string          `json:"type"`
+			Coordinates [][][][]float64 `json:"coordinates"`
+		} `json:"geometry"`
+		Properties map[string]any `json:"properties"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&f); err != nil {
+		t.Fatal(err)
+	}
+	d, _, _ := geo.DistrictByID(244)
+	want := d.BBox.GeoJSON()
+	if f.Type != "Feature" || f.Geometry.Type != "MultiPolygon" || len(f.Geometry.Coordinates) != len(d.Polygons) {
+		t.Errorf("feature = %s with %s geometry of %d polygons", f.Type, f.Geometry.Type, len(f.Geometry.Coordinates))
+	}
+	if len(f.BBox) != 4 || f.BBox[0] != want[0] || f.BBox[1] != want[1] || f.BBox[2] != want[2] || f.BBox[3] != want[3] {
+		t.Errorf("bbox = %v, want %v", f.BBox, want)
+	}
+	p := f.Properties
+	if p["id"] != 244.0 || p["district"] != "Vadodara" || p["state"] != "Gujarat" || p["day"] != 1.0 || p["color"] != "orange" {
+		t.Errorf("properties = %v", p)
+	}
+	if hz, _ := p["hazards"].([]any); len(hz) != 2 || hz[0] != "Heavy Rain" || hz[1] != "Thunderstorm & Lightning" {
+		t.Errorf("hazards = %v", p["hazards"])
+	}
+
+	// Day 2 of the same warning carries no hazards.
+	mock.ExpectQuery("FROM district_warning").WithArgs([]string{"vadodara"}).
+		WillReturnRows(districtWarningRow(pgxmock.NewRows(districtWarningColumns), 1, "vadodara", issued))
+	resp, err = app.Test(httptest.NewRequest("GET", "/districts/244?day=2", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Properties = nil
+	if err := json.NewDecoder(resp.Body).Decode(&f); err != nil {
+		t.Fatal(err)
+	}
+	if hz, _ := f.Properties["hazards"].([]any); f.Properties["color"] != "green" || hz == nil || len(hz) != 0 {
+		t.Errorf("day 2 properties = %v", f.Properties)
+	}
+
+	// No stored warning still serves the boundary, with null properties.
+	mock.ExpectQuery("FROM district_warning").WithArgs([]string{"vadodara"}).
+		WillReturnRows(pgxmock.NewRows(districtWarningColumns))
+	resp, err = app.Test(httptest.NewRequest("GET", "/districts/244", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Properties = nil
+	if err := json.NewDecoder(resp.Body).Decode(&f); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK || f.Properties["color"] != nil || f.Properties["hazards"] != nil || f.Properties["issued_at"] != nil {
+		t.Errorf("no warning: status %d, properties %v", resp.StatusCode, f.Properties)
+	}
+
+	for path, status := range map[string]int{
+		"/districts/999":       fiber.StatusNotFound,
+		"/districts/abc":       fiber.StatusBadRequest,
+		"/districts/0":         fiber.StatusBadRequest,
+		"/districts/244?day=6": fiber.StatusBadRequest,
+	} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != status {
+			t.Errorf("%s: status %d, want %d", path, resp.StatusCode, status)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/fetch"
+	"github.com/lolwierd/weatherboy/be/internal/logger"
+	"github.com/lolwierd/weatherboy/be/internal/repository"
+	"github.com/lolwierd/weatherboy/be/internal/scheduler"
+)
+
+// locationJSON is a location as read and written by the admin API.
+type locationJSON struct {
+	Slug            string   `json:"slug"`
+	Name            string   `json:"name"`
+	Lat             float64  `json:"lat"`
+	Lon             float64  `json:"lon"`
+	DistrictID      int      `json:"district_id"`
+	BulletinProduct string   `json:"bulletin_product"`
+	RadarCodes      []string `json:"radar_codes"`
+	RiverBasinID    int      `json:"river_basin_id"`
+	SubBasinID      int      `json:"sub_basin_id"`
+	AWSStationIDs   []string `json:"aws_station_ids"`
+	AWSNearestK     int      `json:"aws_nearest_k"`
+	AWSRadiusKM     float64  `json:"aws_radius_km"`
+	AWSState        string   `json:"aws_state"`
+	AWSDistrict     string   `json:"aws_district"`
+	Timezone        string   `json:"timezone"`
+}
+
+func toLocationJSON(l config.Location) locationJSON {
+	return locationJSON{
+		Slug:            l.Name,
+		Name:            l.DisplayName,
+		Lat:             l.Lat,
+		Lon:             l.Lon,
+		DistrictID:      l.DistrictID,
+		BulletinProduct: l.PdfSlug,
+		RadarCodes:      l.RadarCodes,
+		RiverBasinID:    l.RiverBasinID,
+		SubBasinID:      l.SubBasinID,
+		AWSStationIDs:   l.AWSStationIDs,
+		AWSNearestK:     l.AWSNearestK,
+		AWSRadiusKM:     l.AWSRadiusKM,
+		AWSState:        l.AWSScope.State,
+		AWSDistrict:     l.AWSScope.District,
+		Timezone:        l.Timezone,
+	}
+}
+
+func (j locationJSON) location() config.Location {
+	tz := j.Timezone
+	if tz == "" {
+		tz = config.DefaultTimezone
+	}
+	return config.Location{
+		Name:          j.Slug,
+		DisplayName:   j.Name,
+		Lat:           j.Lat,
+		Lon:           j.Lon,
+		DistrictID:    j.DistrictID,
+		PdfSlug:       j.BulletinProduct,
+		RadarCodes:    j.RadarCodes,
+		RiverBasinID:  j.RiverBasinID,
+		SubBasinID:    j.SubBasinID,
+		AWSStationIDs: j.AWSStationIDs,
+		AWSNearestK:   j.AWSNearestK,
+		AWSRadiusKM:   j.AWSRadiusKM,
+		AWSScope:      config.AWSScope{State: j.AWSState, District: j.AWSDistrict},
+		Timezone:      tz,
+	}
+}
+
+// GetAdminLocations lists the stored locations.
+func GetAdminLocations(c *fiber.Ctx) error {
+	ls, err := repository.ListLocations(c.Context())
+	if err != nil {
+		logger.Error.Println("location fetch:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	list := make([]locationJSON, 0, len(ls))
+	for _, l := range ls {
+		list = append(list, toLocationJSON(l))
+	}
+	return c.JSON(fiber.Map{"locations": list})
+}
+
+// GetAdminLocation returns a stored location.
+func GetAdminLocation(c *fiber.Ctx) error {
+	l, err := repository.GetLocation(c.Context(), c.Params("slug"))
+	if errors.Is(err, repository.ErrUnknownLocation) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		logger.Error.Println("location fetch:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(toLocationJSON(l))
+}
+
+// PostAdminLocation adds a location. Its IMD identifiers are checked against
+// IMD unless `check_imd=false`. The scheduler picks the location up at once.
+func PostAdminLocation(c *fiber.Ctx) error {
+	var body locationJSON
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	l := body.location()
+	if status, err := checkLocation(c, l); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	err := repository.InsertLocation(c.Context(), l)
+	if errors.Is(err, repository.ErrLocationExists) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		logger.Error.Println("location insert:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(refreshLocations(c, toLocationJSON(l)))
+}
+
+// PutAdminLocation replaces a location. The slug in the path wins over the
+// one in the body.
+func PutAdminLocation(c *fiber.Ctx) error {
+	var body locationJSON
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	body.Slug = c.Params("slug")
+	l := body.location()
+	if status, err := checkLocation(c, l); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	err := repository.UpdateLocation(c.Context(), l)
+	if errors.Is(err, repository.ErrUnknownLocation) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		logger.Error.Println("location update:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(refreshLocations(c, toLocationJSON(l)))
+}
+
+// DeleteAdminLocation removes a location. Data already ingested for it is
+// kept.
+func DeleteAdminLocation(c *fiber.Ctx) error {
+	slug := c.Params("slug")
+	err := repository.DeleteLocation(c.Context(), slug)
+	if errors.Is(err, repository.ErrUnknownLocation) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		logger.Error.Println("location delete:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(refreshLocations(c, fiber.Map{"slug": slug}))
+}
+
+// checkLocation validates l and returns the status to reject it with.
+func checkLocation(c *fiber.Ctx, l config.Location) (int, error) {
+	if err := config.ValidateLocation(l); err != nil {
+		return fiber.StatusUnprocessableEntity, err
+	}
+	if !c.QueryBool("check_imd", true) {
+		return 0, nil
+	}
+	err := fetch.CheckLocation(c.Context(), l)
+	if errors.Is(err, fetch.ErrInvalidLocation) {
+		return fiber.StatusUnprocessableEntity, err
+	}
+	if err != nil {
+		logger.Error.Println("location check:", err)
+		return fiber.StatusBadGateway, err
+	}
+	return 0, nil
+}
+
+// refreshLocations serves the stored locations on this replica and reloads
+// its jobs; other replicas follow within a minute. A failed reload is
+// reported alongside the stored location.
+func refreshLocations(c *fiber.Ctx, stored any) fiber.Map {
+	res := fiber.Map{"location": stored}
+	if err := scheduler.RefreshLocations(c.Context()); err != nil {
+		logger.Error.Println("refresh locations:", err)
+		res["reload_error"] = err.Error()
+	}
+	return res
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	pgxmock "github.com/pashagolub/pgxmock/v4"
+
+	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/db"
+)
+
+func setupMock(t *testing.T) pgxmock.PgxPoolIface {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetDBDriver(&db.Driver{ConnPool: mock})
+	return mock
+}
+
+func locationApp() *fiber.App {
+	app := fiber.New()
+	app.Get("/locations/:slug", GetAdminLocation)
+	app.Post("/locations", PostAdminLocation)
+	app.Put("/locations/:slug", PutAdminLocation)
+	app.Delete("/locations/:slug", DeleteAdminLocation)
+	return app
+}
+
+var locationRowColumns = []string{"slug", "name", "lat", "lon", "district_id", "bulletin_product", "radar_codes", "river_basin_id", "sub_basin_id",
+	"aws_station_ids", "aws_nearest_k", "aws_radius_km", "aws_state", "aws_district", "timezone"}
+
+func locationRows(ls ...config.Location) *pgxmock.Rows {
+	rows := pgxmock.NewRows(locationRowColumns)
+	for _, l := range ls {
+		rows.AddRow(l.Name, l.DisplayName, l.Lat, l.Lon, l.DistrictID, l.PdfSlug, l.RadarCodes, l.RiverBasinID, l.SubBasinID,
+			l.AWSStationIDs, l.AWSNearestK, l.AWSRadiusKM, l.AWSScope.State, l.AWSScope.District, l.Timezone)
+	}
+	return rows
+}
+
+const suratBody = `{"slug":"surat","name":"Surat","lat":21.17,"lon":72.83,"district_id":0,"bulletin_product":"gujarat.pdf","radar_codes":["surat"]}`
+
+func TestPostAdminLocation(t *testing.T) {
+	mock := setupMock(t)
+	defer mock.Close()
+	defer config.SetLocations(config.DefaultLocations)
+
+	surat := config.Location{Name: "surat", DisplayName: "Surat", Lat: 21.17, Lon: 72.83, PdfSlug: "gujarat.pdf",
+		RadarCodes: []string{"surat"}, Timezone: config.DefaultTimezone}
+	mock.ExpectExec("INSERT INTO location").
+		WithArgs("surat", "Surat", 21.17, 72.83, 0, "gujarat.pdf", []string{"surat"}, 0, 0,
+			[]string{}, 0, 0.0, "", "", config.DefaultTimezone).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectQuery("FROM location ORDER BY slug").
+		WillReturnRows(locationRows(append(config.DefaultLocations, surat)...))
+
+	req := httptest.NewRequest("POST", "/locations?check_imd=false", strings.NewReader(suratBody))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := locationApp().Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("status %d, want %d", resp.StatusCode, fiber.StatusCreated)
+	}
+	var got struct {
+		Location    locationJSON `json:"location"`
+		ReloadError string       `json:"reload_error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Location.Slug != "surat" || got.Location.Timezone != config.DefaultTimezone || got.ReloadError != "" {
+		t.Errorf("response = %+v", got)
+	}
+	if _, ok := config.LocationByName("surat"); !ok {
+		t.Error("surat is not served after the insert")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPostAdminLocationConflict(t *testing.T) {
+	mock := setupMock(t)
+	defer mock.Close()
+
+	mock.ExpectExec("INSERT INTO location").WithArgs(append([]any{"surat"}, anyArgs(14)...)...).
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+
+	req := httptest.NewRequest("POST", "/locations?check_imd=false", strings.NewReader(suratBody))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := locationApp().Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusConflict {
+		t.Errorf("status %d, want %d", resp.StatusCode, fiber.StatusConflict)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPostAdminLocationInvalid(t *testing.T) {
+	mock := setupMock(t)
+	defer mock.Close()
+
+	cases := map[string]string{
+		"bad slug":         `{"slug":"Surat City","name":"Surat","lat":21.17,"lon":72.83}`,
+		"missing name":     `{"slug":"surat","lat":21.17,"lon":72.83}`,
+		"outside india":    `{"slug":"london","name":"London","lat":51.5,"lon":-0.12}`,
+		"unknown timezone": `{"slug":"surat","name":"Surat","lat":21.17,"lon":72.83,"timezone":"Asia/Surat"}`,
+		"bad bulletin":     `{"slug":"surat","name":"Surat","lat":21.17,"lon":72.83,"bulletin_product":"gujarat"}`,
+	}
+	for name, body := range cases {
+		req := httptest.NewRequest("POST", "/locations?check_imd=false", strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := locationApp().Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusUnprocessableEntity {
+			t.Errorf("%s: status %d, want %d", name, resp.StatusCode, fiber.StatusUnprocessableEntity)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("invalid locations reached the database: %v", err)
+	}
+}
+
+func TestGetAdminLocation(t *testing.T) {
+	mock := setupMock(t)
+	defer mock.Close()
+
+	mock.ExpectQuery("FROM location WHERE slug").WithArgs("vadodara").
+		WillReturnRows(locationRows(config.DefaultLocations[0]))
+	mock.ExpectQuery("FROM location WHERE slug").WithArgs("atlantis").
+		WillReturnRows(locationRows())
+
+	resp, err := locationApp().Test(httptest.NewRequest("GET", "/locations/vadodara", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got locationJSON
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK || got.Slug != "vadodara" || got.DistrictID != 244 || got.BulletinProduct != "gujarat.pdf" {
+		t.Errorf("status %d, location %+v", resp.StatusCode, got)
+	}
+
+	resp, err = locationApp().Test(httptest.NewRequest("GET", "/locations/atlantis", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("unknown location: status %d, want %d", resp.StatusCode, fiber.StatusNotFound)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPutAdminLocation(t *testing.T) {
+	mock := setupMock(t)
+	defer mock.Close()
+	defer config.SetLocations(config.DefaultLocations)
+
+	// The slug in the path wins over the one in the body.
+	mock.ExpectExec("UPDATE location SET").
+		WithArgs("thane", "Surat", 21.17, 72.83, 0, "gujarat.pdf", []string{"surat"}, 0, 0,
+			[]string{}, 0, 0.0, "", "", config.DefaultTimezone).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectQuery("FROM location ORDER BY slug").WillReturnRows(locationRows(config.DefaultLocations...))
+	mock.ExpectExec("UPDATE location SET").WithArgs(append([]any{"atlantis"}, anyArgs(14)...)...).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	cases := []struct {
+		slug string
+		want int
+	}{
+		{"thane", fiber.StatusOK},
+		{"atlantis", fiber.StatusNotFound},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("PUT", "/locations/"+tc.slug+"?check_imd=false", strings.NewReader(suratBody))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := locationApp().Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.slug, resp.StatusCode, tc.want)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeleteAdminLocation(t *testing.T) {
+	mock := setupMock(t)
+	defer mock.Close()
+	defer config.SetLocations(config.DefaultLocations)
+
+	mock.ExpectExec("DELETE FROM location").WithArgs("pune").WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectQuery("FROM location ORDER BY slug").WillReturnRows(locationRows(config.DefaultLocations[:3]...))
+	mock.ExpectExec("DELETE FROM location").WithArgs("atlantis").WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+	resp, err := locationApp().Test(httptest.NewRequest("DELETE", "/locations/pune", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Errorf("status %d, want %d", resp.StatusCode, fiber.StatusOK)
+	}
+	if _, ok := config.LocationByName("pune"); ok {
+		t.Error("pune is still served after the delete")
+	}
+
+	resp, err = locationApp().Test(httptest.NewRequest("DELETE", "/locations/atlantis", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("unknown location: status %d, want %d", resp.StatusCode, fiber.StatusNotFound)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func anyArgs(n int) []any {
+	args := make([]any, n)
+	for i := range args {
+		args[i] = pgxmock.AnyArg()
+	}
+	return args
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"slices"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/geo"
+	"github.com/lolwierd/weatherboy/be/internal/logger"
+	"github.com/lolwierd/weatherboy/be/internal/model"
+	"github.com/lolwierd/weatherboy/be/internal/repository"
+)
+
+// stationMapWindow is how recently a station must have been fetched to be
+// drawn on the station map.
+const stationMapWindow = 24 * time.Hour
+
+// bboxParam reads the optional `bbox` query parameter. ok is false when it is
+// not set.
+func bboxParam(c *fiber.Ctx) (b geo.BBox, ok bool, err error) {
+	s := c.Query("bbox")
+	if s == "" {
+		return geo.BBox{}, false, nil
+	}
+	b, err = geo.ParseBBox(s)
+	return b, err == nil, err
+}
+
+// warningCoverage reports how many of the districts we ingest warnings for
+// the boundary set can draw.
+type warningCoverage struct {
+	Districts int `json:"districts"`
+	// Missing lists the IMD ids of the districts without a boundary.
+	Missing  []int `json:"missing_district_ids"`
+	Complete bool  `json:"complete"`
+}
+
+// GetMapWarnings returns a GeoJSON FeatureCollection of every district we
+// ingest warnings for, each carrying the colour and hazards of its latest
+// district warning for `day`, 1 (today in the location's timezone, the
+// default) to 5. `bbox` (minLon,minLat,maxLon,maxLat) limits it to the
+// districts overlapping the box. Districts missing from the boundary set
+// cannot be drawn; the collection's `coverage` member lists them.
+func GetMapWarnings(c *fiber.Ctx) error {
+	day, err := warningDayParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	bbox, hasBBox, err := bboxParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	byDistrict := map[int][]string{}
+	var locs []string
+	for _, l := range config.Locations() {
+		if l.DistrictID == 0 {
+			continue
+		}
+		byDistrict[l.DistrictID] = append(byDistrict[l.DistrictID], l.Name)
+		locs = append(locs, l.Name)
+	}
+	warnings, err := repository.LatestDistrictWarnings(c.Context(), locs)
+	if err != nil {
+		logger.Error.Println("district warning fetch:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	byLocation := make(map[string]*model.DistrictWarning, len(warnings))
+	for i := range warnings {
+		byLocation[warnings[i].Location] = &warnings[i]
+	}
+
+	ids := make([]int, 0, len(byDistrict))
+	for id := range byDistrict {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	now := time.Now()
+	var features []geo.Feature
+	coverage := warningCoverage{Districts: len(ids), Missing: []int{}}
+	for _, id := range ids {
+		d, ok, err := geo.DistrictByID(id)
+		if err != nil {
+			logger.Error.Println("district boundaries:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !ok {
+			logger.Warn.Println("no boundary for district", id)
+			coverage.Missing = append(coverage.Missing, id)
+			continue
+		}
+		if hasBBox && !d.BBox.Intersects(bbox) {
+			continue
+		}
+		var latest *model.DistrictWarning
+		for _, loc := range byDistrict[id] {
+			if dw := byLocation[loc]; dw != nil && (latest == nil || dw.IssuedAt.After(latest.IssuedAt)) {
+				latest = dw
+			}
+		}
+		props := warningProperties(latest, day, now)
+		props["locations"] = byDistrict[id]
+		features = append(features, d.Feature(props))
+	}
+	coverage.Complete = len(coverage.Missing) == 0
+	return c.JSON(struct {
+		geo.FeatureCollection
+		Coverage warningCoverage `json:"coverage"`
+	}{geo.NewFeatureCollection(features), coverage})
+}
+
+// GetMapStations returns a GeoJSON FeatureCollection of the latest
+// observation of every AWS/ARG station fetched in the last 24h, as points.
+// `bbox` (minLon,minLat,maxLon,maxLat) limits it to the stations inside the
+// box.
+func GetMapStations(c *fiber.Ctx) error {
+	bbox, hasBBox, err := bboxParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	obs, err := repository.LatestAWSARGSince(c.Context(), time.Now().Add(-stationMapWindow))
+	if err != nil {
+		logger.Error.Println("aws/arg fetch:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	features := make([]geo.Feature, 0, len(obs))
+	for _, a := range obs {
+		if hasBBox && !bbox.Contains(a.Latitude, a.Longitude) {
+			continue
+		}
+		features = append(features, geo.PointFeature(a.Latitude, a.Longitude, map[string]any{
+			"station_id":     a.StationID,
+			"call_sign":      a.CallSign,
+			"station_name":   a.StationName,
+			"district":       a.District,
+			"state":          a.State,
+			"observed_at":    a.ObservedAt(),
+			"temp":           a.CurrentTemp,
+			"dew_point":      a.DewPointTemp,
+			"rh":             a.RH,
+			"wind_direction": a.WindDirection,
+			"wind_speed":     a.WindSpeed,
+			"mslp":           a.MSLP,
+			"rainfall":       a.Rainfall,
+			"rainfall_sel":   a.RainfallSel,
+			"qc":             a.QC,
+		}))
+	}
+	return c.JSON(geo.NewFeatureCollection(features))
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	pgxmock "github.com/pashagolub/pgxmock/v4"
+
+	"github.com/lolwierd/weatherboy/be/internal/config"
+)
+
+// featureCollection is the decoded shape of a GeoJSON map layer.
+type featureCollection struct {
+	Type     string `json:"type"`
+	Features []struct {
+		Type     string    `json:"type"`
+		BBox     []float64 `json:"bbox"`
+		Geometry struct {
+			Type        string          `json:"type"`
+			Coordinates json.RawMessage `json:"coordinates"`
+		} `json:"geometry"`
+		Properties map[string]any `json:"properties"`
+	} `json:"features"`
+	Coverage *warningCoverage `json:"coverage"`
+}
+
+func getFeatures(t *testing.T, app *fiber.App, path string) (int, featureCollection) {
+	t.Helper()
+	resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fc featureCollection
+	if resp.StatusCode == fiber.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&fc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, fc
+}
+
+func TestGetMapWarnings(t *testing.T) {
+	loadDistricts(t)
+	mock := setupMock(t)
+	defer mock.Close()
+	defer config.SetLocations(config.DefaultLocations)
+
+	// Two locations share Vadodara's district; 999 has no boundary.
+	config.SetLocations(append(config.DefaultLocations[:1:1],
+		config.Location{Name: "vadodara-east", DistrictID: 244, Timezone: config.DefaultTimezone},
+		config.Location{Name: "nowhere", DistrictID: 999, Timezone: config.DefaultTimezone}))
+	locs := []string{"vadodara", "vadodara-east", "nowhere"}
+
+	app := fiber.New()
+	app.Get("/map/warnings", GetMapWarnings)
+
+	now := time.Now()
+	rows := pgxmock.NewRows(districtWarningColumns)
+	districtWarningRow(rows, 1, "vadodara", now.Add(-time.Hour))
+	rows.AddRow(2, "vadodara-east", now.Add(-time.Minute), "No Warning", "No Warning", "No Warning", "No Warning", "No Warning",
+		"Green", "Green", "Green", "Green", "Green", now)
+	mock.ExpectQuery("FROM district_warning").WithArgs(locs).WillReturnRows(rows)
+
+	status, fc := getFeatures(t, app, "/map/warnings")
+	if status != fiber.StatusOK || fc.Type != "FeatureCollection" || len(fc.Features) != 1 {
+		t.Fatalf("status %d, collection %+v", status, fc)
+	}
+	f := fc.Features[0]
+	if f.Type != "Feature" || f.Geometry.Type != "MultiPolygon" || len(f.BBox) != 4 {
+		t.Errorf("feature = %s with %s geometry and bbox %v", f.Type, f.Geometry.Type, f.BBox)
+	}
+	// The latest warning across the district's locations wins.
+	p := f.Properties
+	if p["id"] != 244.0 || p["color"] != "green" || p["day"] != 1.0 {
+		t.Errorf("properties = %v", p)
+	}
+	if l, _ := p["locations"].([]any); len(l) != 2 || l[0] != "vadodara" || l[1] != "vadodara-east" {
+		t.Errorf("locations = %v", p["locations"])
+	}
+	// The district without a boundary is reported rather than silently
+	// left out.
+	if c := fc.Coverage; c == nil || c.Districts != 2 || !slices.Equal(c.Missing, []int{999}) || c.Complete {
+		t.Errorf("coverage = %+v", c)
+	}
+
+	// A box around Mumbai leaves Vadodara out, and the collection is empty
+	// rather than null.
+	mock.ExpectQuery("FROM district_warning").WithArgs(locs).WillReturnRows(pgxmock.NewRows(districtWarningColumns))
+	resp, err := app.Test(httptest.NewRequest("GET", "/map/warnings?bbox=72.7,18.8,73.1,19.3", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		t.Fatal(err)
+	}
+	if string(raw["features"]) != "[]" {
+		t.Errorf("features = %s, want []", raw["features"])
+	}
+
+	// Every district of the default locations has a boundary.
+	config.SetLocations(config.DefaultLocations)
+	mock.ExpectQuery("FROM district_warning").WithArgs([]string{"vadodara"}).WillReturnRows(pgxmock.NewRows(districtWarningColumns))
+	status, fc = getFeatures(t, app, "/map/warnings")
+	if c := fc.Coverage; status != fiber.StatusOK || c == nil || c.Districts != 1 || len(c.Missing) != 0 || !c.Complete {
+		t.Errorf("status %d, coverage %+v", status, c)
+	}
+
+	for _, path := range []string{"/map/warnings?bbox=72.7,18.8,73.1", "/map/warnings?bbox=73.1,18.8,72.7,19.3", "/map/warnings?day=0"} {
+		if status, _ := getFeatures(t, app, path); status != fiber.StatusBadRequest {
+			t.Errorf("%s: status %d, want %d", path, status, fiber.StatusBadRequest)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+var awsArgColumns = []string{"id", "station_id", "call_sign", "district", "state", "station_name", "date", "time", "current_temp", "dew_point_temp", "rh",
+	"wind_direction", "wind_speed", "mslp", "min_temp", "max_temp", "latitude", "longitude", "weather_code", "nebulosity",
+	"feel_like", "rainfall_sel", "rainfall", "qc", "fetched_at"}
+
+func awsArgRow(rows *pgxmock.Rows, id int, station string, lat, lon float64, rainfall *float64) *pgxmock.Rows {
+	date := time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC)
+	tod := time.Date(0, 1, 1, 8, 30, 0, 0, time.UTC)
+	temp := 29.5
+	return rows.AddRow(id, station, station, "VADODARA", "GUJARAT", station+" AWS", date, tod, &temp, nil, nil,
+		nil, nil, nil, nil, nil, lat, lon, "", nil,
+		nil, "", rainfall, map[string][]string{}, date.Add(9*time.Hour))
+}
+
+func TestGetMapStations(t *testing.T) {
+	mock := setupMock(t)
+	defer mock.Close()
+
+	app := fiber.New()
+	app.Get("/map/stations", GetMapStations)
+
+	rain := 12.5
+	rows := func() *pgxmock.Rows {
+		rows := pgxmock.NewRows(awsArgColumns)
+		awsArgRow(rows, 1, "NDL", 22.30, 73.18, &rain)
+		return awsArgRow(rows, 2, "SRT", 21.17, 72.83, nil)
+	}
+	mock.ExpectQuery("FROM aws_arg").WithArgs(pgxmock.AnyArg()).WillReturnRows(rows())
+	mock.ExpectQuery("FROM aws_arg").WithArgs(pgxmock.AnyArg()).WillReturnRows(rows())
+
+	status, fc := getFeatures(t, app, "/map/stations")
+	if status != fiber.StatusOK || fc.Type != "FeatureCollection" || len(fc.Features) != 2 {
+		t.Fatalf("status %d, collection %+v", status, fc)
+	}
+	f := fc.Features[0]
+	var coords [2]float64
+	if err := json.Unmarshal(f.Geometry.Coordinates, &coords); err != nil {
+		t.Fatal(err)
+	}
+	// GeoJSON positions are lon, lat.
+	if f.Geometry.Type != "Point" || coords != [2]float64{73.18, 22.30} {
+		t.Errorf("geometry = %s %v", f.Geometry.Type, coords)
+	}
+	if p := f.Properties; p["station_id"] != "NDL" || p["rainfall"] != 12.5 || p["temp"] != 29.5 || p["dew_point"] != nil {
+		t.Errorf("properties = %v", p)
+	}
+	if p := fc.Features[1].Properties; p["station_id"] != "SRT" || p["rainfall"] != nil {
+		t.Errorf("properties = %v", p)
+	}
+
+	status, fc = getFeatures(t, app, "/map/stations?bbox=73.0,22.0,73.5,22.6")
+	if status != fiber.StatusOK || len(fc.Features) != 1 || fc.Features[0].Properties["station_id"] != "NDL" {
+		t.Errorf("bbox: status %d, collection %+v", status, fc)
+	}
+
+	if status, _ := getFeatures(t, app, "/map/stations?bbox=north"); status != fiber.StatusBadRequest {
+		t.Errorf("bad bbox: status %d, want %d", status, fiber.StatusBadRequest)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -7,7 +7,7 @@ import (
 )
 
 func GetNowcast(c *fiber.Ctx) error {
-	loc := c.Params("loc")
+	loc := locParam(c)
 	n, err := repository.NowcastSlice(c.Context(), loc)
 	if err != nil {
 		logger.Error.Println("nowcast fetch:", err)
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/lolwierd/weatherboy/be/internal/locate"
+	"github.com/lolwierd/weatherboy/be/internal/logger"
+)
+
+type resolvedKey struct{}
+
+// ResolveLocation lets a location route be addressed by coordinates instead
+// of a slug. It resolves the `lat` and `lon` query parameters to the served
+// location the handler then runs for. The resolved slug is sent in the
+// X-Resolved-Location header, and handlers answering with a JSON object add
+// the whole resolution to it as `resolved`.
+func ResolveLocation(c *fiber.Ctx) error {
+	lat, errLat := strconv.ParseFloat(c.Query("lat"), 64)
+	lon, errLon := strconv.ParseFloat(c.Query("lon"), 64)
+	if errLat != nil || errLon != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "lat and lon must be decimal degrees"})
+	}
+	res, err := locate.Resolve(lat, lon)
+	switch {
+	case errors.Is(err, locate.ErrInvalidCoordinate):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, locate.ErrNoLocations):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		logger.Error.Println("resolve location:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	c.Locals(resolvedKey{}, res)
+	c.Set("X-Resolved-Location", res.Location)
+	return c.Next()
+}
+
+// resolution returns the resolution ResolveLocation made for the request, or
+// nil when the route was addressed by slug.
+func resolution(c *fiber.Ctx) *locate.Resolution {
+	if res, ok := c.Locals(resolvedKey{}).(locate.Resolution); ok {
+		return &res
+	}
+	return nil
+}
+
+// withResolution adds the resolution of the request, if any, to a response
+// as `resolved`.
+func withResolution(c *fiber.Ctx, m fiber.Map) fiber.Map {
+	if res := resolution(c); res != nil {
+		m["resolved"] = res
+	}
+	return m
+}
+
+// locParam returns the location a route is for: the one ResolveLocation
+// resolved the coordinates to, or else the `loc` path parameter.
+func locParam(c *fiber.Ctx) string {
+	if res := resolution(c); res != nil {
+		return res.Location
+	}
+	return c.Params("loc")
+}
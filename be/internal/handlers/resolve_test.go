@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestResolveLocation(t *testing.T) {
+	loadDistricts(t)
+	app := fiber.New()
+	app.Get("/ok", ResolveLocation, func(c *fiber.Ctx) error {
+		return c.JSON(withResolution(c, fiber.Map{"location": locParam(c)}))
+	})
+	app.Get("/missing", ResolveLocation, func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not found"})
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/ok?lat=22.31&lon=73.18", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Header.Get("X-Resolved-Location"); got != "vadodara" {
+		t.Errorf("X-Resolved-Location = %q", got)
+	}
+	var body struct {
+		Location string `json:"location"`
+		Resolved struct {
+			Location, District, Method string
+		} `json:"resolved"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Location != "vadodara" || body.Resolved.Location != "vadodara" || body.Resolved.Method != "district" {
+		t.Errorf("body = %+v", body)
+	}
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/missing?lat=22.31&lon=73.18", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := io.ReadAll(resp.Body)
+	if string(raw) != `{"error":"not found"}` {
+		t.Errorf("error body = %s, want it untouched", raw)
+	}
+
+	for _, q := range []string{"", "?lat=22.31", "?lat=x&lon=73.18", "?lat=95&lon=73.18"} {
+		resp, err := app.Test(httptest.NewRequest("GET", "/ok"+q, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("%q: status %d, want %d", q, resp.StatusCode, fiber.StatusBadRequest)
+		}
+	}
+}
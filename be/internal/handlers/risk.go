@@ -1,17 +1,113 @@
 package handlers
 
 import (
+	"errors"
+	"strings"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/locate"
 	"github.com/lolwierd/weatherboy/be/internal/logger"
+	"github.com/lolwierd/weatherboy/be/internal/repository"
 	"github.com/lolwierd/weatherboy/be/internal/score"
 )
 
 func GetRisk(c *fiber.Ctx) error {
-	loc := c.Params("loc")
+	loc := locParam(c)
 	res, err := score.RiskLevel(c.Context(), loc)
 	if err != nil {
 		logger.Error.Println("risk level:", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(res)
+	return c.JSON(struct {
+		score.Result
+		Resolved *locate.Resolution `json:"resolved,omitempty"`
+	}{res, resolution(c)})
+}
+
+// maxBatchLocations bounds the locations of one GetRisks request.
+const maxBatchLocations = 50
+
+// GetRisks returns the risk levels of the comma-separated `locs`. Without
+// `locs` the request falls through to the route addressed by coordinates.
+func GetRisks(c *fiber.Ctx) error {
+	q := c.Query("locs")
+	if q == "" {
+		return c.Next()
+	}
+	var locs []string
+	for _, loc := range strings.Split(q, ",") {
+		if loc = strings.TrimSpace(loc); loc != "" {
+			locs = append(locs, loc)
+		}
+	}
+	if len(locs) == 0 || len(locs) > maxBatchLocations {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "locs must list 1 to 50 locations"})
+	}
+	return riskBatch(c, locs)
+}
+
+// GetRiskAll returns the risk level of every served location.
+func GetRiskAll(c *fiber.Ctx) error {
+	var locs []string
+	for _, l := range config.Locations() {
+		locs = append(locs, l.Name)
+	}
+	return riskBatch(c, locs)
+}
+
+// riskBatch responds with the risk levels of locs keyed by location, and the
+// error of each location that could not be scored under `errors`. It fails
+// only when no location could be scored.
+func riskBatch(c *fiber.Ctx, locs []string) error {
+	results, errs := score.RiskLevels(c.Context(), locs)
+	failed := make(map[string]string, len(errs))
+	unknown := 0
+	for loc, err := range errs {
+		if errors.Is(err, repository.ErrUnknownLocation) {
+			unknown++
+		} else {
+			logger.Error.Println("risk level", loc+":", err)
+		}
+		failed[loc] = err.Error()
+	}
+
+	status := fiber.StatusOK
+	switch {
+	case len(results) > 0 || len(errs) == 0:
+	case unknown == len(errs):
+		status = fiber.StatusNotFound
+	default:
+		status = fiber.StatusInternalServerError
+	}
+	return c.Status(status).JSON(fiber.Map{"results": results, "errors": failed})
+}
+
+// GetRiskHistory returns the stored risk levels and level transitions for a
+// location. `from` and `to` are RFC3339 timestamps and default to the last 24h.
+func GetRiskHistory(c *fiber.Ctx) error {
+	loc := locParam(c)
+	from, to, err := parseTimeRange(c, 24*time.Hour)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	history, err := repository.RiskHistoryRange(c.Context(), loc, from, to)
+	if err != nil {
+		logger.Error.Println("risk history fetch:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	transitions, err := repository.RiskTransitionRange(c.Context(), loc, from, to)
+	if err != nil {
+		logger.Error.Println("risk transition fetch:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(withResolution(c, fiber.Map{
+		"location":    loc,
+		"from":        from,
+		"to":          to,
+		"history":     history,
+		"transitions": transitions,
+	}))
 }
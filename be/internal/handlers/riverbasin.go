@@ -2,16 +2,21 @@ package handlers
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/lolwierd/weatherboy/be/internal/locate"
 	"github.com/lolwierd/weatherboy/be/internal/logger"
+	"github.com/lolwierd/weatherboy/be/internal/model"
 	"github.com/lolwierd/weatherboy/be/internal/repository"
 )
 
 func GetRiverBasin(c *fiber.Ctx) error {
-	loc := c.Params("loc")
-	r, err := repository.LatestRiverBasinQPF(c.Context(), loc)
+	loc := locParam(c)
+	r, err := repository.LatestRiverBasinQPFForLocation(c.Context(), loc)
 	if err != nil {
 		logger.Error.Println("river basin fetch:", err)
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not found"})
 	}
-	return c.JSON(r)
+	return c.JSON(struct {
+		*model.RiverBasinQPF
+		Resolved *locate.Resolution `json:"resolved,omitempty"`
+	}{r, resolution(c)})
 }
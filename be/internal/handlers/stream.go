@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/events"
+	"github.com/lolwierd/weatherboy/be/internal/logger"
+	"github.com/lolwierd/weatherboy/be/internal/score"
+)
+
+const (
+	// streamPingInterval is how often an idle stream sends a comment so
+	// proxies keep it open and dead clients are noticed.
+	streamPingInterval = 15 * time.Second
+	// streamWriteTimeout bounds each write to a stream. It replaces the
+	// server's write timeout, which would otherwise end every stream.
+	streamWriteTimeout = 10 * time.Second
+)
+
+// subscribe subscribes a stream to the events of a location. Tests replace
+// it to feed streams their own events.
+var subscribe = events.Subscribe
+
+// GetStream streams the events of a location as Server-Sent Events: its
+// current risk level first, then an event whenever a fetch lands new data for
+// it, or for no location in particular such as AWS/ARG observations, and
+// whenever its risk level changes. `types` limits the stream to a
+// comma-separated list of event types.
+func GetStream(c *fiber.Ctx) error {
+	loc := locParam(c)
+	if _, ok := config.LocationByName(loc); !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "unknown location " + loc})
+	}
+	var types map[string]bool
+	if q := c.Query("types"); q != "" {
+		types = map[string]bool{}
+		for _, t := range strings.Split(q, ",") {
+			types[strings.TrimSpace(t)] = true
+		}
+	}
+	wants := func(e events.Event) bool { return types == nil || types[e.Type] }
+
+	var initial *events.Event
+	if res, err := score.RiskLevel(c.Context(), loc); err != nil {
+		logger.Error.Println("risk level:", err)
+	} else {
+		initial = &events.Event{
+			Type:     events.TypeRisk,
+			Location: loc,
+			At:       time.Now(),
+			Data:     map[string]any{"level": res.Level, "score": res.Score, "breakdown": res.Breakdown},
+		}
+	}
+
+	ch, unsubscribe := subscribe(loc)
+	conn := c.Context().Conn()
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+		write := func(msg string) error {
+			if err := conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil {
+				return err
+			}
+			if _, err := w.WriteString(msg); err != nil {
+				return err
+			}
+			return w.Flush()
+		}
+
+		if initial != nil && wants(*initial) {
+			if err := write(sseMessage(*initial)); err != nil {
+				return
+			}
+		}
+		ping := time.NewTicker(streamPingInterval)
+		defer ping.Stop()
+		for {
+			select {
+			case e, ok := <-ch:
+				if !ok {
+					return
+				}
+				if !wants(e) {
+					continue
+				}
+				if err := write(sseMessage(e)); err != nil {
+					return
+				}
+			case <-ping.C:
+				if err := write(": ping\n\n"); err != nil {
+					return
+				}
+			}
+		}
+	})
+	return nil
+}
+
+// sseMessage formats e as a Server-Sent Events message named by its type.
+func sseMessage(e events.Event) string {
+	b, err := json.Marshal(e)
+	if err != nil {
+		// Event data is built from plain values, so this only guards
+		// against a programming error.
+		b = []byte(fmt.Sprintf(`{"type":%q,"error":"unencodable event"}`, e.Type))
+	}
+	return "event: " + e.Type + "\ndata: " + string(b) + "\n\n"
+}
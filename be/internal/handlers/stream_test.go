@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/lolwierd/weatherboy/be/internal/events"
+)
+
+// fakeSubscription stands in for events.Subscribe, handing streams ch and
+// recording the location subscribed to and when the subscription ends.
+type fakeSubscription struct {
+	ch     chan events.Event
+	loc    string
+	ended  chan struct{}
+	endOne sync.Once
+}
+
+func stubSubscribe(t *testing.T) *fakeSubscription {
+	s := &fakeSubscription{ch: make(chan events.Event, 16), ended: make(chan struct{})}
+	saved := subscribe
+	t.Cleanup(func() { subscribe = saved })
+	subscribe = func(loc string) (<-chan events.Event, func()) {
+		s.loc = loc
+		return s.ch, func() { s.endOne.Do(func() { close(s.ended) }) }
+	}
+	return s
+}
+
+// sseEvent is one parsed Server-Sent Events message.
+type sseEvent struct {
+	name string
+	data events.Event
+}
+
+// readSSE reads the next message from r, skipping comments.
+func readSSE(t *testing.T, r *bufio.Reader) (sseEvent, bool) {
+	t.Helper()
+	var e sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		if err == io.EOF {
+			return e, false
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && e.name != "":
+			return e, true
+		case strings.HasPrefix(line, "event: "):
+			e.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e.data); err != nil {
+				t.Fatalf("data %q: %v", line, err)
+			}
+		}
+	}
+}
+
+func TestGetStream(t *testing.T) {
+	mock := setupMock(t)
+	defer mock.Close()
+
+	app := fiber.New()
+	app.Get("/stream/:loc", GetStream)
+
+	cases := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"risk", "bulletin", "nowcast"}},
+		{"?types=risk,%20nowcast", []string{"risk", "nowcast"}},
+		{"?types=bulletin", []string{"bulletin"}},
+	}
+	for _, tc := range cases {
+		s := stubSubscribe(t)
+		s.ch <- events.Event{Type: "bulletin", Location: "vadodara"}
+		s.ch <- events.Event{Type: "nowcast", Location: "vadodara", Data: map[string]any{"captured_at": "2024-06-20T08:30:00Z"}}
+		close(s.ch)
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/stream/vadodara"+tc.query, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusOK || resp.Header.Get(fiber.HeaderContentType) != "text/event-stream" {
+			t.Fatalf("%q: status %d, content type %q", tc.query, resp.StatusCode, resp.Header.Get(fiber.HeaderContentType))
+		}
+		r := bufio.NewReader(resp.Body)
+		var got []string
+		for {
+			e, ok := readSSE(t, r)
+			if !ok {
+				break
+			}
+			if e.name != e.data.Type {
+				t.Errorf("%q: event %q carries type %q", tc.query, e.name, e.data.Type)
+			}
+			if e.name == events.TypeRisk && (e.data.Location != "vadodara" || e.data.Data["level"] != "GREEN") {
+				t.Errorf("%q: initial risk event %+v", tc.query, e.data)
+			}
+			got = append(got, e.name)
+		}
+		if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("%q: events %v, want %v", tc.query, got, tc.want)
+		}
+		if s.loc != "vadodara" {
+			t.Errorf("%q: subscribed to %q", tc.query, s.loc)
+		}
+		select {
+		case <-s.ended:
+		default:
+			t.Errorf("%q: subscription still open after the stream ended", tc.query)
+		}
+	}
+
+	s := stubSubscribe(t)
+	resp, err := app.Test(httptest.NewRequest("GET", "/stream/atlantis", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNotFound || s.loc != "" {
+		t.Errorf("unknown location: status %d, subscribed to %q", resp.StatusCode, s.loc)
+	}
+}
+
+func TestGetStreamUnsubscribesOnDisconnect(t *testing.T) {
+	mock := setupMock(t)
+	defer mock.Close()
+	s := stubSubscribe(t)
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/stream/:loc", GetStream)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Listener(ln)
+	defer app.Shutdown()
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/stream/vadodara")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := readSSE(t, bufio.NewReader(resp.Body)); !ok || e.name != events.TypeRisk {
+		t.Fatalf("first event %+v, want the risk level", e)
+	}
+	resp.Body.Close()
+
+	// The stream notices the client is gone on its next write.
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case s.ch <- events.Event{Type: "nowcast", Location: "vadodara"}:
+		default:
+		}
+		select {
+		case <-s.ended:
+			return
+		case <-deadline:
+			t.Fatal("subscription still open after the client disconnected")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/logger"
+	"github.com/lolwierd/weatherboy/be/internal/repository"
+	"github.com/lolwierd/weatherboy/be/internal/score"
+)
+
+// summarySection loads one section of a summary and returns its data along
+// with the time the data describes, zero when it is not known.
+type summarySection func(ctx context.Context, loc string) (data any, asOf time.Time, err error)
+
+// summarySections are the sections of a summary, by name.
+var summarySections = map[string]summarySection{
+	"risk": func(ctx context.Context, loc string) (any, time.Time, error) {
+		res, err := score.RiskLevel(ctx, loc)
+		return res, res.AsOf, err
+	},
+	"bulletin": func(ctx context.Context, loc string) (any, time.Time, error) {
+		b, err := repository.LatestBulletin(ctx, loc)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		return b, b.IssuedAt, nil
+	},
+	"nowcast": func(ctx context.Context, loc string) (any, time.Time, error) {
+		n, err := repository.NowcastSlice(ctx, loc)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		if len(n) == 0 {
+			return nil, time.Time{}, fmt.Errorf("no nowcast for %s: %w", loc, pgx.ErrNoRows)
+		}
+		return n, n[0].CapturedAt, nil
+	},
+	"radar": func(ctx context.Context, loc string) (any, time.Time, error) {
+		r, err := repository.LatestRadarSnapshot(ctx, loc)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		return r, r.CapturedAt, nil
+	},
+	"riverbasin": func(ctx context.Context, loc string) (any, time.Time, error) {
+		r, err := repository.LatestRiverBasinQPFForLocation(ctx, loc)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		return r, r.FetchedAt, nil
+	},
+	"awsarg": func(ctx context.Context, loc string) (any, time.Time, error) {
+		obs, err := repository.AWSARGForLocation(ctx, loc)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		a, err := score.AggregateAWSARG(obs, score.AggIDW)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		var asOf time.Time
+		for _, o := range obs {
+			if t := o.Observation.ObservedAt(); t.After(asOf) {
+				asOf = t
+			}
+		}
+		return fiber.Map{"aggregation": score.AggIDW, "aggregate": a, "stations": obs}, asOf, nil
+	},
+}
+
+// summaryOrder lists every section in the order they are documented.
+var summaryOrder = []string{"risk", "bulletin", "nowcast", "radar", "riverbasin", "awsarg"}
+
+// summaryResult is one section of a summary. AsOf and AgeSeconds tell how
+// fresh the data is, for the risk section that of its oldest input, and are
+// null when that is not known; Error is set instead of Data when it could
+// not be loaded.
+type summaryResult struct {
+	Data       any        `json:"data"`
+	AsOf       *time.Time `json:"as_of"`
+	AgeSeconds *int64     `json:"age_seconds"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// GetSummary returns the current conditions of a location in one response:
+// its risk, bulletin, nowcast, radar, river basin and AWS/ARG sections,
+// loaded in parallel. `include` is a comma-separated subset of the sections
+// and defaults to all of them. A section that fails carries its error while
+// the others are still returned.
+func GetSummary(c *fiber.Ctx) error {
+	loc := locParam(c)
+	if _, ok := config.LocationByName(loc); !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "unknown location " + loc})
+	}
+	names := summaryOrder
+	if q := c.Query("include"); q != "" {
+		names = nil
+		for _, name := range strings.Split(q, ",") {
+			name = strings.TrimSpace(name)
+			if _, ok := summarySections[name]; !ok {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "unknown section " + name + ", want " + strings.Join(summaryOrder, ", ")})
+			}
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	ctx := repository.WithCache(c.Context())
+	now := time.Now()
+	sections := make(map[string]summaryResult, len(names))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, asOf, err := summarySections[name](ctx, loc)
+			var res summaryResult
+			if err != nil {
+				logger.Error.Println("summary", name, loc+":", err)
+				res.Error = err.Error()
+			} else {
+				res.Data = data
+				if !asOf.IsZero() {
+					age := int64(now.Sub(asOf).Seconds())
+					res.AsOf, res.AgeSeconds = &asOf, &age
+				}
+			}
+			mu.Lock()
+			sections[name] = res
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return c.JSON(withResolution(c, fiber.Map{"location": loc, "generated_at": now, "sections": sections}))
+}
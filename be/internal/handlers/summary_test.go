@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// stubSummarySections replaces every summary section for the test. Each
+// stub waits until n of them have started, so a summary loading its n
+// sections one at a time fails instead of hanging.
+func stubSummarySections(t *testing.T, asOf time.Time, n int) {
+	saved := summarySections
+	t.Cleanup(func() { summarySections = saved })
+
+	var started sync.WaitGroup
+	started.Add(n)
+	all := make(chan struct{})
+	go func() { started.Wait(); close(all) }()
+	var once sync.Once
+
+	summarySections = map[string]summarySection{}
+	for _, name := range summaryOrder {
+		summarySections[name] = func(ctx context.Context, loc string) (any, time.Time, error) {
+			started.Done()
+			select {
+			case <-all:
+			case <-time.After(2 * time.Second):
+				once.Do(func() { t.Error("sections were not loaded in parallel") })
+			}
+			switch name {
+			case "radar":
+				return nil, time.Time{}, errors.New("radar down")
+			case "risk":
+				return fiber.Map{"level": "GREEN"}, time.Time{}, nil
+			}
+			return fiber.Map{"section": name, "location": loc}, asOf, nil
+		}
+	}
+}
+
+type summaryBody struct {
+	Location string                   `json:"location"`
+	Sections map[string]summaryResult `json:"sections"`
+}
+
+func getSummary(t *testing.T, path string) (int, summaryBody) {
+	t.Helper()
+	app := fiber.New()
+	app.Get("/summary/:loc", GetSummary)
+	resp, err := app.Test(httptest.NewRequest("GET", path, nil), 5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body summaryBody
+	if resp.StatusCode == fiber.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, body
+}
+
+func TestGetSummary(t *testing.T) {
+	asOf := time.Now().Add(-10 * time.Minute).Truncate(time.Second)
+	stubSummarySections(t, asOf, len(summaryOrder))
+
+	status, body := getSummary(t, "/summary/vadodara")
+	if status != fiber.StatusOK || body.Location != "vadodara" || len(body.Sections) != len(summaryOrder) {
+		t.Fatalf("status %d, body %+v", status, body)
+	}
+	bulletin := body.Sections["bulletin"]
+	if bulletin.Error != "" || bulletin.AsOf == nil || !bulletin.AsOf.Equal(asOf) || bulletin.AgeSeconds == nil || *bulletin.AgeSeconds < 600 {
+		t.Errorf("bulletin = %+v", bulletin)
+	}
+	// A failed section carries its error while the others are served.
+	if radar := body.Sections["radar"]; radar.Error != "radar down" || radar.Data != nil || radar.AsOf != nil {
+		t.Errorf("radar = %+v", radar)
+	}
+	// A section without a known time has no freshness.
+	if risk := body.Sections["risk"]; risk.Data == nil || risk.AsOf != nil || risk.AgeSeconds != nil {
+		t.Errorf("risk = %+v", risk)
+	}
+}
+
+func TestGetSummaryInclude(t *testing.T) {
+	stubSummarySections(t, time.Now(), 2)
+
+	status, body := getSummary(t, "/summary/vadodara?include=bulletin,%20risk,bulletin")
+	if status != fiber.StatusOK || len(body.Sections) != 2 {
+		t.Fatalf("status %d, sections %+v", status, body.Sections)
+	}
+	if _, ok := body.Sections["risk"]; !ok {
+		t.Error("risk section missing")
+	}
+
+	if status, _ := getSummary(t, "/summary/vadodara?include=bulletin,forecast"); status != fiber.StatusBadRequest {
+		t.Errorf("unknown section: status %d, want %d", status, fiber.StatusBadRequest)
+	}
+	if status, _ := getSummary(t, "/summary/atlantis"); status != fiber.StatusNotFound {
+		t.Errorf("unknown location: status %d, want %d", status, fiber.StatusNotFound)
+	}
+}
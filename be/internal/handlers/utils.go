@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// parseTimeRange reads the RFC3339 `from` and `to` query parameters. A
+// missing `to` defaults to now and a missing `from` to `to` minus def. Both are
+// returned in UTC whatever offset they were given with.
+func parseTimeRange(c *fiber.Ctx, def time.Duration) (time.Time, time.Time, error) {
+	to := time.Now()
+	if s := c.Query("to"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to: %w", err)
+		}
+		to = t
+	}
+	from := to.Add(-def)
+	if s := c.Query("from"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid from: %w", err)
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from must be before to")
+	}
+	return from.UTC(), to.UTC(), nil
+}
+
+// fitStep widens step to whole minutes so that [from, to) holds at most max
+// buckets.
+func fitStep(from, to time.Time, step time.Duration, max int) time.Duration {
+	span := to.Sub(from)
+	if span/step <= time.Duration(max) {
+		return step
+	}
+	step = (span + time.Duration(max) - 1) / time.Duration(max)
+	return ((step + time.Minute - 1) / time.Minute) * time.Minute
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestParseTimeRangeUTC(t *testing.T) {
+	app := fiber.New()
+	var from, to time.Time
+	app.Get("/", func(c *fiber.Ctx) error {
+		var err error
+		from, to, err = parseTimeRange(c, time.Hour)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+		return nil
+	})
+
+	q := url.Values{"from": {"2024-06-20T05:30:00+05:30"}, "to": {"2024-06-20T08:30:00+05:30"}}
+	resp, err := app.Test(httptest.NewRequest("GET", "/?"+q.Encode(), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	if want := time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC); from != want {
+		t.Errorf("from = %v, want %v", from, want)
+	}
+	if want := time.Date(2024, 6, 20, 3, 0, 0, 0, time.UTC); to != want {
+		t.Errorf("to = %v, want %v", to, want)
+	}
+}
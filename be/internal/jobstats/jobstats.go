@@ -0,0 +1,48 @@
+// Package jobstats accumulates what a scheduled job run did, such as bytes
+// downloaded and rows written, through its context.
+package jobstats
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Stats holds the counters of one job run. It is safe for concurrent use.
+type Stats struct {
+	bytes atomic.Int64
+	rows  atomic.Int64
+}
+
+type ctxKey struct{}
+
+// NewContext returns a context carrying fresh Stats.
+func NewContext(ctx context.Context) (context.Context, *Stats) {
+	s := &Stats{}
+	return context.WithValue(ctx, ctxKey{}, s), s
+}
+
+// FromContext returns the Stats carried by ctx, or nil.
+func FromContext(ctx context.Context) *Stats {
+	s, _ := ctx.Value(ctxKey{}).(*Stats)
+	return s
+}
+
+// AddBytes records n bytes downloaded. It is a no-op outside a job run.
+func AddBytes(ctx context.Context, n int64) {
+	if s := FromContext(ctx); s != nil {
+		s.bytes.Add(n)
+	}
+}
+
+// AddRows records n rows written. It is a no-op outside a job run.
+func AddRows(ctx context.Context, n int64) {
+	if s := FromContext(ctx); s != nil {
+		s.rows.Add(n)
+	}
+}
+
+// Bytes returns the bytes downloaded so far.
+func (s *Stats) Bytes() int64 { return s.bytes.Load() }
+
+// Rows returns the rows written so far.
+func (s *Stats) Rows() int64 { return s.rows.Load() }
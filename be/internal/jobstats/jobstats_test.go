@@ -0,0 +1,18 @@
+package jobstats
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	AddRows(context.Background(), 1) // no stats in context, must not panic
+
+	ctx, s := NewContext(context.Background())
+	AddBytes(ctx, 100)
+	AddRows(ctx, 2)
+	AddRows(ctx, 3)
+	if s.Bytes() != 100 || s.Rows() != 5 {
+		t.Fatalf("unexpected stats bytes=%d rows=%d", s.Bytes(), s.Rows())
+	}
+}
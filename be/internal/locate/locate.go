@@ -0,0 +1,83 @@
+// Package locate resolves coordinates to the served location that covers
+// them.
+package locate
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/geo"
+)
+
+// How a coordinate was resolved.
+const (
+	// MethodDistrict means the coordinate lies in the district of the
+	// resolved location.
+	MethodDistrict = "district"
+	// MethodNearest means no served location covers the coordinate's
+	// district, and the nearest location was used.
+	MethodNearest = "nearest"
+)
+
+var (
+	// ErrInvalidCoordinate is returned for a latitude or longitude out of
+	// range.
+	ErrInvalidCoordinate = errors.New("invalid coordinate")
+	// ErrNoLocations is returned when no location is served.
+	ErrNoLocations = errors.New("no locations served")
+)
+
+// Resolution is the served location a coordinate resolved to.
+type Resolution struct {
+	Location string  `json:"location"`
+	Lat      float64 `json:"lat"`
+	Lon      float64 `json:"lon"`
+	// District is the district containing the coordinate, empty when it lies
+	// outside every known district.
+	District   string  `json:"district,omitempty"`
+	Method     string  `json:"method"`
+	DistanceKM float64 `json:"distance_km"`
+}
+
+// Resolve returns the location serving (lat, lon): the nearest location in
+// the district containing it, or else the nearest location.
+func Resolve(lat, lon float64) (Resolution, error) {
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return Resolution{}, fmt.Errorf("%w %g,%g", ErrInvalidCoordinate, lat, lon)
+	}
+	ls := config.Locations()
+	if len(ls) == 0 {
+		return Resolution{}, ErrNoLocations
+	}
+	res := Resolution{Lat: lat, Lon: lon, Method: MethodNearest}
+
+	d, ok, err := geo.DistrictAt(lat, lon)
+	if err != nil {
+		return Resolution{}, err
+	}
+	if ok {
+		res.District = d.Name
+		var inDistrict []config.Location
+		for _, l := range ls {
+			if (d.ID != 0 && l.DistrictID == d.ID) || d.Contains(l.Lat, l.Lon) {
+				inDistrict = append(inDistrict, l)
+			}
+		}
+		if len(inDistrict) > 0 {
+			ls = inDistrict
+			res.Method = MethodDistrict
+		}
+	}
+
+	pos := func(i int) (float64, float64) { return ls[i].Lat, ls[i].Lon }
+	nearest := geo.Nearest(len(ls), pos, lat, lon, 1, maxDistanceKM)
+	n := nearest[0]
+	res.Location = ls[n.Index].Name
+	res.DistanceKM = n.DistanceKM
+	return res, nil
+}
+
+// maxDistanceKM exceeds any distance on Earth, so Nearest searches without a
+// radius.
+const maxDistanceKM = 1e5
@@ -0,0 +1,41 @@
+package locate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lolwierd/weatherboy/be/internal/geo"
+)
+
+func TestResolve(t *testing.T) {
+	if err := geo.LoadDistricts("../geo/testdata/districts.geojson"); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name     string
+		lat, lon float64
+		loc      string
+		method   string
+	}{
+		{"vadodara outskirts", 22.45, 73.40, "vadodara", MethodDistrict},
+		{"thane", 19.30, 73.10, "thane", MethodDistrict},
+		// Mumbai City has no location of its own.
+		{"colaba", 18.92, 72.83, "mumbai", MethodNearest},
+		{"surat", 21.17, 72.83, "vadodara", MethodNearest},
+	}
+	for _, tc := range cases {
+		res, err := Resolve(tc.lat, tc.lon)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if res.Location != tc.loc || res.Method != tc.method {
+			t.Errorf("%s: resolved to %s by %s, want %s by %s", tc.name, res.Location, res.Method, tc.loc, tc.method)
+		}
+	}
+}
+
+func TestResolveInvalid(t *testing.T) {
+	if _, err := Resolve(95, 73); !errors.Is(err, ErrInvalidCoordinate) {
+		t.Fatalf("err = %v, want ErrInvalidCoordinate", err)
+	}
+}
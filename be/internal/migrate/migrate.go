@@ -0,0 +1,246 @@
+// Package migrate applies the embedded SQL migrations. It keeps its state in
+// the same `schema_migrations` table golang-migrate uses, so databases
+// migrated with the `migrate` CLI are picked up where they left off.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/lolwierd/weatherboy/be/internal/db"
+	"github.com/lolwierd/weatherboy/be/internal/logger"
+	"github.com/lolwierd/weatherboy/be/migrations"
+)
+
+// lockKey serialises migrations across replicas started together.
+const lockKey int64 = 0x6d696772617465 // "migrate"
+
+// Migration is one numbered schema change.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes the schema version of the database.
+type Status struct {
+	// Current is the applied version, 0 when nothing has been applied.
+	Current int
+	// Dirty is set when a migration failed half-way and needs fixing by hand.
+	Dirty bool
+	// Latest is the newest embedded version.
+	Latest int
+	// Pending lists the embedded migrations not applied yet.
+	Pending []Migration
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Load reads and orders the migrations in fsys. Every version needs both an
+// up and a down file.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		v, _ := strconv.Atoi(m[1])
+		b, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		mig, ok := byVersion[v]
+		if !ok {
+			mig = &Migration{Version: v, Name: m[2]}
+			byVersion[v] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", v, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(b)
+		} else {
+			mig.Down = string(b)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both up and down files", m.Version, m.Name)
+		}
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+const createSchemaMigrations = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT NOT NULL PRIMARY KEY,
+    dirty BOOLEAN NOT NULL
+)
+`
+
+// currentVersion returns the applied version, creating the bookkeeping table
+// on first use.
+func currentVersion(ctx context.Context, tx pgx.Tx) (int, bool, error) {
+	if _, err := tx.Exec(ctx, createSchemaMigrations); err != nil {
+		return 0, false, err
+	}
+	var v int
+	var dirty bool
+	err := tx.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&v, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	return v, dirty, err
+}
+
+func setVersion(ctx context.Context, tx pgx.Tx, v int) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return err
+	}
+	if v == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)`, v)
+	return err
+}
+
+// inLockedTx runs fn in a transaction holding the migration lock.
+func inLockedTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := db.GetDBDriver().ConnPool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, lockKey); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// GetStatus compares the database schema with the embedded migrations.
+func GetStatus(ctx context.Context) (Status, error) {
+	list, err := Load(migrations.FS)
+	if err != nil {
+		return Status{}, err
+	}
+	var s Status
+	err = inLockedTx(ctx, func(tx pgx.Tx) error {
+		s.Current, s.Dirty, err = currentVersion(ctx, tx)
+		return err
+	})
+	if err != nil {
+		return Status{}, err
+	}
+	for _, m := range list {
+		s.Latest = m.Version
+		if m.Version > s.Current {
+			s.Pending = append(s.Pending, m)
+		}
+	}
+	return s, nil
+}
+
+// Up applies every pending migration, each in its own transaction together
+// with the version bump.
+func Up(ctx context.Context) error {
+	list, err := Load(migrations.FS)
+	if err != nil {
+		return err
+	}
+	for _, m := range list {
+		applied := false
+		err := inLockedTx(ctx, func(tx pgx.Tx) error {
+			cur, dirty, err := currentVersion(ctx, tx)
+			if err != nil {
+				return err
+			}
+			if dirty {
+				return fmt.Errorf("schema version %d is dirty, fix it by hand", cur)
+			}
+			if m.Version <= cur {
+				return nil
+			}
+			if _, err := tx.Exec(ctx, m.Up); err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", m.Version, m.Name, err)
+			}
+			applied = true
+			return setVersion(ctx, tx, m.Version)
+		})
+		if err != nil {
+			return err
+		}
+		if applied {
+			logger.Info.Printf("applied migration %d_%s", m.Version, m.Name)
+		}
+	}
+	return nil
+}
+
+// Down rolls back the latest applied migration.
+func Down(ctx context.Context) error {
+	list, err := Load(migrations.FS)
+	if err != nil {
+		return err
+	}
+	return inLockedTx(ctx, func(tx pgx.Tx) error {
+		cur, dirty, err := currentVersion(ctx, tx)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("schema version %d is dirty, fix it by hand", cur)
+		}
+		if cur == 0 {
+			return fmt.Errorf("no migration to roll back")
+		}
+		prev := 0
+		for i, m := range list {
+			if m.Version != cur {
+				continue
+			}
+			if i > 0 {
+				prev = list[i-1].Version
+			}
+			if _, err := tx.Exec(ctx, m.Down); err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", m.Version, m.Name, err)
+			}
+			logger.Info.Printf("rolled back migration %d_%s", m.Version, m.Name)
+			return setVersion(ctx, tx, prev)
+		}
+		return fmt.Errorf("applied version %d has no embedded migration", cur)
+	})
+}
+
+// Check fails when the schema is dirty or behind the embedded migrations.
+func Check(ctx context.Context) error {
+	s, err := GetStatus(ctx)
+	if err != nil {
+		return err
+	}
+	if s.Dirty {
+		return fmt.Errorf("schema version %d is dirty", s.Current)
+	}
+	if len(s.Pending) > 0 {
+		return fmt.Errorf("schema is at version %d, %d migrations behind %d; run with -run migrate up or -auto-migrate", s.Current, len(s.Pending), s.Latest)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/lolwierd/weatherboy/be/migrations"
+)
+
+func TestLoadEmbedded(t *testing.T) {
+	list, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(list) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for i, m := range list {
+		if m.Version != i+1 {
+			t.Fatalf("migration %d_%s out of sequence, want version %d", m.Version, m.Name, i+1)
+		}
+	}
+}
+
+func TestLoadNeedsBothDirections(t *testing.T) {
+	fsys := fstest.MapFS{
+		"001_a.up.sql":   {Data: []byte("SELECT 1")},
+		"001_a.down.sql": {Data: []byte("SELECT 1")},
+		"002_b.up.sql":   {Data: []byte("SELECT 1")},
+	}
+	if _, err := Load(fsys); err == nil {
+		t.Fatal("expected error for missing down file")
+	}
+}
@@ -30,16 +30,33 @@ type Nowcast struct {
 	LeadMin    int       `db:"lead_min"`
 	POP        float64   `db:"pop"`
 	MMPerHr    float64   `db:"mm_per_hr"`
-	CreatedAt  time.Time `db:"created_at"`
+	// NowcastRawID is the raw payload the row was parsed from.
+	NowcastRawID  *int      `db:"nowcast_raw_id"`
+	ParserVersion int       `db:"parser_version"`
+	CreatedAt     time.Time `db:"created_at"`
 }
 
-// BulletinRaw records a fetched bulletin PDF path and time.
+// BulletinRaw records a fetched bulletin PDF. Path identifies the bulletin of
+// a day; BlobKey is where the PDF is kept in the blob store, nil for
+// bulletins fetched before the blob store, whose PDF is on local disk at
+// Path.
 type BulletinRaw struct {
 	ID        int       `db:"id"`
 	Path      string    `db:"path"`
+	BlobKey   *string   `db:"blob_key"`
 	FetchedAt time.Time `db:"fetched_at"`
 }
 
+// Blob records an object kept in the blob store. Keys are content-addressed,
+// so a blob never changes once stored.
+type Blob struct {
+	Key         string    `db:"key"`
+	SHA256      string    `db:"sha256"`
+	Size        int64     `db:"size"`
+	ContentType string    `db:"content_type"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
 // IMDAPICall records each call made to IMD endpoints.
 type IMDAPICall struct {
 	ID          int       `db:"id"`
@@ -79,7 +96,20 @@ type DistrictWarning struct {
 	Day3Color   string    `db:"day3_color"`
 	Day4Color   string    `db:"day4_color"`
 	Day5Color   string    `db:"day5_color"`
-	CreatedAt   time.Time `db:"created_at"`
+	// DistrictWarningRawID is the raw payload the row was parsed from.
+	DistrictWarningRawID *int      `db:"district_warning_raw_id"`
+	ParserVersion        int       `db:"parser_version"`
+	CreatedAt            time.Time `db:"created_at"`
+}
+
+// ForecastDay returns the forecast day of dw that covers t in zone: 1 on the
+// local date dw was issued, 2 on the next date, and so on.
+func (dw DistrictWarning) ForecastDay(t time.Time, zone *time.Location) int {
+	iy, im, id := dw.IssuedAt.In(zone).Date()
+	ty, tm, td := t.In(zone).Date()
+	issued := time.Date(iy, im, id, 0, 0, 0, 0, time.UTC)
+	today := time.Date(ty, tm, td, 0, 0, 0, 0, time.UTC)
+	return int(today.Sub(issued)/(24*time.Hour)) + 1
 }
 
 // DistrictWarningRaw stores the unparsed district warning JSON for historical reference.
@@ -96,6 +126,7 @@ type BulletinParsed struct {
 	BulletinRawID int       `db:"bulletin_raw_id"`
 	Location      string    `db:"location"`
 	Forecast      string    `db:"forecast"`
+	ParserVersion int       `db:"parser_version"`
 	FetchedAt     time.Time `db:"fetched_at"`
 }
 
@@ -110,47 +141,147 @@ type Radar struct {
 
 // RiverBasinQPF mirrors the `river_basin_qpf` table.
 type RiverBasinQPF struct {
-	ID        int       `db:"id"`
-	BasinID   int       `db:"basin_id"`
-	Date      time.Time `db:"date"`
-	FMO       string    `db:"fmo"`
-	Basin     string    `db:"basin"`
-	SubBasin  string    `db:"sub_basin"`
-	Area      string    `db:"area"`
-	Day1      string    `db:"day1"`
-	Day2      string    `db:"day2"`
-	Day3      string    `db:"day3"`
-	Day4      string    `db:"day4"`
-	Day5      string    `db:"day5"`
-	AAP       string    `db:"aap"`
-	FetchedAt time.Time `db:"fetched_at"`
+	ID         int       `db:"id"`
+	BasinID    int       `db:"basin_id"`
+	SubBasinID int       `db:"sub_basin_id"`
+	Date       time.Time `db:"date"`
+	FMO        string    `db:"fmo"`
+	Basin      string    `db:"basin"`
+	SubBasin   string    `db:"sub_basin"`
+	Area       string    `db:"area"`
+	Day1       string    `db:"day1"`
+	Day2       string    `db:"day2"`
+	Day3       string    `db:"day3"`
+	Day4       string    `db:"day4"`
+	Day5       string    `db:"day5"`
+	AAP        string    `db:"aap"`
+	FetchedAt  time.Time `db:"fetched_at"`
 }
 
-// AWSARG mirrors the `aws_arg` table.
+// AWSARG mirrors the `aws_arg` table. Numeric readings are nil when the
+// station did not report them or they failed a range check; QC maps a field
+// name to the quality-control flags raised for it.
 type AWSARG struct {
-	ID            int       `db:"id"`
-	StationID     string    `db:"station_id"`
-	CallSign      string    `db:"call_sign"`
-	District      string    `db:"district"`
-	State         string    `db:"state"`
-	StationName   string    `db:"station_name"`
-	Date          time.Time `db:"date"`
-	Time          time.Time `db:"time"`
-	CurrentTemp   float64   `db:"current_temp"`
-	DewPointTemp  float64   `db:"dew_point_temp"`
-	RH            float64   `db:"rh"`
-	WindDirection float64   `db:"wind_direction"`
-	WindSpeed     float64   `db:"wind_speed"`
-	MSLP          float64   `db:"mslp"`
-	MinTemp       float64   `db:"min_temp"`
-	MaxTemp       float64   `db:"max_temp"`
-	Latitude      float64   `db:"latitude"`
-	Longitude     float64   `db:"longitude"`
-	WeatherCode   string    `db:"weather_code"`
-	Nebulosity    float64   `db:"nebulosity"`
-	FeelLike      float64   `db:"feel_like"`
-	RainfallSel   string    `db:"rainfall_sel"`
-	Rainfall      float64   `db:"rainfall"`
-	FetchedAt     time.Time `db:"fetched_at"`
+	ID            int                 `db:"id"`
+	StationID     string              `db:"station_id"`
+	CallSign      string              `db:"call_sign"`
+	District      string              `db:"district"`
+	State         string              `db:"state"`
+	StationName   string              `db:"station_name"`
+	Date          time.Time           `db:"date"`
+	Time          time.Time           `db:"time"`
+	CurrentTemp   *float64            `db:"current_temp"`
+	DewPointTemp  *float64            `db:"dew_point_temp"`
+	RH            *float64            `db:"rh"`
+	WindDirection *float64            `db:"wind_direction"`
+	WindSpeed     *float64            `db:"wind_speed"`
+	MSLP          *float64            `db:"mslp"`
+	MinTemp       *float64            `db:"min_temp"`
+	MaxTemp       *float64            `db:"max_temp"`
+	Latitude      float64             `db:"latitude"`
+	Longitude     float64             `db:"longitude"`
+	WeatherCode   string              `db:"weather_code"`
+	Nebulosity    *float64            `db:"nebulosity"`
+	FeelLike      *float64            `db:"feel_like"`
+	RainfallSel   string              `db:"rainfall_sel"`
+	Rainfall      *float64            `db:"rainfall"`
+	QC            map[string][]string `db:"qc"`
+	FetchedAt     time.Time           `db:"fetched_at"`
+}
+
+// RiskHistory mirrors the `risk_history` table.
+type RiskHistory struct {
+	ID         int                `db:"id"`
+	Location   string             `db:"location"`
+	Level      string             `db:"level"`
+	Score      float64            `db:"score"`
+	Breakdown  map[string]float64 `db:"breakdown"`
+	ComputedAt time.Time          `db:"computed_at"`
+}
+
+// RiskTransition records a change in risk level for a location.
+type RiskTransition struct {
+	ID             int       `db:"id"`
+	Location       string    `db:"location"`
+	FromLevel      string    `db:"from_level"`
+	ToLevel        string    `db:"to_level"`
+	Score          float64   `db:"score"`
+	TransitionedAt time.Time `db:"transitioned_at"`
+}
+
+// AWSStation mirrors the `aws_station` table, the catalogue of AWS/ARG
+// stations seen in API responses.
+type AWSStation struct {
+	StationID   string    `db:"station_id"`
+	CallSign    string    `db:"call_sign"`
+	StationName string    `db:"station_name"`
+	District    string    `db:"district"`
+	State       string    `db:"state"`
+	Latitude    float64   `db:"latitude"`
+	Longitude   float64   `db:"longitude"`
+	FirstSeenAt time.Time `db:"first_seen_at"`
+	LastSeenAt  time.Time `db:"last_seen_at"`
+}
+
+// AWSStationObservation pairs a station's latest observation with its
+// distance from the location it was resolved for.
+type AWSStationObservation struct {
+	Observation AWSARG
+	DistanceKM  float64
+}
+
+// ObservedAt combines the observation date and time of day.
+func (a AWSARG) ObservedAt() time.Time {
+	return a.Date.Add(time.Duration(a.Time.Hour())*time.Hour +
+		time.Duration(a.Time.Minute())*time.Minute +
+		time.Duration(a.Time.Second())*time.Second)
+}
+
+// AWSRainfall mirrors the `aws_rainfall` table, the rain accumulated at a
+// station over a window ending at EndsAt.
+type AWSRainfall struct {
+	ID         int       `db:"id"`
+	StationID  string    `db:"station_id"`
+	Window     string    `db:"window"`
+	EndsAt     time.Time `db:"ends_at"`
+	MM         float64   `db:"mm"`
+	Readings   int       `db:"readings"`
+	ComputedAt time.Time `db:"computed_at"`
+}
+
+// AWSStationRainfall pairs a station's latest rainfall accumulation with its
+// distance from the location it was resolved for.
+type AWSStationRainfall struct {
+	Rainfall   AWSRainfall
+	DistanceKM float64
 }
 
+// SeriesPoint is one time bucket of an aggregated observation series. The
+// statistics are nil when no reading in the bucket had a value.
+type SeriesPoint struct {
+	Bucket time.Time
+	Min    *float64
+	Max    *float64
+	Mean   *float64
+	Last   *float64
+	Count  int
+}
+
+// Job run statuses stored in `job_run.status`.
+const (
+	JobRunRunning = "running"
+	JobRunSuccess = "success"
+	JobRunFailure = "failure"
+)
+
+// JobRun mirrors the `job_run` table, one execution of a scheduled job.
+type JobRun struct {
+	ID          int        `db:"id"`
+	JobName     string     `db:"job_name"`
+	StartedAt   time.Time  `db:"started_at"`
+	FinishedAt  *time.Time `db:"finished_at"`
+	Status      string     `db:"status"`
+	Error       *string    `db:"error"`
+	Bytes       int64      `db:"bytes"`
+	RowsWritten int64      `db:"rows_written"`
+}
@@ -84,7 +84,11 @@ func New(opts ...Option) fiber.Handler {
 		)
 
 		requestSize := int64(len(c.Request().Body()))
-		responseSize := int64(len(c.Response().Body()))
+		// Reading a streamed body would drain it, so streams record no size.
+		var responseSize int64
+		if !c.Response().IsBodyStream() {
+			responseSize = int64(len(c.Response().Body()))
+		}
 
 		defer func() {
 			responseMetricAttrs = append(
@@ -0,0 +1,34 @@
+package qc
+
+import "math"
+
+// FeelsLike estimates the apparent temperature in °C from the air
+// temperature (°C), relative humidity (%) and wind speed (km/h). It uses the
+// NWS heat index when it is hot and humid, the wind chill when it is cold and
+// windy and the air temperature otherwise. It returns nil without a
+// temperature.
+func FeelsLike(temp, rh, wind *float64) *float64 {
+	if temp == nil {
+		return nil
+	}
+	t := *temp
+	v := t
+	switch {
+	case rh != nil && t >= 27 && *rh >= 40:
+		v = heatIndex(t, *rh)
+	case wind != nil && t <= 10 && *wind > 4.8:
+		w := math.Pow(*wind, 0.16)
+		v = 13.12 + 0.6215*t - 11.37*w + 0.3965*t*w
+	}
+	v = math.Round(v*10) / 10
+	return &v
+}
+
+// heatIndex implements the Rothfusz regression, which works in °F.
+func heatIndex(t, rh float64) float64 {
+	f := t*9/5 + 32
+	hi := -42.379 + 2.04901523*f + 10.14333127*rh -
+		0.22475541*f*rh - 0.00683783*f*f - 0.05481717*rh*rh +
+		0.00122874*f*f*rh + 0.00085282*f*rh*rh - 0.00000199*f*f*rh*rh
+	return (hi - 32) * 5 / 9
+}
@@ -0,0 +1,149 @@
+// Package qc runs quality-control checks on AWS/ARG observations.
+package qc
+
+import (
+	"math"
+	"time"
+
+	"github.com/lolwierd/weatherboy/be/internal/model"
+)
+
+// Flag values recorded against an observation field.
+const (
+	// Missing marks a field the station did not report.
+	Missing = "missing"
+	// OutOfRange marks a physically impossible reading. The value is dropped.
+	OutOfRange = "out_of_range"
+	// Spike marks a reading that jumped implausibly since the previous one.
+	Spike = "spike"
+	// Stuck marks a reading that has not changed over StuckWindow.
+	Stuck = "stuck"
+	// Derived marks a value computed by us rather than reported.
+	Derived = "derived"
+)
+
+const (
+	// HistoryWindow is how far back Check needs previous readings.
+	HistoryWindow = 6 * time.Hour
+	// spikeWindow bounds how old the previous reading may be for a spike check.
+	spikeWindow = 3 * time.Hour
+	// StuckWindow is how long a reading must be unchanged to be flagged.
+	StuckWindow = 6 * time.Hour
+	// stuckMinReadings avoids flagging a station that rarely reports.
+	stuckMinReadings = 4
+)
+
+// field describes the checks applied to one numeric observation field.
+type field struct {
+	name     string
+	get      func(a *model.AWSARG) **float64
+	min, max float64
+	// maxStep is the largest plausible change between consecutive readings;
+	// zero disables the spike check.
+	maxStep float64
+	// stuck enables the stuck sensor check. Fields that legitimately stay
+	// constant (rainfall, calm wind) leave it off.
+	stuck bool
+}
+
+var fields = []field{
+	{"current_temp", func(a *model.AWSARG) **float64 { return &a.CurrentTemp }, -30, 55, 8, true},
+	{"dew_point_temp", func(a *model.AWSARG) **float64 { return &a.DewPointTemp }, -40, 40, 10, true},
+	{"rh", func(a *model.AWSARG) **float64 { return &a.RH }, 0, 100, 40, true},
+	{"wind_direction", func(a *model.AWSARG) **float64 { return &a.WindDirection }, 0, 360, 0, false},
+	{"wind_speed", func(a *model.AWSARG) **float64 { return &a.WindSpeed }, 0, 250, 0, false},
+	{"mslp", func(a *model.AWSARG) **float64 { return &a.MSLP }, 870, 1085, 8, true},
+	{"min_temp", func(a *model.AWSARG) **float64 { return &a.MinTemp }, -30, 55, 0, false},
+	{"max_temp", func(a *model.AWSARG) **float64 { return &a.MaxTemp }, -30, 55, 0, false},
+	{"nebulosity", func(a *model.AWSARG) **float64 { return &a.Nebulosity }, 0, 9, 0, false},
+	{"feel_like", func(a *model.AWSARG) **float64 { return &a.FeelLike }, -50, 70, 0, false},
+	{"rainfall", func(a *model.AWSARG) **float64 { return &a.Rainfall }, 0, 1000, 0, false},
+}
+
+// Check runs every check on cur, drops out of range values, fills in a
+// missing feels-like temperature and records the raised flags in cur.QC.
+// history holds earlier readings of the same station, in any order.
+func Check(cur *model.AWSARG, history []model.AWSARG) {
+	flags := map[string][]string{}
+	at := cur.ObservedAt()
+
+	for _, f := range fields {
+		v := f.get(cur)
+		if *v == nil {
+			if f.name != "feel_like" {
+				flags[f.name] = append(flags[f.name], Missing)
+			}
+			continue
+		}
+		if **v < f.min || **v > f.max || math.IsNaN(**v) {
+			flags[f.name] = append(flags[f.name], OutOfRange)
+			*v = nil
+			continue
+		}
+		if f.maxStep > 0 {
+			if prev := previous(history, at, f); prev != nil && math.Abs(**v-*prev) > f.maxStep {
+				flags[f.name] = append(flags[f.name], Spike)
+			}
+		}
+		if f.stuck && stuck(history, at, **v, f) {
+			flags[f.name] = append(flags[f.name], Stuck)
+		}
+	}
+
+	if cur.FeelLike == nil {
+		if fl := FeelsLike(cur.CurrentTemp, cur.RH, cur.WindSpeed); fl != nil {
+			cur.FeelLike = fl
+			flags["feel_like"] = append(flags["feel_like"], Derived)
+		} else {
+			flags["feel_like"] = append(flags["feel_like"], Missing)
+		}
+	}
+
+	cur.QC = flags
+}
+
+// previous returns the field value of the latest reading before at and within
+// spikeWindow.
+func previous(history []model.AWSARG, at time.Time, f field) *float64 {
+	var best *model.AWSARG
+	for i := range history {
+		h := &history[i]
+		t := h.ObservedAt()
+		if !t.Before(at) || at.Sub(t) > spikeWindow || *f.get(h) == nil {
+			continue
+		}
+		if best == nil || t.After(best.ObservedAt()) {
+			best = h
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return *f.get(best)
+}
+
+// stuck reports whether every reading within StuckWindow before at equals v
+// and the readings span most of the window.
+func stuck(history []model.AWSARG, at time.Time, v float64, f field) bool {
+	n := 1
+	oldest := at
+	for i := range history {
+		h := &history[i]
+		t := h.ObservedAt()
+		if !t.Before(at) || at.Sub(t) > StuckWindow {
+			continue
+		}
+		hv := *f.get(h)
+		if hv == nil {
+			continue
+		}
+		if *hv != v {
+			return false
+		}
+		n++
+		if t.Before(oldest) {
+			oldest = t
+		}
+	}
+	return n >= stuckMinReadings && at.Sub(oldest) >= StuckWindow/2
+}
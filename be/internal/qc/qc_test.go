@@ -0,0 +1,64 @@
+package qc
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/lolwierd/weatherboy/be/internal/model"
+)
+
+func f(v float64) *float64 { return &v }
+
+func obs(at time.Time, temp, rh, mslp *float64) model.AWSARG {
+	day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+	return model.AWSARG{
+		Date:        day,
+		Time:        time.Date(0, 1, 1, at.Hour(), at.Minute(), 0, 0, time.UTC),
+		CurrentTemp: temp,
+		RH:          rh,
+		MSLP:        mslp,
+	}
+}
+
+func TestCheck(t *testing.T) {
+	at := time.Date(2024, 6, 20, 12, 0, 0, 0, time.UTC)
+	cur := obs(at, f(39), f(150), f(1002))
+	var history []model.AWSARG
+	for h := 1; h <= 5; h++ {
+		history = append(history, obs(at.Add(-time.Duration(h)*time.Hour), f(30), f(60), f(1002)))
+	}
+
+	Check(&cur, history)
+
+	if !slices.Contains(cur.QC["rh"], OutOfRange) || cur.RH != nil {
+		t.Errorf("expected rh dropped as out of range: %v", cur.QC["rh"])
+	}
+	if !slices.Contains(cur.QC["current_temp"], Spike) {
+		t.Errorf("expected temperature spike: %v", cur.QC["current_temp"])
+	}
+	if !slices.Contains(cur.QC["mslp"], Stuck) {
+		t.Errorf("expected stuck pressure: %v", cur.QC["mslp"])
+	}
+	if !slices.Contains(cur.QC["wind_speed"], Missing) {
+		t.Errorf("expected missing wind speed: %v", cur.QC["wind_speed"])
+	}
+	if cur.FeelLike == nil || !slices.Contains(cur.QC["feel_like"], Derived) {
+		t.Errorf("expected derived feels-like: %v", cur.QC["feel_like"])
+	}
+}
+
+func TestFeelsLike(t *testing.T) {
+	if FeelsLike(nil, f(50), nil) != nil {
+		t.Fatalf("expected nil without temperature")
+	}
+	if v := FeelsLike(f(22), f(50), f(10)); *v != 22 {
+		t.Fatalf("expected air temperature in mild weather, got %f", *v)
+	}
+	if v := FeelsLike(f(35), f(70), nil); *v <= 35 {
+		t.Fatalf("expected heat index above air temperature, got %f", *v)
+	}
+	if v := FeelsLike(f(5), nil, f(30)); *v >= 5 {
+		t.Fatalf("expected wind chill below air temperature, got %f", *v)
+	}
+}
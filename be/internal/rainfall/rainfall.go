@@ -0,0 +1,184 @@
+// Package rainfall derives rainfall accumulations from AWS/ARG rain gauge
+// readings.
+//
+// Each observation's RainfallSel names the period its rainfall value
+// accumulates over: either the rain since the start of the IMD rainfall day
+// at 08:30 IST (03:00 UTC), or the rain over a trailing number of hours.
+// AWS/ARG observation times are UTC, so readings are placed on the UTC
+// timeline. A day-start counter is expected to restart at every day start,
+// and one that drops mid-day is treated as a reset.
+package rainfall
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Window is an accumulation period ending at the latest reading.
+type Window string
+
+const (
+	Window1H  Window = "1h"
+	Window3H  Window = "3h"
+	Window24H Window = "24h"
+	// WindowSinceDayStart is the rain since 08:30 IST of the current IMD day.
+	WindowSinceDayStart Window = "since_0830"
+)
+
+// Windows lists every accumulation window computed.
+var Windows = []Window{Window1H, Window3H, Window24H, WindowSinceDayStart}
+
+// ParseWindow validates a window name.
+func ParseWindow(s string) (Window, error) {
+	for _, w := range Windows {
+		if string(w) == s {
+			return w, nil
+		}
+	}
+	return "", fmt.Errorf("unknown rainfall window %q", s)
+}
+
+// Duration returns the length of a fixed window. It is zero for
+// WindowSinceDayStart.
+func (w Window) Duration() time.Duration {
+	switch w {
+	case Window1H:
+		return time.Hour
+	case Window3H:
+		return 3 * time.Hour
+	case Window24H:
+		return 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// dayStartHourUTC is 08:30 IST expressed in UTC.
+const dayStartHourUTC = 3
+
+// DayStart returns the start of the IMD rainfall day containing t.
+func DayStart(t time.Time) time.Time {
+	u := t.UTC()
+	s := time.Date(u.Year(), u.Month(), u.Day(), dayStartHourUTC, 0, 0, 0, time.UTC)
+	if u.Before(s) {
+		s = s.AddDate(0, 0, -1)
+	}
+	return s
+}
+
+// Selection is the period a reading accumulates rain over, as named by the
+// observation's RainfallSel.
+type Selection struct {
+	// SinceDayStart is set for readings counting the rain since 08:30 IST.
+	SinceDayStart bool
+	// Period is the trailing period other readings count the rain over.
+	Period time.Duration
+}
+
+// selectionHours matches a trailing period such as "1 HR", "3HRS" or
+// "LAST 24 HOURS".
+var selectionHours = regexp.MustCompile(`(\d+)\s*(HRS|HR|HOURS|HOUR|H)\b`)
+
+// ParseSelection reads the accumulation period of a RainfallSel value.
+func ParseSelection(sel string) (Selection, error) {
+	s := strings.ToUpper(strings.TrimSpace(sel))
+	if strings.Contains(s, "SINCE") {
+		for _, t := range []string{"0830", "08:30", "0300", "03:00"} {
+			if strings.Contains(s, t) {
+				return Selection{SinceDayStart: true}, nil
+			}
+		}
+		return Selection{}, fmt.Errorf("unknown rainfall window %q", sel)
+	}
+	if s == "HOURLY" {
+		return Selection{Period: time.Hour}, nil
+	}
+	if m := selectionHours.FindStringSubmatch(s); m != nil {
+		if h, err := strconv.Atoi(m[1]); err == nil && h > 0 && h <= 24 {
+			return Selection{Period: time.Duration(h) * time.Hour}, nil
+		}
+	}
+	return Selection{}, fmt.Errorf("unknown rainfall window %q", sel)
+}
+
+// Reading is a gauge reading and the period it accumulates over.
+type Reading struct {
+	At  time.Time
+	MM  float64
+	Sel Selection
+}
+
+// Increment is the rain that fell in (From, At].
+type Increment struct {
+	From time.Time
+	At   time.Time
+	MM   float64
+}
+
+// Increments converts readings sorted by time into the rain that fell in the
+// interval each one covers. A trailing-period reading covers its period. A
+// day-start reading covers the time since the day-start reading before it on
+// the same IMD day, or since the day start when it is the first.
+func Increments(rs []Reading) []Increment {
+	var out []Increment
+	var prev *Reading
+	for i := range rs {
+		cur := rs[i]
+		if !cur.Sel.SinceDayStart {
+			out = append(out, Increment{From: cur.At.Add(-cur.Sel.Period), At: cur.At, MM: cur.MM})
+			continue
+		}
+		inc := Increment{From: DayStart(cur.At), At: cur.At, MM: cur.MM}
+		if prev != nil && DayStart(prev.At).Equal(DayStart(cur.At)) {
+			inc.From = prev.At
+			// A counter that dropped was reset mid-day.
+			if cur.MM >= prev.MM {
+				inc.MM = cur.MM - prev.MM
+			}
+		}
+		out = append(out, inc)
+		prev = &rs[i]
+	}
+	return out
+}
+
+// Accumulation is the rain over a window and the number of readings in it.
+type Accumulation struct {
+	MM       float64
+	Readings int
+}
+
+// Accumulate computes every window ending at end from readings sorted by
+// time. Readings after end are ignored.
+func Accumulate(rs []Reading, end time.Time) map[Window]Accumulation {
+	incs := Increments(rs)
+	out := make(map[Window]Accumulation, len(Windows))
+	for _, w := range Windows {
+		start := DayStart(end)
+		if d := w.Duration(); d > 0 {
+			start = end.Add(-d)
+		}
+		out[w] = sum(incs, start, end)
+	}
+	return out
+}
+
+// sum adds up the increments lying wholly within (start, end], latest first,
+// skipping any that overlap one already counted.
+func sum(incs []Increment, start, end time.Time) Accumulation {
+	var a Accumulation
+	edge := end
+	for i := len(incs) - 1; i >= 0; i-- {
+		inc := incs[i]
+		if inc.At.After(edge) || inc.From.Before(start) {
+			continue
+		}
+		a.MM += inc.MM
+		a.Readings++
+		edge = inc.From
+	}
+	return a
+}
@@ -0,0 +1,138 @@
+package rainfall
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lolwierd/weatherboy/be/internal/model"
+)
+
+var sinceDayStart = Selection{SinceDayStart: true}
+
+func TestAccumulate(t *testing.T) {
+	at := func(h, m int) time.Time { return time.Date(2024, 6, 20, h, m, 0, 0, time.UTC) }
+	rs := []Reading{
+		{at(0, 0), 40, sinceDayStart},
+		{at(2, 0), 45, sinceDayStart},
+		{at(3, 15), 2, sinceDayStart}, // IMD day rolled over at 03:00 UTC
+		{at(4, 0), 6, sinceDayStart},
+		{at(5, 0), 1, sinceDayStart}, // gauge reset mid-day
+		{at(6, 0), 4, sinceDayStart},
+	}
+	got := Accumulate(rs, at(6, 0))
+
+	cases := map[Window]float64{
+		Window1H:            3,
+		Window3H:            2 + 4 + 1 + 3,
+		Window24H:           5 + 2 + 4 + 1 + 3,
+		WindowSinceDayStart: 2 + 4 + 1 + 3,
+	}
+	for w, want := range cases {
+		if got[w].MM != want {
+			t.Errorf("%s: want %.1f got %.1f", w, want, got[w].MM)
+		}
+	}
+	if got[Window1H].Readings != 1 {
+		t.Errorf("1h: want 1 reading got %d", got[Window1H].Readings)
+	}
+}
+
+func TestAccumulateHourly(t *testing.T) {
+	at := func(h int) time.Time { return time.Date(2024, 6, 20, h, 0, 0, 0, time.UTC) }
+	hourly := Selection{Period: time.Hour}
+	var rs []Reading
+	for h := 0; h <= 6; h++ {
+		rs = append(rs, Reading{at(h), float64(h), hourly})
+	}
+	got := Accumulate(rs, at(6))
+
+	cases := map[Window]float64{
+		Window1H:            6,
+		Window3H:            4 + 5 + 6,
+		Window24H:           1 + 2 + 3 + 4 + 5 + 6,
+		WindowSinceDayStart: 4 + 5 + 6,
+	}
+	for w, want := range cases {
+		if got[w].MM != want {
+			t.Errorf("%s: want %.1f got %.1f", w, want, got[w].MM)
+		}
+	}
+}
+
+func TestAccumulateThreeHourly(t *testing.T) {
+	at := func(h int) time.Time { return time.Date(2024, 6, 20, h, 0, 0, 0, time.UTC) }
+	threeHourly := Selection{Period: 3 * time.Hour}
+	// Hourly readings of the rain over the last three hours overlap, so
+	// only every third one is counted.
+	var rs []Reading
+	for h := 3; h <= 9; h++ {
+		rs = append(rs, Reading{at(h), float64(10 * h), threeHourly})
+	}
+	got := Accumulate(rs, at(9))
+
+	cases := map[Window]Accumulation{
+		Window1H:            {0, 0},
+		Window3H:            {90, 1},
+		Window24H:           {90 + 60 + 30, 3},
+		WindowSinceDayStart: {90 + 60, 2},
+	}
+	for w, want := range cases {
+		if got[w] != want {
+			t.Errorf("%s: want %+v got %+v", w, want, got[w])
+		}
+	}
+}
+
+func TestParseSelection(t *testing.T) {
+	cases := map[string]Selection{
+		"Since 0830 IST":  sinceDayStart,
+		"since 03:00 UTC": sinceDayStart,
+		"1 HR":            {Period: time.Hour},
+		"Hourly":          {Period: time.Hour},
+		"3HRS":            {Period: 3 * time.Hour},
+		"Last 24 Hours":   {Period: 24 * time.Hour},
+	}
+	for sel, want := range cases {
+		got, err := ParseSelection(sel)
+		if err != nil || got != want {
+			t.Errorf("%q: got %+v, %v, want %+v", sel, got, err, want)
+		}
+	}
+	for _, sel := range []string{"", "0", "since yesterday", "48 HRS", "daily"} {
+		if _, err := ParseSelection(sel); err == nil {
+			t.Errorf("%q: expected an error", sel)
+		}
+	}
+}
+
+func TestReadingsSkipsUnknownWindows(t *testing.T) {
+	date := time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC)
+	obs := func(h int, sel string, mm *float64) model.AWSARG {
+		return model.AWSARG{Date: date, Time: time.Date(0, 1, 1, h, 0, 0, 0, time.UTC), RainfallSel: sel, Rainfall: mm}
+	}
+	mm := 2.5
+	rs, skipped := readings([]model.AWSARG{
+		obs(5, "Since 0830 IST", &mm),
+		obs(4, "1 HR", &mm),
+		obs(6, "", &mm),
+		obs(7, "1 HR", nil),
+	})
+	if skipped != 1 || len(rs) != 2 {
+		t.Fatalf("readings %+v, skipped %d", rs, skipped)
+	}
+	if !rs[0].At.Equal(date.Add(4*time.Hour)) || rs[0].Sel.Period != time.Hour || !rs[1].Sel.SinceDayStart {
+		t.Errorf("readings %+v", rs)
+	}
+}
+
+func TestDayStart(t *testing.T) {
+	ist, _ := time.LoadLocation("Asia/Kolkata")
+	before := time.Date(2024, 6, 20, 8, 29, 0, 0, ist)
+	after := time.Date(2024, 6, 20, 8, 30, 0, 0, ist)
+	if !DayStart(after).Equal(after) {
+		t.Fatalf("expected 08:30 IST to start the day, got %s", DayStart(after))
+	}
+	if !DayStart(before).Equal(after.AddDate(0, 0, -1)) {
+		t.Fatalf("expected 08:29 IST to belong to the previous day, got %s", DayStart(before))
+	}
+}
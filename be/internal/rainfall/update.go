@@ -0,0 +1,81 @@
+package rainfall
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/lolwierd/weatherboy/be/internal/logger"
+	"github.com/lolwierd/weatherboy/be/internal/model"
+	"github.com/lolwierd/weatherboy/be/internal/repository"
+)
+
+// lookback is how much history is loaded to compute the longest window. It
+// is longer than 24h so the first increment in the window has a predecessor.
+const lookback = 27 * time.Hour
+
+// Update computes and stores every accumulation window for each station that
+// reported in the last day, ending at its latest observation.
+func Update(ctx context.Context) error {
+	stations, err := repository.ActiveAWSStations(ctx, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, s := range stations {
+		if err := updateStation(ctx, s.StationID); err != nil {
+			errs = append(errs, fmt.Errorf("station %s: %w", s.StationID, err))
+		}
+	}
+	logger.Info.Printf("rainfall accumulations stations=%d failed=%d", len(stations), len(errs))
+	return errors.Join(errs...)
+}
+
+func updateStation(ctx context.Context, stationID string) error {
+	obs, err := repository.RecentAWSARG(ctx, stationID, time.Now().UTC().Add(-lookback))
+	if err != nil {
+		return err
+	}
+	rs, skipped := readings(obs)
+	if skipped > 0 {
+		logger.Warn.Printf("station %s: skipped %d rainfall readings with an unknown window", stationID, skipped)
+	}
+	if len(rs) == 0 {
+		return nil
+	}
+	end := rs[len(rs)-1].At
+	for w, a := range Accumulate(rs, end) {
+		r := model.AWSRainfall{
+			StationID: stationID,
+			Window:    string(w),
+			EndsAt:    end,
+			MM:        a.MM,
+			Readings:  a.Readings,
+		}
+		if err := repository.UpsertAWSRainfall(ctx, &r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readings converts observations to readings sorted by time, skipping those
+// without a rainfall value. Those whose window is unknown are skipped and
+// counted.
+func readings(obs []model.AWSARG) (rs []Reading, skipped int) {
+	for _, o := range obs {
+		if o.Rainfall == nil {
+			continue
+		}
+		sel, err := ParseSelection(o.RainfallSel)
+		if err != nil {
+			skipped++
+			continue
+		}
+		rs = append(rs, Reading{At: o.ObservedAt(), MM: *o.Rainfall, Sel: sel})
+	}
+	slices.SortFunc(rs, func(a, b Reading) int { return a.At.Compare(b.At) })
+	return rs, skipped
+}
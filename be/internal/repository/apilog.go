@@ -3,26 +3,20 @@ package repository
 import (
 	"context"
 
+	"github.com/lolwierd/weatherboy/be/internal/jobstats"
 	"github.com/lolwierd/weatherboy/be/internal/model"
 )
 
-// InsertIMDAPICall stores an IMD API usage record.
+// InsertIMDAPICall stores an IMD API usage record and counts its bytes
+// towards the current job run.
 func InsertIMDAPICall(ctx context.Context, l *model.IMDAPICall) error {
-	conn, err := getConn(ctx)
-	if err != nil {
-		return err
-	}
-	defer conn.Release()
+	jobstats.AddBytes(ctx, l.Bytes)
 
-	row := conn.QueryRow(ctx,
+	row := dbConn(ctx).QueryRow(ctx,
 		`INSERT INTO imd_api_log (endpoint, bytes, requested_at)
          VALUES ($1,$2,$3)
          RETURNING id`,
 		l.Endpoint, l.Bytes, l.RequestedAt,
 	)
-	if err := row.Scan(&l.ID); err != nil {
-		
-		return err
-	}
-	return nil
+	return row.Scan(&l.ID)
 }
@@ -3,67 +3,209 @@ package repository
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
 
 	"github.com/lolwierd/weatherboy/be/internal/model"
 )
 
-const insertAWSARG = `
+const awsArgColumns = `id, station_id, call_sign, district, state, station_name, date, time, current_temp, dew_point_temp, rh,
+       wind_direction, wind_speed, mslp, min_temp, max_temp, latitude, longitude, weather_code, nebulosity,
+       feel_like, rainfall_sel, rainfall, qc, fetched_at`
+
+// scanAWSARG scans a row selected with awsArgColumns.
+func scanAWSARG(row pgx.Row, a *model.AWSARG) error {
+	return row.Scan(
+		&a.ID, &a.StationID, &a.CallSign, &a.District, &a.State, &a.StationName, &a.Date, &a.Time, &a.CurrentTemp, &a.DewPointTemp, &a.RH,
+		&a.WindDirection, &a.WindSpeed, &a.MSLP, &a.MinTemp, &a.MaxTemp, &a.Latitude, &a.Longitude, &a.WeatherCode, &a.Nebulosity,
+		&a.FeelLike, &a.RainfallSel, &a.Rainfall, &a.QC, &a.FetchedAt,
+	)
+}
+
+const upsertAWSARG = `
 INSERT INTO aws_arg (
 	station_id, call_sign, district, state, station_name, date, time, current_temp, dew_point_temp, rh,
 	wind_direction, wind_speed, mslp, min_temp, max_temp, latitude, longitude, weather_code, nebulosity,
-	feel_like, rainfall_sel, rainfall
+	feel_like, rainfall_sel, rainfall, qc
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)
-RETURNING id, fetched_at
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)
+ON CONFLICT (station_id, date, time) DO UPDATE SET
+	call_sign = EXCLUDED.call_sign,
+	district = EXCLUDED.district,
+	state = EXCLUDED.state,
+	station_name = EXCLUDED.station_name,
+	current_temp = EXCLUDED.current_temp,
+	dew_point_temp = EXCLUDED.dew_point_temp,
+	rh = EXCLUDED.rh,
+	wind_direction = EXCLUDED.wind_direction,
+	wind_speed = EXCLUDED.wind_speed,
+	mslp = EXCLUDED.mslp,
+	min_temp = EXCLUDED.min_temp,
+	max_temp = EXCLUDED.max_temp,
+	latitude = EXCLUDED.latitude,
+	longitude = EXCLUDED.longitude,
+	weather_code = EXCLUDED.weather_code,
+	nebulosity = EXCLUDED.nebulosity,
+	feel_like = EXCLUDED.feel_like,
+	rainfall_sel = EXCLUDED.rainfall_sel,
+	rainfall = EXCLUDED.rainfall,
+	qc = EXCLUDED.qc,
+	fetched_at = NOW()
+RETURNING id, fetched_at, (xmax = 0) AS inserted
 `
 
-// InsertAWSARG inserts a new AWS/ARG record into the database.
-func InsertAWSARG(ctx context.Context, a *model.AWSARG) error {
-	conn, err := getConn(ctx)
-	if err != nil {
-		return err
+// UpsertAWSARG inserts an AWS/ARG record, replacing any earlier record of the
+// same station and observation time. It reports whether the record is a new
+// observation rather than a replacement.
+func UpsertAWSARG(ctx context.Context, a *model.AWSARG) (inserted bool, err error) {
+	qc := a.QC
+	if qc == nil {
+		qc = map[string][]string{}
 	}
-	defer conn.Release()
-
-	row := conn.QueryRow(ctx,
-		insertAWSARG,
+	row := dbConn(ctx).QueryRow(ctx,
+		upsertAWSARG,
 		a.StationID, a.CallSign, a.District, a.State, a.StationName, a.Date, a.Time, a.CurrentTemp, a.DewPointTemp, a.RH,
 		a.WindDirection, a.WindSpeed, a.MSLP, a.MinTemp, a.MaxTemp, a.Latitude, a.Longitude, a.WeatherCode, a.Nebulosity,
-		a.FeelLike, a.RainfallSel, a.Rainfall,
+		a.FeelLike, a.RainfallSel, a.Rainfall, qc,
 	)
-	if err := row.Scan(&a.ID, &a.FetchedAt); err != nil {
-		return err
-	}
-	return nil
+	err = row.Scan(&a.ID, &a.FetchedAt, &inserted)
+	return inserted, err
 }
 
 const getLatestAWSARG = `
-SELECT id, station_id, call_sign, district, state, station_name, date, time, current_temp, dew_point_temp, rh,
-       wind_direction, wind_speed, mslp, min_temp, max_temp, latitude, longitude, weather_code, nebulosity,
-       feel_like, rainfall_sel, rainfall, fetched_at
+SELECT ` + awsArgColumns + `
 FROM aws_arg
-WHERE station_id = $1
-ORDER BY fetched_at DESC
+WHERE station_id = ANY($1)
+ORDER BY date DESC, time DESC, fetched_at DESC
 LIMIT 1
 `
 
-// LatestAWSARG retrieves the latest AWS/ARG record for a given station.
-func LatestAWSARG(ctx context.Context, stationID string) (*model.AWSARG, error) {
-	conn, err := getConn(ctx)
+// LatestAWSARG retrieves the latest AWS/ARG record across the given stations.
+func LatestAWSARG(ctx context.Context, stationIDs []string) (*model.AWSARG, error) {
+	a := &model.AWSARG{}
+	if err := scanAWSARG(dbConn(ctx).QueryRow(ctx, getLatestAWSARG, stationIDs), a); err != nil {
+		return nil, fmt.Errorf("get latest aws/arg: %w", err)
+	}
+	return a, nil
+}
+
+const getLatestAWSARGByStation = `
+SELECT DISTINCT ON (station_id) ` + awsArgColumns + `
+FROM aws_arg
+WHERE station_id = ANY($1)
+ORDER BY station_id, date DESC, time DESC, fetched_at DESC
+`
+
+// LatestAWSARGByStation returns the latest AWS/ARG record of each given station.
+func LatestAWSARGByStation(ctx context.Context, stationIDs []string) ([]model.AWSARG, error) {
+	return cached(ctx, "latest_aws_arg:"+strings.Join(stationIDs, ","), func() ([]model.AWSARG, error) {
+		list, err := queryAWSARG(ctx, getLatestAWSARGByStation, stationIDs)
+		if err != nil {
+			return nil, fmt.Errorf("get latest aws/arg by station: %w", err)
+		}
+		return list, nil
+	})
+}
+
+const getLatestAWSARGSince = `
+SELECT DISTINCT ON (station_id) ` + awsArgColumns + `
+FROM aws_arg
+WHERE fetched_at >= $1
+ORDER BY station_id, date DESC, time DESC, fetched_at DESC
+`
+
+// LatestAWSARGSince returns the latest AWS/ARG record of every station
+// fetched at or after since.
+func LatestAWSARGSince(ctx context.Context, since time.Time) ([]model.AWSARG, error) {
+	list, err := queryAWSARG(ctx, getLatestAWSARGSince, since)
+	if err != nil {
+		return nil, fmt.Errorf("get latest aws/arg since: %w", err)
+	}
+	return list, nil
+}
+
+const getRecentAWSARG = `
+SELECT ` + awsArgColumns + `
+FROM aws_arg
+WHERE station_id = $1 AND date + time >= $2
+ORDER BY date DESC, time DESC
+`
+
+// RecentAWSARG returns a station's records observed at or after since, newest
+// first. since is compared with the stored date plus time of day, so it should
+// be derived from another record's observation time (see model.AWSARG.ObservedAt).
+func RecentAWSARG(ctx context.Context, stationID string, since time.Time) ([]model.AWSARG, error) {
+	list, err := queryAWSARG(ctx, getRecentAWSARG, stationID, since)
+	if err != nil {
+		return nil, fmt.Errorf("get recent aws/arg: %w", err)
+	}
+	return list, nil
+}
+
+func queryAWSARG(ctx context.Context, sql string, args ...any) ([]model.AWSARG, error) {
+	rows, err := dbConn(ctx).Query(ctx, sql, args...)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Release()
+	defer rows.Close()
+	var list []model.AWSARG
+	for rows.Next() {
+		var a model.AWSARG
+		if err := scanAWSARG(rows, &a); err != nil {
+			return nil, err
+		}
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
 
-	a := &model.AWSARG{StationID: stationID}
-	row := conn.QueryRow(ctx, getLatestAWSARG, stationID)
-	err = row.Scan(
-		&a.ID, &a.StationID, &a.CallSign, &a.District, &a.State, &a.StationName, &a.Date, &a.Time, &a.CurrentTemp, &a.DewPointTemp, &a.RH,
-		&a.WindDirection, &a.WindSpeed, &a.MSLP, &a.MinTemp, &a.MaxTemp, &a.Latitude, &a.Longitude, &a.WeatherCode, &a.Nebulosity,
-		&a.FeelLike, &a.RainfallSel, &a.Rainfall, &a.FetchedAt,
-	)
+// AWSARGSeriesFields maps the field names accepted by AWSARGSeries to their
+// aws_arg columns.
+var AWSARGSeriesFields = map[string]string{
+	"temp":       "current_temp",
+	"dew_point":  "dew_point_temp",
+	"rh":         "rh",
+	"wind_speed": "wind_speed",
+	"mslp":       "mslp",
+	"feel_like":  "feel_like",
+	"rainfall":   "rainfall",
+}
+
+// AWSARGSeries buckets a field of the given stations' observations in
+// [from, to) into step-wide buckets aligned to from, and returns the min, max,
+// mean and last value of each non-empty bucket. Observations are dated by the
+// UTC wall clock of their date and time columns, so from and to are compared
+// in UTC.
+func AWSARGSeries(ctx context.Context, stationIDs []string, field string, from, to time.Time, step time.Duration) ([]model.SeriesPoint, error) {
+	col, ok := AWSARGSeriesFields[field]
+	if !ok {
+		return nil, fmt.Errorf("unknown aws/arg series field %q", field)
+	}
+	sql := fmt.Sprintf(`
+SELECT date_bin($1, date + time, $2) AS bucket,
+       MIN(%[1]s), MAX(%[1]s), AVG(%[1]s),
+       (ARRAY_AGG(%[1]s ORDER BY date + time DESC) FILTER (WHERE %[1]s IS NOT NULL))[1],
+       COUNT(%[1]s)
+FROM aws_arg
+WHERE station_id = ANY($3) AND date + time >= $2 AND date + time < $4
+GROUP BY bucket
+ORDER BY bucket
+`, col)
+
+	rows, err := dbConn(ctx).Query(ctx, sql, step, from.UTC(), stationIDs, to.UTC())
 	if err != nil {
-		return nil, fmt.Errorf("get latest aws/arg: %w", err)
+		return nil, fmt.Errorf("get aws/arg series: %w", err)
 	}
-	return a, nil
+	defer rows.Close()
+	var list []model.SeriesPoint
+	for rows.Next() {
+		var p model.SeriesPoint
+		if err := rows.Scan(&p.Bucket, &p.Min, &p.Max, &p.Mean, &p.Last, &p.Count); err != nil {
+			return nil, fmt.Errorf("get aws/arg series: %w", err)
+		}
+		list = append(list, p)
+	}
+	return list, rows.Err()
 }
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lolwierd/weatherboy/be/internal/model"
+)
+
+const upsertAWSStation = `
+INSERT INTO aws_station (station_id, call_sign, station_name, district, state, latitude, longitude, last_seen_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (station_id) DO UPDATE SET
+	call_sign = EXCLUDED.call_sign,
+	station_name = EXCLUDED.station_name,
+	district = EXCLUDED.district,
+	state = EXCLUDED.state,
+	latitude = EXCLUDED.latitude,
+	longitude = EXCLUDED.longitude,
+	last_seen_at = GREATEST(aws_station.last_seen_at, EXCLUDED.last_seen_at)
+RETURNING first_seen_at, last_seen_at
+`
+
+// UpsertAWSStation adds a station to the catalogue or refreshes its metadata.
+func UpsertAWSStation(ctx context.Context, s *model.AWSStation) error {
+	return dbConn(ctx).QueryRow(ctx, upsertAWSStation,
+		s.StationID, s.CallSign, s.StationName, s.District, s.State, s.Latitude, s.Longitude, s.LastSeenAt,
+	).Scan(&s.FirstSeenAt, &s.LastSeenAt)
+}
+
+const getActiveAWSStations = `
+SELECT station_id, call_sign, station_name, district, state, latitude, longitude, first_seen_at, last_seen_at
+FROM aws_station
+WHERE last_seen_at >= $1
+`
+
+// ActiveAWSStations returns the stations that reported at or after since.
+func ActiveAWSStations(ctx context.Context, since time.Time) ([]model.AWSStation, error) {
+	return cached(ctx, fmt.Sprintf("active_aws_stations:%d", since.Truncate(time.Minute).Unix()), func() ([]model.AWSStation, error) {
+		rows, err := dbConn(ctx).Query(ctx, getActiveAWSStations, since)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		var list []model.AWSStation
+		for rows.Next() {
+			var s model.AWSStation
+			if err := rows.Scan(&s.StationID, &s.CallSign, &s.StationName, &s.District, &s.State, &s.Latitude, &s.Longitude, &s.FirstSeenAt, &s.LastSeenAt); err != nil {
+				return nil, err
+			}
+			list = append(list, s)
+		}
+		return list, rows.Err()
+	})
+}
+
+// UnknownAWSStations returns the ids among ids that are not in the station
+// catalogue.
+func UnknownAWSStations(ctx context.Context, ids []string) ([]string, error) {
+	rows, err := dbConn(ctx).Query(ctx,
+		`SELECT id FROM unnest($1::text[]) AS id WHERE NOT EXISTS (SELECT 1 FROM aws_station WHERE station_id = id)`,
+		ids,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var unknown []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		unknown = append(unknown, id)
+	}
+	return unknown, rows.Err()
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lolwierd/weatherboy/be/internal/model"
+)
+
+// Content-addressed blobs never change, so storing one twice keeps the
+// first record.
+const insertBlob = `
+INSERT INTO blob (key, sha256, size, content_type)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (key) DO UPDATE SET key = EXCLUDED.key
+RETURNING created_at
+`
+
+// InsertBlob records a blob kept in the blob store.
+func InsertBlob(ctx context.Context, b *model.Blob) error {
+	return dbConn(ctx).QueryRow(ctx, insertBlob, b.Key, b.SHA256, b.Size, b.ContentType).Scan(&b.CreatedAt)
+}
+
+// orphanBlobs selects the blobs recorded before $1 that no bulletin
+// references.
+const orphanBlobs = `
+SELECT b.key FROM blob b
+WHERE b.created_at < $1
+  AND NOT EXISTS (SELECT 1 FROM bulletin_raw r WHERE r.blob_key = b.key)
+`
+
+// CountOrphanBlobs returns how many blobs recorded before before no bulletin
+// references.
+func CountOrphanBlobs(ctx context.Context, before time.Time) (int64, error) {
+	var n int64
+	err := dbConn(ctx).QueryRow(ctx, `SELECT count(*) FROM (`+orphanBlobs+`) o`, before).Scan(&n)
+	return n, err
+}
+
+// DeleteOrphanBlobs deletes the records of up to limit blobs recorded before
+// before that no bulletin references and returns their keys. Run it in the
+// transaction that removes the blobs from the store, so a failed removal
+// keeps the records.
+func DeleteOrphanBlobs(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	rows, err := dbConn(ctx).Query(ctx,
+		`DELETE FROM blob WHERE key IN (`+orphanBlobs+` ORDER BY b.key LIMIT $2 FOR UPDATE OF b SKIP LOCKED) RETURNING key`,
+		before, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
@@ -2,49 +2,88 @@ package repository
 
 import (
 	"context"
-
+	"time"
 
 	"github.com/lolwierd/weatherboy/be/internal/model"
 )
 
 const insertBulletinRaw = `
-INSERT INTO bulletin_raw (path, fetched_at)
-VALUES ($1, $2)
+INSERT INTO bulletin_raw (path, blob_key, fetched_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (path) DO UPDATE SET
+	blob_key = COALESCE(EXCLUDED.blob_key, bulletin_raw.blob_key),
+	fetched_at = EXCLUDED.fetched_at
 RETURNING id
 `
 
-// InsertBulletinRaw inserts a new bulletin raw record into the database.
+// InsertBulletinRaw records the bulletin PDF identified by path and the blob
+// it is kept in. Re-downloading the bulletin of the same day keeps the
+// existing record, points it at the blob of the new download and bumps its
+// fetch time.
 func InsertBulletinRaw(ctx context.Context, br *model.BulletinRaw) error {
-	conn, err := getConn(ctx)
-	if err != nil {
-		return err
-	}
-	defer conn.Release()
+	return dbConn(ctx).QueryRow(ctx, insertBulletinRaw, br.Path, br.BlobKey, br.FetchedAt).Scan(&br.ID)
+}
 
-	row := conn.QueryRow(ctx, insertBulletinRaw, br.Path, br.FetchedAt)
-	if err := row.Scan(&br.ID); err != nil {
-		return err
-	}
-	return nil
+// A re-issued bulletin keeps its raw record, so a forecast is new to readers
+// when its record is new or its text differs from the one stored.
+const upsertParsedBulletin = `
+WITH prev AS (
+	SELECT forecast FROM bulletin_parsed WHERE bulletin_raw_id = $1 AND location = $2
+), up AS (
+	INSERT INTO bulletin_parsed (bulletin_raw_id, location, forecast, parser_version)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (bulletin_raw_id, location) DO UPDATE SET
+		forecast = EXCLUDED.forecast,
+		parser_version = EXCLUDED.parser_version,
+		fetched_at = NOW()
+	RETURNING id, fetched_at
+)
+SELECT id, fetched_at, NOT EXISTS (SELECT 1 FROM prev WHERE prev.forecast = $3) AS changed FROM up
+`
+
+// UpsertParsedBulletin inserts a parsed bulletin record, replacing the record
+// of the same raw bulletin and location. It reports whether the record is new
+// or its forecast changed.
+func UpsertParsedBulletin(ctx context.Context, b *model.BulletinParsed) (changed bool, err error) {
+	err = dbConn(ctx).QueryRow(ctx, upsertParsedBulletin, b.BulletinRawID, b.Location, b.Forecast, b.ParserVersion).Scan(&b.ID, &b.FetchedAt, &changed)
+	return changed, err
 }
 
-const insertParsedBulletin = `
-INSERT INTO bulletin_parsed (bulletin_raw_id, location, forecast)
-VALUES ($1, $2, $3)
-RETURNING id, fetched_at
+// BulletinRawExists reports whether the bulletin PDF identified by path is
+// recorded.
+func BulletinRawExists(ctx context.Context, path string) (bool, error) {
+	var ok bool
+	err := dbConn(ctx).QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM bulletin_raw WHERE path = $1)`, path,
+	).Scan(&ok)
+	return ok, err
+}
+
+const streamBulletinRaw = `
+SELECT r.id, r.path, r.blob_key, r.fetched_at
+FROM bulletin_raw r
+LEFT JOIN bulletin_parsed p ON p.bulletin_raw_id = r.id AND p.location = $3
+WHERE r.fetched_at >= $1 AND (p.id IS NULL OR p.parser_version < $2)
+ORDER BY r.fetched_at
 `
 
-// InsertParsedBulletin inserts a new parsed bulletin record into the database.
-func InsertParsedBulletin(ctx context.Context, b *model.BulletinParsed) error {
-	conn, err := getConn(ctx)
+// StreamBulletinRaw calls fn with every bulletin PDF fetched at or after since
+// whose parsed record for loc is missing or older than parserVersion, oldest
+// first. It stops at the first error fn returns.
+func StreamBulletinRaw(ctx context.Context, since time.Time, loc string, parserVersion int, fn func(model.BulletinRaw) error) error {
+	rows, err := dbConn(ctx).Query(ctx, streamBulletinRaw, since, parserVersion, loc)
 	if err != nil {
 		return err
 	}
-	defer conn.Release()
-
-	row := conn.QueryRow(ctx, insertParsedBulletin, b.BulletinRawID, b.Location, b.Forecast)
-	if err := row.Scan(&b.ID, &b.FetchedAt); err != nil {
-		return err
+	defer rows.Close()
+	for rows.Next() {
+		var r model.BulletinRaw
+		if err := rows.Scan(&r.ID, &r.Path, &r.BlobKey, &r.FetchedAt); err != nil {
+			return err
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
 	}
-	return nil
-}
\ No newline at end of file
+	return rows.Err()
+}
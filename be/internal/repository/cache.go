@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"sync"
+)
+
+type cacheKey struct{}
+
+// lookupCache memoizes lookups for the lifetime of a context.
+type lookupCache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	once sync.Once
+	v    any
+	err  error
+}
+
+// WithCache returns a context under which the lookups shared between
+// locations, such as the station catalogue and basin forecasts, run once and
+// are reused, even across goroutines. It suits one request computing several
+// locations; the cache never expires, so it must not outlive the request.
+func WithCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheKey{}, &lookupCache{entries: map[string]*cacheEntry{}})
+}
+
+// cached returns the result of fn, computed once per key under a context
+// from WithCache, and on every call otherwise. Concurrent callers of the same
+// key wait for the first. Callers must not modify the value.
+func cached[T any](ctx context.Context, key string, fn func() (T, error)) (T, error) {
+	c, ok := ctx.Value(cacheKey{}).(*lookupCache)
+	if !ok {
+		return fn()
+	}
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if !ok {
+		e = &cacheEntry{}
+		c.entries[key] = e
+	}
+	c.mu.Unlock()
+
+	e.once.Do(func() { e.v, e.err = fn() })
+	v, _ := e.v.(T)
+	return v, e.err
+}
@@ -2,60 +2,62 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/lolwierd/weatherboy/be/internal/model"
 )
 
-// InsertDistrictWarning inserts a district warning record.
-func InsertDistrictWarning(ctx context.Context, dw *model.DistrictWarning) error {
-	conn, err := getConn(ctx)
-	if err != nil {
-		return err
-	}
-	defer conn.Release()
-
-	row := conn.QueryRow(ctx,
-		`INSERT INTO district_warning (location, issued_at, day1_warning, day2_warning, day3_warning, day4_warning, day5_warning, day1_color, day2_color, day3_color, day4_color, day5_color)
-         VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
-         RETURNING id, created_at`,
-		dw.Location, dw.IssuedAt, dw.Day1Warning, dw.Day2Warning, dw.Day3Warning, dw.Day4Warning, dw.Day5Warning, dw.Day1Color, dw.Day2Color, dw.Day3Color, dw.Day4Color, dw.Day5Color,
+// UpsertDistrictWarning inserts a district warning record, replacing the
+// record of the same location and issue time. The record stays linked to the
+// first raw payload it was parsed from, while the raw payload it is parsed
+// from now is marked as parsed into it. It reports whether the record is new
+// rather than a replacement.
+func UpsertDistrictWarning(ctx context.Context, dw *model.DistrictWarning) (inserted bool, err error) {
+	row := dbConn(ctx).QueryRow(ctx,
+		`WITH up AS (
+             INSERT INTO district_warning (location, issued_at, day1_warning, day2_warning, day3_warning, day4_warning, day5_warning, day1_color, day2_color, day3_color, day4_color, day5_color, district_warning_raw_id, parser_version)
+             VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)
+             ON CONFLICT (location, issued_at) DO UPDATE SET
+                 day1_warning = EXCLUDED.day1_warning,
+                 day2_warning = EXCLUDED.day2_warning,
+                 day3_warning = EXCLUDED.day3_warning,
+                 day4_warning = EXCLUDED.day4_warning,
+                 day5_warning = EXCLUDED.day5_warning,
+                 day1_color = EXCLUDED.day1_color,
+                 day2_color = EXCLUDED.day2_color,
+                 day3_color = EXCLUDED.day3_color,
+                 day4_color = EXCLUDED.day4_color,
+                 day5_color = EXCLUDED.day5_color,
+                 district_warning_raw_id = COALESCE(district_warning.district_warning_raw_id, EXCLUDED.district_warning_raw_id),
+                 parser_version = EXCLUDED.parser_version
+             RETURNING id, created_at, (xmax = 0) AS inserted
+         ), raw AS (
+             UPDATE district_warning_raw SET district_warning_id = up.id, parser_version = $14
+             FROM up
+             WHERE district_warning_raw.id = $13
+         )
+         SELECT id, created_at, inserted FROM up`,
+		dw.Location, dw.IssuedAt, dw.Day1Warning, dw.Day2Warning, dw.Day3Warning, dw.Day4Warning, dw.Day5Warning, dw.Day1Color, dw.Day2Color, dw.Day3Color, dw.Day4Color, dw.Day5Color, dw.DistrictWarningRawID, dw.ParserVersion,
 	)
-	if err := row.Scan(&dw.ID, &dw.CreatedAt); err != nil {
-		return err
-	}
-	return nil
+	err = row.Scan(&dw.ID, &dw.CreatedAt, &inserted)
+	return inserted, err
 }
 
 // InsertDistrictWarningRaw stores the raw district warning JSON.
 func InsertDistrictWarningRaw(ctx context.Context, dwr *model.DistrictWarningRaw) error {
-	conn, err := getConn(ctx)
-	if err != nil {
-		return err
-	}
-	defer conn.Release()
-
-	row := conn.QueryRow(ctx,
+	row := dbConn(ctx).QueryRow(ctx,
 		`INSERT INTO district_warning_raw (location, data, fetched_at)
          VALUES ($1,$2,$3)
          RETURNING id`,
 		dwr.Location, dwr.Data, dwr.FetchedAt,
 	)
-	if err := row.Scan(&dwr.ID); err != nil {
-		return err
-	}
-	return nil
+	return row.Scan(&dwr.ID)
 }
 
 // LatestDistrictWarning returns the latest district warning record for a location.
 func LatestDistrictWarning(ctx context.Context, loc string) (*model.DistrictWarning, error) {
-	conn, err := getConn(ctx)
-	if err != nil {
-		return nil, err
-	}
-	defer conn.Release()
-
 	dw := &model.DistrictWarning{}
-	row := conn.QueryRow(ctx,
+	row := dbConn(ctx).QueryRow(ctx,
 		`SELECT id, location, issued_at, day1_warning, day2_warning, day3_warning, day4_warning, day5_warning, day1_color, day2_color, day3_color, day4_color, day5_color, created_at
          FROM district_warning
          WHERE location = $1
@@ -68,3 +70,94 @@ func LatestDistrictWarning(ctx context.Context, loc string) (*model.DistrictWarn
 	}
 	return dw, nil
 }
+
+const getLatestDistrictWarnings = `
+SELECT DISTINCT ON (location) id, location, issued_at, day1_warning, day2_warning, day3_warning, day4_warning, day5_warning, day1_color, day2_color, day3_color, day4_color, day5_color, created_at
+FROM district_warning
+WHERE location = ANY($1)
+ORDER BY location, issued_at DESC, created_at DESC
+`
+
+// LatestDistrictWarnings returns the latest district warning record of each
+// given location that has one.
+func LatestDistrictWarnings(ctx context.Context, locs []string) ([]model.DistrictWarning, error) {
+	rows, err := dbConn(ctx).Query(ctx, getLatestDistrictWarnings, locs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []model.DistrictWarning
+	for rows.Next() {
+		var dw model.DistrictWarning
+		if err := rows.Scan(&dw.ID, &dw.Location, &dw.IssuedAt, &dw.Day1Warning, &dw.Day2Warning, &dw.Day3Warning, &dw.Day4Warning, &dw.Day5Warning, &dw.Day1Color, &dw.Day2Color, &dw.Day3Color, &dw.Day4Color, &dw.Day5Color, &dw.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, dw)
+	}
+	return list, rows.Err()
+}
+
+const getDistrictWarningRawRange = `
+SELECT id, location, data, fetched_at
+FROM district_warning_raw
+WHERE fetched_at >= $1 AND fetched_at < $2
+ORDER BY fetched_at
+`
+
+// DistrictWarningRawRange returns the raw district warning payloads fetched
+// within [from, to), oldest first.
+func DistrictWarningRawRange(ctx context.Context, from, to time.Time) ([]model.DistrictWarningRaw, error) {
+	rows, err := dbConn(ctx).Query(ctx, getDistrictWarningRawRange, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []model.DistrictWarningRaw
+	for rows.Next() {
+		var r model.DistrictWarningRaw
+		if err := rows.Scan(&r.ID, &r.Location, &r.Data, &r.FetchedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+// DistrictWarningExists reports whether a district warning of a location
+// issued at the given time is stored.
+func DistrictWarningExists(ctx context.Context, loc string, issuedAt time.Time) (bool, error) {
+	var ok bool
+	err := dbConn(ctx).QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM district_warning WHERE location = $1 AND issued_at = $2)`,
+		loc, issuedAt,
+	).Scan(&ok)
+	return ok, err
+}
+
+const streamDistrictWarningRaw = `
+SELECT id, location, data, fetched_at
+FROM district_warning_raw
+WHERE fetched_at >= $1 AND (parser_version IS NULL OR parser_version < $2)
+ORDER BY fetched_at
+`
+
+// StreamDistrictWarningRaw calls fn with every raw district warning payload
+// fetched at or after since that was never parsed or was parsed by a version
+// older than parserVersion, oldest first. It stops at the first error fn returns.
+func StreamDistrictWarningRaw(ctx context.Context, since time.Time, parserVersion int, fn func(model.DistrictWarningRaw) error) error {
+	rows, err := dbConn(ctx).Query(ctx, streamDistrictWarningRaw, since, parserVersion)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r model.DistrictWarningRaw
+		if err := rows.Scan(&r.ID, &r.Location, &r.Data, &r.FetchedAt); err != nil {
+			return err
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
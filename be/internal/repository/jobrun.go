@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/lolwierd/weatherboy/be/internal/model"
+)
+
+const insertJobRun = `
+INSERT INTO job_run (job_name, started_at, status)
+VALUES ($1, $2, $3)
+RETURNING id
+`
+
+// InsertJobRun records the start of a job run.
+func InsertJobRun(ctx context.Context, r *model.JobRun) error {
+	return dbConn(ctx).QueryRow(ctx, insertJobRun, r.JobName, r.StartedAt, r.Status).Scan(&r.ID)
+}
+
+const finishJobRun = `
+UPDATE job_run
+SET finished_at = $2, status = $3, error = $4, bytes = $5, rows_written = $6
+WHERE id = $1
+`
+
+// FinishJobRun records the outcome of a job run started with InsertJobRun.
+func FinishJobRun(ctx context.Context, r *model.JobRun) error {
+	_, err := dbConn(ctx).Exec(ctx, finishJobRun, r.ID, r.FinishedAt, r.Status, r.Error, r.Bytes, r.RowsWritten)
+	return err
+}
+
+const jobRunColumns = `id, job_name, started_at, finished_at, status, error, bytes, rows_written`
+
+const getLatestJobRuns = `
+SELECT DISTINCT ON (job_name) ` + jobRunColumns + `
+FROM job_run
+WHERE status = $1
+ORDER BY job_name, started_at DESC
+`
+
+// LatestJobRuns returns the latest run with the given status of every job,
+// keyed by job name.
+func LatestJobRuns(ctx context.Context, status string) (map[string]model.JobRun, error) {
+	list, err := queryJobRuns(ctx, getLatestJobRuns, status)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]model.JobRun, len(list))
+	for _, r := range list {
+		m[r.JobName] = r
+	}
+	return m, nil
+}
+
+const getJobRuns = `
+SELECT ` + jobRunColumns + `
+FROM job_run
+WHERE job_name = $1
+ORDER BY started_at DESC
+LIMIT $2
+`
+
+// JobRuns returns the latest runs of a job, newest first.
+func JobRuns(ctx context.Context, name string, limit int) ([]model.JobRun, error) {
+	return queryJobRuns(ctx, getJobRuns, name, limit)
+}
+
+func queryJobRuns(ctx context.Context, sql string, args ...any) ([]model.JobRun, error) {
+	rows, err := dbConn(ctx).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []model.JobRun
+	for rows.Next() {
+		var r model.JobRun
+		if err := rows.Scan(&r.ID, &r.JobName, &r.StartedAt, &r.FinishedAt, &r.Status, &r.Error, &r.Bytes, &r.RowsWritten); err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,286 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/geo"
+	"github.com/lolwierd/weatherboy/be/internal/model"
+)
+
+var (
+	// ErrUnknownLocation is returned when a location name is not configured.
+	ErrUnknownLocation = errors.New("unknown location")
+	// ErrNoSource is returned when a location has no keys configured for a source.
+	ErrNoSource = errors.New("no source configured for location")
+)
+
+// LatestRiverBasinQPFForLocation resolves loc to its configured river basin and
+// sub-basin and returns the latest QPF for them.
+func LatestRiverBasinQPFForLocation(ctx context.Context, loc string) (*model.RiverBasinQPF, error) {
+	l, ok := config.LocationByName(loc)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownLocation, loc)
+	}
+	if l.RiverBasinID == 0 {
+		return nil, fmt.Errorf("%w: river basin for %s", ErrNoSource, loc)
+	}
+	return LatestRiverBasinQPF(ctx, l.RiverBasinID, l.SubBasinID)
+}
+
+// activeStationWindow is how recently a station must have reported to be
+// considered for nearest-station resolution.
+const activeStationWindow = 24 * time.Hour
+
+// AWSStationIDsForLocation resolves loc to its explicitly configured AWS/ARG
+// stations plus the nearest active catalogued stations within its radius.
+func AWSStationIDsForLocation(ctx context.Context, loc string) ([]string, error) {
+	l, ok := config.LocationByName(loc)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownLocation, loc)
+	}
+	return awsStationIDs(ctx, l)
+}
+
+func awsStationIDs(ctx context.Context, l config.Location) ([]string, error) {
+	stations, err := ActiveAWSStations(ctx, time.Now().Add(-activeStationWindow))
+	if err != nil {
+		return nil, err
+	}
+	ids := locationStationIDs(l, stations)
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("%w: aws/arg for %s", ErrNoSource, l.Name)
+	}
+	return ids, nil
+}
+
+// locationStationIDs returns the configured stations of l followed by the
+// nearest of the given active stations within its radius.
+func locationStationIDs(l config.Location, stations []model.AWSStation) []string {
+	ids := append([]string{}, l.AWSStationIDs...)
+	pos := func(i int) (float64, float64) { return stations[i].Latitude, stations[i].Longitude }
+	for _, n := range geo.Nearest(len(stations), pos, l.Lat, l.Lon, l.NearestK(), l.RadiusKM()) {
+		if !slices.Contains(ids, stations[n.Index].StationID) {
+			ids = append(ids, stations[n.Index].StationID)
+		}
+	}
+	return ids
+}
+
+// AWSStationLocations maps each served location that uses any of the given
+// AWS/ARG stations, as configured or nearest active stations, to those of the
+// stations it uses.
+func AWSStationLocations(ctx context.Context, ids []string) (map[string][]string, error) {
+	stations, err := ActiveAWSStations(ctx, time.Now().Add(-activeStationWindow))
+	if err != nil {
+		return nil, err
+	}
+	locs := map[string][]string{}
+	for _, l := range config.Locations() {
+		for _, id := range locationStationIDs(l, stations) {
+			if slices.Contains(ids, id) {
+				locs[l.Name] = append(locs[l.Name], id)
+			}
+		}
+	}
+	return locs, nil
+}
+
+// AWSARGForLocation resolves loc to its explicitly configured AWS/ARG stations
+// plus the nearest active catalogued stations within its radius, and returns
+// the latest observation of each, closest first.
+func AWSARGForLocation(ctx context.Context, loc string) ([]model.AWSStationObservation, error) {
+	l, ok := config.LocationByName(loc)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownLocation, loc)
+	}
+	ids, err := awsStationIDs(ctx, l)
+	if err != nil {
+		return nil, err
+	}
+
+	latest, err := LatestAWSARGByStation(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(latest) == 0 {
+		return nil, fmt.Errorf("no aws/arg observations for %s: %w", loc, pgx.ErrNoRows)
+	}
+	obs := make([]model.AWSStationObservation, 0, len(latest))
+	for _, a := range latest {
+		obs = append(obs, model.AWSStationObservation{
+			Observation: a,
+			DistanceKM:  geo.HaversineKM(l.Lat, l.Lon, a.Latitude, a.Longitude),
+		})
+	}
+	sort.Slice(obs, func(i, j int) bool { return obs[i].DistanceKM < obs[j].DistanceKM })
+	return obs, nil
+}
+
+// AWSRainfallForLocation returns the latest rainfall accumulation over window
+// of each AWS/ARG station resolved for loc, closest first.
+func AWSRainfallForLocation(ctx context.Context, loc string, window string) ([]model.AWSStationRainfall, error) {
+	obs, err := AWSARGForLocation(ctx, loc)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(obs))
+	dist := make(map[string]float64, len(obs))
+	for _, o := range obs {
+		ids = append(ids, o.Observation.StationID)
+		dist[o.Observation.StationID] = o.DistanceKM
+	}
+
+	rs, err := LatestAWSRainfall(ctx, ids, window)
+	if err != nil {
+		return nil, err
+	}
+	if len(rs) == 0 {
+		return nil, fmt.Errorf("no %s rainfall for %s: %w", window, loc, pgx.ErrNoRows)
+	}
+	out := make([]model.AWSStationRainfall, 0, len(rs))
+	for _, r := range rs {
+		out = append(out, model.AWSStationRainfall{Rainfall: r, DistanceKM: dist[r.StationID]})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].DistanceKM < out[j].DistanceKM })
+	return out, nil
+}
+
+const locationColumns = `slug, name, lat, lon, district_id, bulletin_product, radar_codes, river_basin_id, sub_basin_id,
+	aws_station_ids, aws_nearest_k, aws_radius_km, aws_state, aws_district, timezone`
+
+func scanLocation(row pgx.Row) (config.Location, error) {
+	var l config.Location
+	err := row.Scan(&l.Name, &l.DisplayName, &l.Lat, &l.Lon, &l.DistrictID, &l.PdfSlug, &l.RadarCodes, &l.RiverBasinID, &l.SubBasinID,
+		&l.AWSStationIDs, &l.AWSNearestK, &l.AWSRadiusKM, &l.AWSScope.State, &l.AWSScope.District, &l.Timezone)
+	return l, err
+}
+
+func locationArgs(l config.Location) []any {
+	radarCodes, stationIDs := l.RadarCodes, l.AWSStationIDs
+	if radarCodes == nil {
+		radarCodes = []string{}
+	}
+	if stationIDs == nil {
+		stationIDs = []string{}
+	}
+	return []any{l.Name, l.DisplayName, l.Lat, l.Lon, l.DistrictID, l.PdfSlug, radarCodes, l.RiverBasinID, l.SubBasinID,
+		stationIDs, l.AWSNearestK, l.AWSRadiusKM, l.AWSScope.State, l.AWSScope.District, l.Timezone}
+}
+
+// ListLocations returns every stored location ordered by slug.
+func ListLocations(ctx context.Context) ([]config.Location, error) {
+	rows, err := dbConn(ctx).Query(ctx, `SELECT `+locationColumns+` FROM location ORDER BY slug`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := make([]config.Location, 0)
+	for rows.Next() {
+		l, err := scanLocation(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, l)
+	}
+	return list, rows.Err()
+}
+
+// GetLocation returns the stored location with the given slug, or
+// ErrUnknownLocation.
+func GetLocation(ctx context.Context, slug string) (config.Location, error) {
+	l, err := scanLocation(dbConn(ctx).QueryRow(ctx, `SELECT `+locationColumns+` FROM location WHERE slug = $1`, slug))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return config.Location{}, fmt.Errorf("%w: %s", ErrUnknownLocation, slug)
+	}
+	return l, err
+}
+
+// ErrLocationExists is returned when inserting a location whose slug is taken.
+var ErrLocationExists = errors.New("location already exists")
+
+const insertLocation = `
+INSERT INTO location (` + locationColumns + `)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+ON CONFLICT (slug) DO NOTHING
+`
+
+// InsertLocation stores a new location, or returns ErrLocationExists.
+func InsertLocation(ctx context.Context, l config.Location) error {
+	tag, err := dbConn(ctx).Exec(ctx, insertLocation, locationArgs(l)...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", ErrLocationExists, l.Name)
+	}
+	return nil
+}
+
+const updateLocation = `
+UPDATE location SET
+	name = $2, lat = $3, lon = $4, district_id = $5, bulletin_product = $6, radar_codes = $7,
+	river_basin_id = $8, sub_basin_id = $9, aws_station_ids = $10, aws_nearest_k = $11,
+	aws_radius_km = $12, aws_state = $13, aws_district = $14, timezone = $15, updated_at = NOW()
+WHERE slug = $1
+`
+
+// UpdateLocation replaces the stored location with l's slug, or returns
+// ErrUnknownLocation.
+func UpdateLocation(ctx context.Context, l config.Location) error {
+	tag, err := dbConn(ctx).Exec(ctx, updateLocation, locationArgs(l)...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownLocation, l.Name)
+	}
+	return nil
+}
+
+// DeleteLocation removes the stored location with the given slug, or returns
+// ErrUnknownLocation. Data already ingested for it is kept.
+func DeleteLocation(ctx context.Context, slug string) error {
+	tag, err := dbConn(ctx).Exec(ctx, `DELETE FROM location WHERE slug = $1`, slug)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownLocation, slug)
+	}
+	return nil
+}
+
+// LatestDistrictWarningForDistrict returns the latest district warning stored
+// for any location in the IMD district id.
+func LatestDistrictWarningForDistrict(ctx context.Context, id int) (*model.DistrictWarning, error) {
+	var locs []string
+	for _, l := range config.Locations() {
+		if id != 0 && l.DistrictID == id {
+			locs = append(locs, l.Name)
+		}
+	}
+	var latest *model.DistrictWarning
+	if len(locs) > 0 {
+		list, err := LatestDistrictWarnings(ctx, locs)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list {
+			if latest == nil || list[i].IssuedAt.After(latest.IssuedAt) {
+				latest = &list[i]
+			}
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no district warning for district %d: %w", id, pgx.ErrNoRows)
+	}
+	return latest, nil
+}
@@ -0,0 +1,27 @@
+package repository
+
+import "context"
+
+// TryLock takes the session-level Postgres advisory lock key on a connection
+// of its own, so it is held across transactions and excludes every replica.
+// ok is false when another session holds the lock. unlock releases the lock
+// and the connection.
+func TryLock(ctx context.Context, key int64) (unlock func(), ok bool, err error) {
+	conn, err := getConn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&ok); err != nil || !ok {
+		conn.Release()
+		return nil, false, err
+	}
+	return func() {
+		if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, key); err != nil {
+			// The session may still hold the lock, so it must not go back to
+			// the pool.
+			conn.Hijack().Close(context.Background())
+			return
+		}
+		conn.Release()
+	}, true, nil
+}
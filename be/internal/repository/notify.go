@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Notify sends payload to the listeners of a Postgres NOTIFY channel. Inside
+// a unit of work it is delivered on commit.
+func Notify(ctx context.Context, channel, payload string) error {
+	_, err := dbConn(ctx).Exec(ctx, `SELECT pg_notify($1, $2)`, channel, payload)
+	return err
+}
+
+// Listen takes a connection out of the pool and subscribes it to channel.
+// The caller waits for notifications on it and closes it when done.
+func Listen(ctx context.Context, channel string) (*pgx.Conn, error) {
+	pc, err := getConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// The connection stays subscribed, so it must not go back to the pool.
+	conn := pc.Hijack()
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		conn.Close(context.Background())
+		return nil, err
+	}
+	return conn, nil
+}
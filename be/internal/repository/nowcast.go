@@ -2,80 +2,74 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/lolwierd/weatherboy/be/internal/model"
 )
 
-// InsertNowcast inserts a nowcast record.
-func InsertNowcast(ctx context.Context, n *model.Nowcast) error {
-	conn, err := getConn(ctx)
-	if err != nil {
-		return err
-	}
-	defer conn.Release()
-
-	row := conn.QueryRow(ctx,
-		`INSERT INTO nowcast (location, captured_at, lead_min, pop, mm_per_hr)
-         VALUES ($1,$2,$3,$4,$5)
-         RETURNING id, created_at`,
-		n.Location, n.CapturedAt, n.LeadMin, n.POP, n.MMPerHr,
+// UpsertNowcast inserts a nowcast record, replacing the record of the same
+// location and capture time. The record stays linked to the first raw payload
+// it was parsed from, while the raw payload it is parsed from now is marked as
+// parsed into it. It reports whether the record is new rather than a
+// replacement.
+func UpsertNowcast(ctx context.Context, n *model.Nowcast) (inserted bool, err error) {
+	row := dbConn(ctx).QueryRow(ctx,
+		`WITH up AS (
+             INSERT INTO nowcast (location, captured_at, lead_min, pop, mm_per_hr, nowcast_raw_id, parser_version)
+             VALUES ($1,$2,$3,$4,$5,$6,$7)
+             ON CONFLICT (location, captured_at) DO UPDATE SET
+                 lead_min = EXCLUDED.lead_min,
+                 pop = EXCLUDED.pop,
+                 mm_per_hr = EXCLUDED.mm_per_hr,
+                 nowcast_raw_id = COALESCE(nowcast.nowcast_raw_id, EXCLUDED.nowcast_raw_id),
+                 parser_version = EXCLUDED.parser_version
+             RETURNING id, created_at, (xmax = 0) AS inserted
+         ), raw AS (
+             UPDATE nowcast_raw SET nowcast_id = up.id, parser_version = $7
+             FROM up
+             WHERE nowcast_raw.id = $6
+         )
+         SELECT id, created_at, inserted FROM up`,
+		n.Location, n.CapturedAt, n.LeadMin, n.POP, n.MMPerHr, n.NowcastRawID, n.ParserVersion,
 	)
-	if err := row.Scan(&n.ID, &n.CreatedAt); err != nil {
-		return err
-	}
-	return nil
+	err = row.Scan(&n.ID, &n.CreatedAt, &inserted)
+	return inserted, err
 }
 
 // InsertNowcastRaw stores the raw nowcast JSON.
 func InsertNowcastRaw(ctx context.Context, nr *model.NowcastRaw) error {
-	conn, err := getConn(ctx)
-	if err != nil {
-		return err
-	}
-	defer conn.Release()
-
-	row := conn.QueryRow(ctx,
+	row := dbConn(ctx).QueryRow(ctx,
 		`INSERT INTO nowcast_raw (location, data, fetched_at)
          VALUES ($1,$2,$3)
          RETURNING id`,
 		nr.Location, nr.Data, nr.FetchedAt,
 	)
-	if err := row.Scan(&nr.ID); err != nil {
-		return err
-	}
-	return nil
+	return row.Scan(&nr.ID)
 }
 
-// InsertNowcastCategory stores a category value for a nowcast row.
+// InsertNowcastCategory stores a category value for a nowcast row, replacing
+// the value already stored for the same category.
 func InsertNowcastCategory(ctx context.Context, c *model.NowcastCategory) error {
-	conn, err := getConn(ctx)
-	if err != nil {
-		return err
-	}
-	defer conn.Release()
-
-	row := conn.QueryRow(ctx,
+	row := dbConn(ctx).QueryRow(ctx,
 		`INSERT INTO nowcast_category (nowcast_id, category, value)
          VALUES ($1,$2,$3)
+         ON CONFLICT (nowcast_id, category) DO UPDATE SET value = EXCLUDED.value
          RETURNING id`,
 		c.NowcastID, c.Category, c.Value,
 	)
-	if err := row.Scan(&c.ID); err != nil {
-		return err
-	}
-	return nil
+	return row.Scan(&c.ID)
+}
+
+// DeleteNowcastCategories removes the category flags of a nowcast row.
+func DeleteNowcastCategories(ctx context.Context, nowcastID int) error {
+	_, err := dbConn(ctx).Exec(ctx, `DELETE FROM nowcast_category WHERE nowcast_id = $1`, nowcastID)
+	return err
 }
 
 // LatestNowcast returns the latest nowcast record for a location.
 func LatestNowcast(ctx context.Context, loc string) (*model.Nowcast, error) {
-	conn, err := getConn(ctx)
-	if err != nil {
-		return nil, err
-	}
-	defer conn.Release()
-
 	n := &model.Nowcast{}
-	row := conn.QueryRow(ctx,
+	row := dbConn(ctx).QueryRow(ctx,
 		`SELECT id, location, captured_at, lead_min, pop, mm_per_hr, created_at
          FROM nowcast
          WHERE location = $1
@@ -88,3 +82,57 @@ func LatestNowcast(ctx context.Context, loc string) (*model.Nowcast, error) {
 	}
 	return n, nil
 }
+
+const getNowcastRawRange = `
+SELECT id, location, data, fetched_at
+FROM nowcast_raw
+WHERE fetched_at >= $1 AND fetched_at < $2 AND nowcast_id IS NULL
+ORDER BY fetched_at
+`
+
+// NowcastRawRange returns the raw nowcast payloads fetched within [from, to)
+// that are not marked as parsed into a nowcast, oldest first.
+func NowcastRawRange(ctx context.Context, from, to time.Time) ([]model.NowcastRaw, error) {
+	rows, err := dbConn(ctx).Query(ctx, getNowcastRawRange, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []model.NowcastRaw
+	for rows.Next() {
+		var r model.NowcastRaw
+		if err := rows.Scan(&r.ID, &r.Location, &r.Data, &r.FetchedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+const streamNowcastRaw = `
+SELECT id, location, data, fetched_at
+FROM nowcast_raw
+WHERE fetched_at >= $1 AND (parser_version IS NULL OR parser_version < $2)
+ORDER BY fetched_at
+`
+
+// StreamNowcastRaw calls fn with every raw nowcast payload fetched at or
+// after since that was never parsed or was parsed by a version older than
+// parserVersion, oldest first. It stops at the first error fn returns.
+func StreamNowcastRaw(ctx context.Context, since time.Time, parserVersion int, fn func(model.NowcastRaw) error) error {
+	rows, err := dbConn(ctx).Query(ctx, streamNowcastRaw, since, parserVersion)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r model.NowcastRaw
+		if err := rows.Scan(&r.ID, &r.Location, &r.Data, &r.FetchedAt); err != nil {
+			return err
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
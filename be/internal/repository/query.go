@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/lolwierd/weatherboy/be/internal/model"
 )
@@ -38,19 +40,21 @@ func LatestRadarSnapshot(ctx context.Context, loc string) (*model.RadarSnapshot,
 	return &r, nil
 }
 
-// NowcastPOP1H returns the probability of precipitation for the first hour of the latest nowcast.
-func NowcastPOP1H(ctx context.Context, loc string) (float64, error) {
+// NowcastPOP1H returns the probability of precipitation for the first hour of the latest nowcast,
+// and the time that nowcast was captured.
+func NowcastPOP1H(ctx context.Context, loc string) (float64, time.Time, error) {
 	conn, err := getConn(ctx)
 	if err != nil {
-		return 0, err
+		return 0, time.Time{}, err
 	}
 	defer conn.Release()
-	row := conn.QueryRow(ctx, `SELECT pop FROM nowcast WHERE location=$1 AND captured_at=(SELECT MAX(captured_at) FROM nowcast WHERE location=$1) AND lead_min <= 60 ORDER BY lead_min DESC LIMIT 1`, loc)
+	row := conn.QueryRow(ctx, `SELECT pop, captured_at FROM nowcast WHERE location=$1 AND captured_at=(SELECT MAX(captured_at) FROM nowcast WHERE location=$1) AND lead_min <= 60 ORDER BY lead_min DESC LIMIT 1`, loc)
 	var pop float64
-	if err := row.Scan(&pop); err != nil {
-		return 0, err
+	var capturedAt time.Time
+	if err := row.Scan(&pop, &capturedAt); err != nil {
+		return 0, time.Time{}, err
 	}
-	return pop, nil
+	return pop, capturedAt, nil
 }
 
 // NowcastSlice returns the latest nowcast rows up to lead_min 240 minutes.
@@ -77,23 +81,25 @@ func NowcastSlice(ctx context.Context, loc string) ([]model.Nowcast, error) {
 	return list, rows.Err()
 }
 
-// LatestNowcastCategories returns category values for the latest nowcast row.
-func LatestNowcastCategories(ctx context.Context, loc string) (map[int]int16, error) {
+// LatestNowcastCategories returns category values for the latest nowcast row, and the time that
+// row was captured.
+func LatestNowcastCategories(ctx context.Context, loc string) (map[int]int16, time.Time, error) {
 	conn, err := getConn(ctx)
 	if err != nil {
-		return nil, err
+		return nil, time.Time{}, err
 	}
 	defer conn.Release()
 
-	row := conn.QueryRow(ctx, `SELECT id FROM nowcast WHERE location=$1 ORDER BY captured_at DESC LIMIT 1`, loc)
+	row := conn.QueryRow(ctx, `SELECT id, captured_at FROM nowcast WHERE location=$1 ORDER BY captured_at DESC LIMIT 1`, loc)
 	var nid int
-	if err := row.Scan(&nid); err != nil {
-		return nil, err
+	var capturedAt time.Time
+	if err := row.Scan(&nid, &capturedAt); err != nil {
+		return nil, time.Time{}, err
 	}
 
 	rows, err := conn.Query(ctx, `SELECT category, value FROM nowcast_category WHERE nowcast_id=$1`, nid)
 	if err != nil {
-		return nil, err
+		return nil, time.Time{}, err
 	}
 	defer rows.Close()
 	m := make(map[int]int16)
@@ -101,25 +107,28 @@ func LatestNowcastCategories(ctx context.Context, loc string) (map[int]int16, er
 		var cat int
 		var val int16
 		if err := rows.Scan(&cat, &val); err != nil {
-			return nil, err
+			return nil, time.Time{}, err
 		}
 		m[cat] = val
 	}
-	return m, rows.Err()
+	return m, capturedAt, rows.Err()
 }
 
-// LatestRiverBasinQPF returns the latest river basin QPF for a location.
-func LatestRiverBasinQPF(ctx context.Context, loc string) (*model.RiverBasinQPF, error) {
-	conn, err := getConn(ctx)
-	if err != nil {
-		return nil, err
-	}
-	defer conn.Release()
-	row := conn.QueryRow(ctx, `SELECT id, basin_id, date, fmo, basin, sub_basin, area, day1, day2, day3, day4, day5, aap, fetched_at
-        FROM river_basin_qpf WHERE basin=$1 ORDER BY fetched_at DESC LIMIT 1`, loc)
-	var r model.RiverBasinQPF
-	if err := row.Scan(&r.ID, &r.BasinID, &r.Date, &r.FMO, &r.Basin, &r.SubBasin, &r.Area, &r.Day1, &r.Day2, &r.Day3, &r.Day4, &r.Day5, &r.AAP, &r.FetchedAt); err != nil {
-		return nil, err
-	}
-	return &r, nil
+// LatestRiverBasinQPF returns the latest river basin QPF for a basin. A zero
+// subBasinID matches any sub-basin of the basin.
+func LatestRiverBasinQPF(ctx context.Context, basinID, subBasinID int) (*model.RiverBasinQPF, error) {
+	return cached(ctx, fmt.Sprintf("river_basin_qpf:%d:%d", basinID, subBasinID), func() (*model.RiverBasinQPF, error) {
+		conn, err := getConn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Release()
+		row := conn.QueryRow(ctx, `SELECT id, basin_id, sub_basin_id, date, fmo, basin, sub_basin, area, day1, day2, day3, day4, day5, aap, fetched_at
+	        FROM river_basin_qpf WHERE basin_id=$1 AND ($2=0 OR sub_basin_id=$2) ORDER BY fetched_at DESC, date DESC LIMIT 1`, basinID, subBasinID)
+		var r model.RiverBasinQPF
+		if err := row.Scan(&r.ID, &r.BasinID, &r.SubBasinID, &r.Date, &r.FMO, &r.Basin, &r.SubBasin, &r.Area, &r.Day1, &r.Day2, &r.Day3, &r.Day4, &r.Day5, &r.AAP, &r.FetchedAt); err != nil {
+			return nil, err
+		}
+		return &r, nil
+	})
 }
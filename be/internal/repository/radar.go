@@ -3,7 +3,6 @@ package repository
 import (
 	"context"
 
-	"github.com/lolwierd/weatherboy/be/internal/db"
 	"github.com/lolwierd/weatherboy/be/internal/model"
 )
 
@@ -15,7 +14,7 @@ RETURNING id, fetched_at
 
 // InsertRadar inserts a new radar record into the database.
 func InsertRadar(ctx context.Context, r *model.Radar) error {
-	return db.GetDBDriver().ConnPool.QueryRow(ctx, insertRadar, r.Location, r.MaxDBZ, r.CapturedAt).Scan(&r.ID, &r.FetchedAt)
+	return dbConn(ctx).QueryRow(ctx, insertRadar, r.Location, r.MaxDBZ, r.CapturedAt).Scan(&r.ID, &r.FetchedAt)
 }
 
 const insertRadarSnapshot = `
@@ -26,5 +25,5 @@ RETURNING id, created_at
 
 // InsertRadarSnapshot inserts a new radar snapshot record into the database.
 func InsertRadarSnapshot(ctx context.Context, rs *model.RadarSnapshot) error {
-	return db.GetDBDriver().ConnPool.QueryRow(ctx, insertRadarSnapshot, rs.Location, rs.CapturedAt, rs.MaxDBZ, rs.Bearing, rs.RangeKM).Scan(&rs.ID, &rs.CreatedAt)
+	return dbConn(ctx).QueryRow(ctx, insertRadarSnapshot, rs.Location, rs.CapturedAt, rs.MaxDBZ, rs.Bearing, rs.RangeKM).Scan(&rs.ID, &rs.CreatedAt)
 }
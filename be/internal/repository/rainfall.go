@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github.com/lolwierd/weatherboy/be/internal/model"
+)
+
+const upsertAWSRainfall = `
+INSERT INTO aws_rainfall (station_id, "window", ends_at, mm, readings)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (station_id, "window", ends_at) DO UPDATE SET
+	mm = EXCLUDED.mm,
+	readings = EXCLUDED.readings,
+	computed_at = NOW()
+RETURNING id, computed_at
+`
+
+// UpsertAWSRainfall stores a rainfall accumulation, replacing an earlier one
+// for the same station, window and end time.
+func UpsertAWSRainfall(ctx context.Context, r *model.AWSRainfall) error {
+	return dbConn(ctx).QueryRow(ctx, upsertAWSRainfall,
+		r.StationID, r.Window, r.EndsAt, r.MM, r.Readings,
+	).Scan(&r.ID, &r.ComputedAt)
+}
+
+const getLatestAWSRainfall = `
+SELECT DISTINCT ON (station_id) id, station_id, "window", ends_at, mm, readings, computed_at
+FROM aws_rainfall
+WHERE station_id = ANY($1) AND "window" = $2
+ORDER BY station_id, ends_at DESC
+`
+
+// LatestAWSRainfall returns the latest accumulation over window of each given
+// station.
+func LatestAWSRainfall(ctx context.Context, stationIDs []string, window string) ([]model.AWSRainfall, error) {
+	return cached(ctx, "aws_rainfall:"+window+":"+strings.Join(stationIDs, ","), func() ([]model.AWSRainfall, error) {
+		rows, err := dbConn(ctx).Query(ctx, getLatestAWSRainfall, stationIDs, window)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		var list []model.AWSRainfall
+		for rows.Next() {
+			var r model.AWSRainfall
+			if err := rows.Scan(&r.ID, &r.StationID, &r.Window, &r.EndsAt, &r.MM, &r.Readings, &r.ComputedAt); err != nil {
+				return nil, err
+			}
+			list = append(list, r)
+		}
+		return list, rows.Err()
+	})
+}
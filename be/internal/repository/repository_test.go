@@ -2,11 +2,14 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"testing"
 	"time"
 
 	pgxmock "github.com/pashagolub/pgxmock/v4"
 
+	"github.com/lolwierd/weatherboy/be/internal/config"
 	"github.com/lolwierd/weatherboy/be/internal/db"
 	"github.com/lolwierd/weatherboy/be/internal/model"
 )
@@ -26,11 +29,12 @@ func TestInsertBulletinRaw(t *testing.T) {
 	mock := setupMock(t)
 	defer mock.Close()
 
-	mock.ExpectQuery("INSERT INTO bulletin_raw").
-		WithArgs("/tmp/a.pdf", pgxmock.AnyArg()).
+	key := "bulletin/ab/ab.pdf"
+	mock.ExpectQuery("INSERT INTO bulletin_raw .* blob_key = COALESCE\\(EXCLUDED.blob_key, bulletin_raw.blob_key\\)").
+		WithArgs("/tmp/a.pdf", &key, pgxmock.AnyArg()).
 		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(1))
 
-	br := &model.BulletinRaw{Path: "/tmp/a.pdf", FetchedAt: time.Now()}
+	br := &model.BulletinRaw{Path: "/tmp/a.pdf", BlobKey: &key, FetchedAt: time.Now()}
 	if err := InsertBulletinRaw(context.Background(), br); err != nil {
 		t.Fatalf("insert raw: %v", err)
 	}
@@ -44,11 +48,9 @@ func TestInsertIMDAPICall(t *testing.T) {
 	mock := setupMock(t)
 	defer mock.Close()
 
-	mock.ExpectBegin()
 	mock.ExpectQuery("INSERT INTO imd_api_log").
 		WithArgs("https://example.com", int64(123), pgxmock.AnyArg()).
 		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(1))
-	mock.ExpectCommit()
 
 	call := &model.IMDAPICall{Endpoint: "https://example.com", Bytes: 123, RequestedAt: time.Now()}
 	if err := InsertIMDAPICall(context.Background(), call); err != nil {
@@ -64,11 +66,9 @@ func TestInsertNowcastRaw(t *testing.T) {
 	mock := setupMock(t)
 	defer mock.Close()
 
-	mock.ExpectBegin()
 	mock.ExpectQuery("INSERT INTO nowcast_raw").
 		WithArgs("vadodara", pgxmock.AnyArg(), pgxmock.AnyArg()).
 		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(1))
-	mock.ExpectCommit()
 
 	nr := &model.NowcastRaw{Location: "vadodara", Data: []byte("{}"), FetchedAt: time.Now()}
 	if err := InsertNowcastRaw(context.Background(), nr); err != nil {
@@ -84,11 +84,9 @@ func TestInsertNowcastCategory(t *testing.T) {
 	mock := setupMock(t)
 	defer mock.Close()
 
-	mock.ExpectBegin()
 	mock.ExpectQuery("INSERT INTO nowcast_category").
 		WithArgs(1, 2, int16(3)).
 		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(1))
-	mock.ExpectCommit()
 
 	c := &model.NowcastCategory{NowcastID: 1, Category: 2, Value: 3};
 	if err := InsertNowcastCategory(context.Background(), c); err != nil {
@@ -98,4 +96,320 @@ func TestInsertNowcastCategory(t *testing.T) {
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Fatalf("expectations: %v", err)
 	}
-}
\ No newline at end of file
+}
+func TestAWSARGSeries(t *testing.T) {
+	mock := setupMock(t)
+	defer mock.Close()
+
+	from := time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC)
+	to := from.Add(3 * time.Hour)
+	mean := 30.5
+	mock.ExpectQuery("SELECT date_bin").
+		WithArgs(time.Hour, from, []string{"A1"}, to).
+		WillReturnRows(pgxmock.NewRows([]string{"bucket", "min", "max", "avg", "last", "count"}).
+			AddRow(from, &mean, &mean, &mean, &mean, 2))
+
+	points, err := AWSARGSeries(context.Background(), []string{"A1"}, "temp", from, to, time.Hour)
+	if err != nil {
+		t.Fatalf("series: %v", err)
+	}
+	if len(points) != 1 || *points[0].Mean != mean || points[0].Count != 2 {
+		t.Fatalf("unexpected points: %+v", points)
+	}
+	if _, err := AWSARGSeries(context.Background(), []string{"A1"}, "station_id", from, to, time.Hour); err == nil {
+		t.Fatalf("expected error for unknown field")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestAWSARGSeriesOffsetRange(t *testing.T) {
+	mock := setupMock(t)
+	defer mock.Close()
+
+	ist := time.FixedZone("IST", 5*3600+1800)
+	from := time.Date(2024, 6, 20, 5, 30, 0, 0, ist)
+	to := from.Add(3 * time.Hour)
+	mock.ExpectQuery("SELECT date_bin").
+		WithArgs(time.Hour, time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC), []string{"A1"}, time.Date(2024, 6, 20, 3, 0, 0, 0, time.UTC)).
+		WillReturnRows(pgxmock.NewRows([]string{"bucket", "min", "max", "avg", "last", "count"}))
+
+	if _, err := AWSARGSeries(context.Background(), []string{"A1"}, "temp", from, to, time.Hour); err != nil {
+		t.Fatalf("series: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestInTxSharesTransaction(t *testing.T) {
+	mock := setupMock(t)
+	defer mock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO nowcast_raw").
+		WithArgs("vadodara", pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("INSERT INTO nowcast_category").
+		WithArgs(1, 2, int16(3)).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	err := InTx(context.Background(), func(ctx context.Context) error {
+		nr := &model.NowcastRaw{Location: "vadodara", Data: []byte("{}"), FetchedAt: time.Now()}
+		if err := InsertNowcastRaw(ctx, nr); err != nil {
+			return err
+		}
+		return InsertNowcastCategory(ctx, &model.NowcastCategory{NowcastID: 1, Category: 2, Value: 3})
+	})
+	if err != nil {
+		t.Fatalf("in tx: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestInTxRollsBack(t *testing.T) {
+	mock := setupMock(t)
+	defer mock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO nowcast_raw").
+		WithArgs("vadodara", pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectRollback()
+
+	want := errors.New("store nowcast")
+	err := InTx(context.Background(), func(ctx context.Context) error {
+		nr := &model.NowcastRaw{Location: "vadodara", Data: []byte("{}"), FetchedAt: time.Now()}
+		if err := InsertNowcastRaw(ctx, nr); err != nil {
+			return err
+		}
+		return want
+	})
+	if !errors.Is(err, want) {
+		t.Fatalf("in tx: got %v, want %v", err, want)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestInsertLocationExists(t *testing.T) {
+	mock := setupMock(t)
+	defer mock.Close()
+
+	mock.ExpectExec("INSERT INTO location").
+		WithArgs("vadodara", "Vadodara", 0.0, 0.0, 0, "", []string{}, 0, 0, []string{}, 0, 0.0, "", "", "").
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+
+	err := InsertLocation(context.Background(), config.Location{Name: "vadodara", DisplayName: "Vadodara"})
+	if !errors.Is(err, ErrLocationExists) {
+		t.Fatalf("err = %v, want ErrLocationExists", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestLatestDistrictWarningForDistrict(t *testing.T) {
+	mock := setupMock(t)
+	defer mock.Close()
+
+	issued := time.Date(2026, 7, 1, 6, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT DISTINCT ON \\(location\\)").
+		WithArgs([]string{"vadodara"}).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "location", "issued_at", "day1_warning", "day2_warning", "day3_warning", "day4_warning", "day5_warning", "day1_color", "day2_color", "day3_color", "day4_color", "day5_color", "created_at"}).
+			AddRow(1, "vadodara", issued, "Heavy Rain", "", "", "", "", "Orange", "", "", "", "", issued))
+
+	dw, err := LatestDistrictWarningForDistrict(context.Background(), 244)
+	if err != nil {
+		t.Fatalf("latest district warning: %v", err)
+	}
+	if dw.Location != "vadodara" || dw.Day1Color != "Orange" {
+		t.Fatalf("unexpected warning %+v", dw)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestCachedRunsOncePerKey(t *testing.T) {
+	ctx := WithCache(context.Background())
+	calls := 0
+	fn := func() (int, error) { calls++; return calls, nil }
+
+	for range 3 {
+		if v, _ := cached(ctx, "a", fn); v != 1 {
+			t.Fatalf("cached a = %d, want 1", v)
+		}
+	}
+	if v, _ := cached(ctx, "b", fn); v != 2 {
+		t.Fatalf("cached b = %d, want 2", v)
+	}
+	if v, _ := cached(context.Background(), "a", fn); v != 3 {
+		t.Fatalf("uncached a = %d, want 3", v)
+	}
+}
+
+func TestUpsertNowcastMarksEveryRaw(t *testing.T) {
+	mock := setupMock(t)
+	defer mock.Close()
+
+	captured := time.Date(2026, 7, 1, 8, 30, 0, 0, time.UTC)
+	for _, rawID := range []int{1, 2} {
+		mock.ExpectQuery("INSERT INTO nowcast .* UPDATE nowcast_raw SET nowcast_id = up.id, parser_version = \\$7").
+			WithArgs("vadodara", captured, 0, 0.8, 2.0, &rawID, 1).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "created_at", "inserted"}).AddRow(7, captured, rawID == 1))
+	}
+	mock.ExpectQuery("FROM nowcast_raw\\s+WHERE fetched_at >= \\$1 AND \\(parser_version IS NULL OR parser_version < \\$2\\)").
+		WithArgs(captured, 1).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "location", "data", "fetched_at"}))
+
+	for _, rawID := range []int{1, 2} {
+		n := model.Nowcast{Location: "vadodara", CapturedAt: captured, POP: 0.8, MMPerHr: 2, NowcastRawID: &rawID, ParserVersion: 1}
+		inserted, err := UpsertNowcast(context.Background(), &n)
+		if err != nil {
+			t.Fatalf("upsert raw %d: %v", rawID, err)
+		}
+		if n.ID != 7 {
+			t.Fatalf("raw %d parsed into %d, want 7", rawID, n.ID)
+		}
+		if inserted != (rawID == 1) {
+			t.Errorf("raw %d reported inserted %v", rawID, inserted)
+		}
+	}
+	err := StreamNowcastRaw(context.Background(), captured, 1, func(r model.NowcastRaw) error {
+		t.Errorf("raw %d streamed after being parsed", r.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestUpsertDistrictWarningMarksEveryRaw(t *testing.T) {
+	mock := setupMock(t)
+	defer mock.Close()
+
+	issued := time.Date(2026, 7, 1, 6, 0, 0, 0, time.UTC)
+	for _, rawID := range []int{1, 2} {
+		mock.ExpectQuery("INSERT INTO district_warning .* UPDATE district_warning_raw SET district_warning_id = up.id, parser_version = \\$14").
+			WithArgs("vadodara", issued, "Heavy Rain", "", "", "", "", "Orange", "", "", "", "", &rawID, 1).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "created_at", "inserted"}).AddRow(3, issued, rawID == 1))
+	}
+
+	for _, rawID := range []int{1, 2} {
+		dw := model.DistrictWarning{Location: "vadodara", IssuedAt: issued, Day1Warning: "Heavy Rain", Day1Color: "Orange", DistrictWarningRawID: &rawID, ParserVersion: 1}
+		inserted, err := UpsertDistrictWarning(context.Background(), &dw)
+		if err != nil {
+			t.Fatalf("upsert raw %d: %v", rawID, err)
+		}
+		if inserted != (rawID == 1) {
+			t.Errorf("raw %d reported inserted %v", rawID, inserted)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestUpsertParsedBulletinChanged(t *testing.T) {
+	mock := setupMock(t)
+	defer mock.Close()
+
+	// A new record, the same forecast again, then a re-issued forecast.
+	cases := []struct {
+		forecast string
+		changed  bool
+	}{
+		{"Light rain", true},
+		{"Light rain", false},
+		{"Heavy rain", true},
+	}
+	for _, tc := range cases {
+		mock.ExpectQuery("WITH prev AS .* INSERT INTO bulletin_parsed .* NOT EXISTS \\(SELECT 1 FROM prev WHERE prev.forecast = \\$3\\)").
+			WithArgs(1, "vadodara", tc.forecast, 1).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "fetched_at", "changed"}).AddRow(5, time.Now(), tc.changed))
+	}
+
+	for _, tc := range cases {
+		bp := model.BulletinParsed{BulletinRawID: 1, Location: "vadodara", Forecast: tc.forecast, ParserVersion: 1}
+		changed, err := UpsertParsedBulletin(context.Background(), &bp)
+		if err != nil {
+			t.Fatalf("upsert %q: %v", tc.forecast, err)
+		}
+		if bp.ID != 5 || changed != tc.changed {
+			t.Errorf("%q: id %d, changed %v, want 5, %v", tc.forecast, bp.ID, changed, tc.changed)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestUpsertRiverBasinQPFInserted(t *testing.T) {
+	mock := setupMock(t)
+	defer mock.Close()
+
+	date := time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+	for _, inserted := range []bool{true, false} {
+		mock.ExpectQuery("INSERT INTO river_basin_qpf .* RETURNING id, fetched_at, \\(xmax = 0\\) AS inserted").
+			WithArgs(1, 12, date, "", "", "", "", "11-25", "", "", "", "", "").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "fetched_at", "inserted"}).AddRow(9, time.Now(), inserted))
+	}
+
+	for _, want := range []bool{true, false} {
+		q := model.RiverBasinQPF{BasinID: 1, SubBasinID: 12, Date: date, Day1: "11-25"}
+		inserted, err := UpsertRiverBasinQPF(context.Background(), &q)
+		if err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+		if q.ID != 9 || inserted != want {
+			t.Errorf("id %d, inserted %v, want 9, %v", q.ID, inserted, want)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestAWSStationLocations(t *testing.T) {
+	mock := setupMock(t)
+	defer mock.Close()
+
+	seen := time.Now()
+	rows := pgxmock.NewRows([]string{"station_id", "call_sign", "station_name", "district", "state", "latitude", "longitude", "first_seen_at", "last_seen_at"}).
+		AddRow("VDR", "VDR", "Vadodara AWS", "VADODARA", "GUJARAT", 22.31, 73.18, seen, seen).
+		AddRow("CLB", "CLB", "Colaba AWS", "MUMBAI", "MAHARASHTRA", 19.10, 72.85, seen, seen)
+	mock.ExpectQuery("FROM aws_station").WithArgs(pgxmock.AnyArg()).WillReturnRows(rows)
+
+	// NDL is configured for Vadodara; CLB is among the nearest stations of
+	// Mumbai and Thane but too far from Pune.
+	locs, err := AWSStationLocations(context.Background(), []string{"NDL", "CLB"})
+	if err != nil {
+		t.Fatalf("locations: %v", err)
+	}
+	if len(locs) != 3 || !slices.Equal(locs["vadodara"], []string{"NDL"}) ||
+		!slices.Equal(locs["mumbai"], []string{"CLB"}) || !slices.Equal(locs["thane"], []string{"CLB"}) {
+		t.Errorf("locations = %v", locs)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// retentionTable describes how rows of a table age out.
+type retentionTable struct {
+	// timeColumn dates each row.
+	timeColumn string
+	// partition and rank pick the row kept per bucket when downsampling: the
+	// first row by rank within each partition. Empty when the table cannot be
+	// downsampled.
+	partition string
+	rank      string
+	// rollup lists the columns the kept row takes the bucket maximum of, so
+	// the rollup of a bucket reports its worst reading.
+	rollup []string
+}
+
+// retentionTables lists the tables retention policies can prune. Table and
+// column names are only ever taken from here.
+var retentionTables = map[string]retentionTable{
+	"nowcast_raw":          {timeColumn: "fetched_at"},
+	"district_warning_raw": {timeColumn: "fetched_at"},
+	"imd_api_log":          {timeColumn: "requested_at"},
+	"job_run":              {timeColumn: "started_at"},
+	"aws_arg":              {timeColumn: "fetched_at"},
+	// Keep the wettest reading of each bucket so downsampling never hides a
+	// rain event.
+	"nowcast":        {timeColumn: "captured_at", partition: "location", rank: "mm_per_hr DESC, pop DESC, id DESC", rollup: []string{"pop", "mm_per_hr"}},
+	"radar_snapshot": {timeColumn: "captured_at", partition: "location", rank: "max_dbz DESC, id DESC", rollup: []string{"max_dbz"}},
+}
+
+func lookupRetentionTable(table string, downsample bool) (retentionTable, error) {
+	t, ok := retentionTables[table]
+	if !ok {
+		return retentionTable{}, fmt.Errorf("table %s has no retention policy support", table)
+	}
+	if downsample && t.partition == "" {
+		return retentionTable{}, fmt.Errorf("table %s cannot be downsampled", table)
+	}
+	return t, nil
+}
+
+// CountExpired returns how many rows of table are dated before before.
+func CountExpired(ctx context.Context, table string, before time.Time) (int64, error) {
+	t, err := lookupRetentionTable(table, false)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	err = dbConn(ctx).QueryRow(ctx,
+		fmt.Sprintf(`SELECT count(*) FROM %s WHERE %s < $1`, table, t.timeColumn),
+		before,
+	).Scan(&n)
+	return n, err
+}
+
+// DeleteExpired deletes up to limit rows of table dated before before and
+// returns how many it deleted.
+func DeleteExpired(ctx context.Context, table string, before time.Time, limit int) (int64, error) {
+	t, err := lookupRetentionTable(table, false)
+	if err != nil {
+		return 0, err
+	}
+	tag, err := dbConn(ctx).Exec(ctx,
+		fmt.Sprintf(`DELETE FROM %[1]s WHERE id IN (SELECT id FROM %[1]s WHERE %[2]s < $1 ORDER BY id LIMIT $2)`, table, t.timeColumn),
+		before, limit,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// downsampledRows selects the ids of the rows dated before $1 that are not
+// the one kept in their $2 bucket.
+func downsampledRows(table string, t retentionTable) string {
+	return fmt.Sprintf(`
+SELECT id FROM (
+    SELECT id, row_number() OVER (
+        PARTITION BY %[2]s, date_bin($2, %[3]s, TIMESTAMPTZ '2000-01-01')
+        ORDER BY %[4]s
+    ) AS n
+    FROM %[1]s
+    WHERE %[3]s < $1
+) ranked
+WHERE n > 1`, table, t.partition, t.timeColumn, t.rank)
+}
+
+// CountDownsampled returns how many rows of table dated before before would
+// be removed by keeping one row per interval.
+func CountDownsampled(ctx context.Context, table string, before time.Time, interval time.Duration) (int64, error) {
+	t, err := lookupRetentionTable(table, true)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	err = dbConn(ctx).QueryRow(ctx,
+		`SELECT count(*) FROM (`+downsampledRows(table, t)+`) d`,
+		before, interval,
+	).Scan(&n)
+	return n, err
+}
+
+// downsampleOrigin aligns downsampling buckets, as date_bin does.
+var downsampleOrigin = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// DownsampleBucket returns the start of the interval-wide bucket t falls in.
+func DownsampleBucket(t time.Time, interval time.Duration) time.Time {
+	return downsampleOrigin.Add(t.Sub(downsampleOrigin) / interval * interval)
+}
+
+// OldestRollupPending returns the start of the oldest bucket of table dated
+// before before that is not yet rolled up, or nil when every bucket is. A
+// rolled up bucket holds a single row dated at the bucket start.
+func OldestRollupPending(ctx context.Context, table string, before time.Time, interval time.Duration) (*time.Time, error) {
+	t, err := lookupRetentionTable(table, true)
+	if err != nil {
+		return nil, err
+	}
+	var start *time.Time
+	err = dbConn(ctx).QueryRow(ctx,
+		fmt.Sprintf(`SELECT min(date_bin($2, %[2]s, TIMESTAMPTZ '2000-01-01')) FROM %[1]s
+WHERE %[2]s < $1 AND %[2]s <> date_bin($2, %[2]s, TIMESTAMPTZ '2000-01-01')`, table, t.timeColumn),
+		before, interval,
+	).Scan(&start)
+	return start, err
+}
+
+// RollupRange reduces the rows of table dated within [from, to) to one row
+// per interval and partition and returns how many it deleted. The row kept
+// for a bucket is the first by rank; it is moved to the bucket start and
+// takes the bucket maximum of the rollup columns. from and to should fall on
+// bucket boundaries so no bucket is split.
+func RollupRange(ctx context.Context, table string, from, to time.Time, interval time.Duration) (int64, error) {
+	t, err := lookupRetentionTable(table, true)
+	if err != nil {
+		return 0, err
+	}
+	var cols, aggregates, sets strings.Builder
+	for _, col := range t.rollup {
+		fmt.Fprintf(&cols, ", %s", col)
+		fmt.Fprintf(&aggregates, ",\n       MAX(%[1]s) OVER (PARTITION BY %[2]s, date_bin($3, %[3]s, TIMESTAMPTZ '2000-01-01'))", col, t.partition, t.timeColumn)
+		fmt.Fprintf(&sets, ", %[1]s = r.%[1]s", col)
+	}
+
+	var deleted int64
+	err = InTx(ctx, func(ctx context.Context) error {
+		// Rank the range once so the delete and the update agree on the
+		// kept rows. The kept rows move to their bucket start only after
+		// the other rows are gone, which may already sit there.
+		_, err := dbConn(ctx).Exec(ctx, fmt.Sprintf(
+			`CREATE TEMP TABLE rollup_range ON COMMIT DROP AS SELECT id, %[2]s AS bucket, 0::bigint AS n%[3]s FROM %[1]s WITH NO DATA`,
+			table, t.timeColumn, cols.String()))
+		if err != nil {
+			return err
+		}
+		_, err = dbConn(ctx).Exec(ctx, fmt.Sprintf(`
+INSERT INTO rollup_range
+SELECT id,
+       date_bin($3, %[3]s, TIMESTAMPTZ '2000-01-01'),
+       row_number() OVER (
+           PARTITION BY %[2]s, date_bin($3, %[3]s, TIMESTAMPTZ '2000-01-01')
+           ORDER BY %[4]s
+       )%[5]s
+FROM %[1]s
+WHERE %[3]s >= $1 AND %[3]s < $2`, table, t.partition, t.timeColumn, t.rank, aggregates.String()),
+			from, to, interval,
+		)
+		if err != nil {
+			return err
+		}
+		tag, err := dbConn(ctx).Exec(ctx, fmt.Sprintf(
+			`DELETE FROM %s t USING rollup_range r WHERE t.id = r.id AND r.n > 1`, table))
+		if err != nil {
+			return err
+		}
+		deleted = tag.RowsAffected()
+		_, err = dbConn(ctx).Exec(ctx, fmt.Sprintf(
+			`UPDATE %[1]s t SET %[2]s = r.bucket%[3]s FROM rollup_range r WHERE t.id = r.id AND r.n = 1`,
+			table, t.timeColumn, sets.String()))
+		return err
+	})
+	return deleted, err
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lolwierd/weatherboy/be/internal/model"
+)
+
+const insertRiskHistory = `
+INSERT INTO risk_history (location, level, score, breakdown, computed_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id
+`
+
+// InsertRiskHistory stores a computed risk level for a location.
+func InsertRiskHistory(ctx context.Context, h *model.RiskHistory) error {
+	return dbConn(ctx).QueryRow(ctx, insertRiskHistory,
+		h.Location, h.Level, h.Score, h.Breakdown, h.ComputedAt,
+	).Scan(&h.ID)
+}
+
+const getLatestRiskHistory = `
+SELECT id, location, level, score, breakdown, computed_at
+FROM risk_history
+WHERE location = $1
+ORDER BY computed_at DESC
+LIMIT 1
+`
+
+// LatestRiskHistory returns the most recently stored risk level for a location.
+func LatestRiskHistory(ctx context.Context, loc string) (*model.RiskHistory, error) {
+	var h model.RiskHistory
+	err := dbConn(ctx).QueryRow(ctx, getLatestRiskHistory, loc).
+		Scan(&h.ID, &h.Location, &h.Level, &h.Score, &h.Breakdown, &h.ComputedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+const getRiskHistoryRange = `
+SELECT id, location, level, score, breakdown, computed_at
+FROM risk_history
+WHERE location = $1 AND computed_at >= $2 AND computed_at < $3
+ORDER BY computed_at
+`
+
+// RiskHistoryRange returns the stored risk levels for a location within [from, to).
+func RiskHistoryRange(ctx context.Context, loc string, from, to time.Time) ([]model.RiskHistory, error) {
+	rows, err := dbConn(ctx).Query(ctx, getRiskHistoryRange, loc, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []model.RiskHistory
+	for rows.Next() {
+		var h model.RiskHistory
+		if err := rows.Scan(&h.ID, &h.Location, &h.Level, &h.Score, &h.Breakdown, &h.ComputedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, h)
+	}
+	return list, rows.Err()
+}
+
+const insertRiskTransition = `
+INSERT INTO risk_transition (location, from_level, to_level, score, transitioned_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id
+`
+
+// InsertRiskTransition records a change in risk level for a location.
+func InsertRiskTransition(ctx context.Context, t *model.RiskTransition) error {
+	return dbConn(ctx).QueryRow(ctx, insertRiskTransition,
+		t.Location, t.FromLevel, t.ToLevel, t.Score, t.TransitionedAt,
+	).Scan(&t.ID)
+}
+
+const getRiskTransitionRange = `
+SELECT id, location, from_level, to_level, score, transitioned_at
+FROM risk_transition
+WHERE location = $1 AND transitioned_at >= $2 AND transitioned_at < $3
+ORDER BY transitioned_at
+`
+
+// RiskTransitionRange returns the risk level transitions for a location within [from, to).
+func RiskTransitionRange(ctx context.Context, loc string, from, to time.Time) ([]model.RiskTransition, error) {
+	rows, err := dbConn(ctx).Query(ctx, getRiskTransitionRange, loc, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []model.RiskTransition
+	for rows.Next() {
+		var t model.RiskTransition
+		if err := rows.Scan(&t.ID, &t.Location, &t.FromLevel, &t.ToLevel, &t.Score, &t.TransitionedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, t)
+	}
+	return list, rows.Err()
+}
@@ -3,19 +3,33 @@ package repository
 import (
 	"context"
 
-	"github.com/lolwierd/weatherboy/be/internal/db"
 	"github.com/lolwierd/weatherboy/be/internal/model"
 )
 
-const insertRiverBasinQPF = `
-INSERT INTO river_basin_qpf (basin_id, date, fmo, basin, sub_basin, area, day1, day2, day3, day4, day5, aap)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-RETURNING id, fetched_at
+const upsertRiverBasinQPF = `
+INSERT INTO river_basin_qpf (basin_id, sub_basin_id, date, fmo, basin, sub_basin, area, day1, day2, day3, day4, day5, aap)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+ON CONFLICT (basin_id, sub_basin_id, date) DO UPDATE SET
+	fmo = EXCLUDED.fmo,
+	basin = EXCLUDED.basin,
+	sub_basin = EXCLUDED.sub_basin,
+	area = EXCLUDED.area,
+	day1 = EXCLUDED.day1,
+	day2 = EXCLUDED.day2,
+	day3 = EXCLUDED.day3,
+	day4 = EXCLUDED.day4,
+	day5 = EXCLUDED.day5,
+	aap = EXCLUDED.aap,
+	fetched_at = NOW()
+RETURNING id, fetched_at, (xmax = 0) AS inserted
 `
 
-// InsertRiverBasinQPF inserts a new river basin QPF record into the database.
-func InsertRiverBasinQPF(ctx context.Context, r *model.RiverBasinQPF) error {
-	return db.GetDBDriver().ConnPool.QueryRow(ctx, insertRiverBasinQPF, 
-		r.BasinID, r.Date, r.FMO, r.Basin, r.SubBasin, r.Area, r.Day1, r.Day2, r.Day3, r.Day4, r.Day5, r.AAP,
-	).Scan(&r.ID, &r.FetchedAt)
+// UpsertRiverBasinQPF inserts a river basin QPF record, replacing the record
+// of the same sub-basin and forecast date. It reports whether the record is
+// new rather than a replacement.
+func UpsertRiverBasinQPF(ctx context.Context, r *model.RiverBasinQPF) (inserted bool, err error) {
+	err = dbConn(ctx).QueryRow(ctx, upsertRiverBasinQPF, 
+		r.BasinID, r.SubBasinID, r.Date, r.FMO, r.Basin, r.SubBasin, r.Area, r.Day1, r.Day2, r.Day3, r.Day4, r.Day5, r.AAP,
+	).Scan(&r.ID, &r.FetchedAt, &inserted)
+	return inserted, err
 }
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/lolwierd/weatherboy/be/internal/db"
+)
+
+// querier is implemented by both the connection pool and a transaction.
+type querier interface {
+	QueryRow(ctx context.Context, sql string, arguments ...any) pgx.Row
+	Query(ctx context.Context, sql string, arguments ...any) (pgx.Rows, error)
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+type txKey struct{}
+
+// InTx runs fn as a unit of work: repository calls made with the context
+// passed to fn share one transaction, committed when fn returns nil and
+// rolled back otherwise. Nested calls join the outer transaction.
+func InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return fn(ctx)
+	}
+
+	_, tx, err := getConnTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// dbConn returns the transaction of the unit of work ctx belongs to, or the
+// pool outside of one.
+func dbConn(ctx context.Context) querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return db.GetDBDriver().ConnPool
+}
@@ -0,0 +1,156 @@
+// Package retention prunes ingested rows that are past their table's
+// retention policy.
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/repository"
+	"github.com/lolwierd/weatherboy/be/internal/storage"
+)
+
+// blobStore is the store blobs no bulletin references are removed from.
+// Tests replace it.
+var blobStore = storage.Default
+
+// Report is what the policy of one table removed, or would remove on a dry
+// run.
+type Report struct {
+	Table string `json:"table"`
+	// Before is the cutoff: rows dated earlier are deleted or downsampled.
+	Before time.Time `json:"before"`
+	// Downsample is the interval older rows are rolled up to, empty when they
+	// are deleted.
+	Downsample string `json:"downsample,omitempty"`
+	Rows       int64  `json:"rows"`
+}
+
+// Run applies the policy of every table as of now. A dry run only counts the
+// rows that would be removed. Rows are deleted in batches of r.BatchSize and
+// rolled up a day at a time; a failing table does not stop the others.
+func Run(ctx context.Context, r config.Retention, now time.Time, dryRun bool) ([]Report, error) {
+	var reports []Report
+	var errs []error
+	for _, table := range r.TableNames() {
+		p := r.Tables[table]
+		if p.Keep == 0 {
+			continue
+		}
+		rep := Report{Table: table, Before: now.Add(-p.Keep)}
+		if p.Downsample > 0 {
+			// Never split a bucket between rolled up and full resolution rows.
+			rep.Before = repository.DownsampleBucket(rep.Before, p.Downsample)
+			rep.Downsample = p.Downsample.String()
+		}
+
+		n, err := apply(ctx, table, rep.Before, p.Downsample, r.BatchSize, dryRun)
+		rep.Rows = n
+		reports = append(reports, rep)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", table, err))
+		}
+	}
+	return reports, errors.Join(errs...)
+}
+
+// apply deletes, or counts on a dry run, the rows of table a policy removes.
+func apply(ctx context.Context, table string, before time.Time, downsample time.Duration, batchSize int, dryRun bool) (int64, error) {
+	switch {
+	case table == "blob" && dryRun:
+		return repository.CountOrphanBlobs(ctx, before)
+	case table == "blob":
+		return sweepBlobs(ctx, before, batchSize)
+	case dryRun && downsample > 0:
+		return repository.CountDownsampled(ctx, table, before, downsample)
+	case dryRun:
+		return repository.CountExpired(ctx, table, before)
+	case downsample > 0:
+		return rollup(ctx, table, before, downsample)
+	default:
+		return inBatches(ctx, batchSize, func() (int64, error) {
+			return repository.DeleteExpired(ctx, table, before, batchSize)
+		})
+	}
+}
+
+// sweepBlobs removes the blobs recorded before before that no bulletin
+// references, a batch at a time, and returns how many it removed. Each
+// batch's records are deleted in the transaction that removes the blobs from
+// the store, so a blob the store fails to remove is tried again next run.
+func sweepBlobs(ctx context.Context, before time.Time, batchSize int) (int64, error) {
+	store, err := blobStore()
+	if err != nil {
+		return 0, err
+	}
+	return inBatches(ctx, batchSize, func() (int64, error) {
+		var n int64
+		err := repository.InTx(ctx, func(ctx context.Context) error {
+			keys, err := repository.DeleteOrphanBlobs(ctx, before, batchSize)
+			if err != nil {
+				return err
+			}
+			for _, key := range keys {
+				if err := store.Delete(ctx, key); err != nil {
+					return fmt.Errorf("delete blob %s: %w", key, err)
+				}
+			}
+			n = int64(len(keys))
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		return n, nil
+	})
+}
+
+// rollupSpan is how much of a table rollup reduces at a time.
+const rollupSpan = 24 * time.Hour
+
+// rollup rolls the rows of table dated before before up to one row per
+// interval, a span at a time from the oldest bucket not yet rolled up, and
+// returns how many rows it deleted. before must fall on a bucket boundary.
+func rollup(ctx context.Context, table string, before time.Time, interval time.Duration) (int64, error) {
+	start, err := repository.OldestRollupPending(ctx, table, before, interval)
+	if err != nil || start == nil {
+		return 0, err
+	}
+	span := max(rollupSpan/interval, 1) * interval
+
+	var total int64
+	for from := *start; from.Before(before); from = from.Add(span) {
+		to := from.Add(span)
+		if to.After(before) {
+			to = before
+		}
+		n, err := repository.RollupRange(ctx, table, from, to, interval)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// inBatches calls del until it deletes less than a full batch and returns
+// the total deleted.
+func inBatches(ctx context.Context, batchSize int, del func() (int64, error)) (int64, error) {
+	var total int64
+	for {
+		n, err := del()
+		total += n
+		if err != nil || n < int64(batchSize) {
+			return total, err
+		}
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+	}
+}
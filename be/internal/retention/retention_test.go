@@ -0,0 +1,178 @@
+package retention
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pgxmock "github.com/pashagolub/pgxmock/v4"
+
+	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/db"
+	"github.com/lolwierd/weatherboy/be/internal/storage"
+)
+
+func TestRunDeletesInBatches(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mock.Close()
+	db.SetDBDriver(&db.Driver{ConnPool: mock})
+
+	now := time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC)
+	before := now.Add(-24 * time.Hour)
+	mock.ExpectExec("DELETE FROM imd_api_log").
+		WithArgs(before, 2).
+		WillReturnResult(pgxmock.NewResult("DELETE", 2))
+	mock.ExpectExec("DELETE FROM imd_api_log").
+		WithArgs(before, 2).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+	r := config.Retention{
+		BatchSize: 2,
+		Tables: map[string]config.TablePolicy{
+			"imd_api_log": {Keep: 24 * time.Hour},
+			"job_run":     {},
+		},
+	}
+	reports, err := Run(context.Background(), r, now, false)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(reports) != 1 || reports[0].Table != "imd_api_log" || reports[0].Rows != 3 {
+		t.Fatalf("reports = %+v", reports)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRunDryRunCounts(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mock.Close()
+	db.SetDBDriver(&db.Driver{ConnPool: mock})
+
+	now := time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC)
+	before := now.Add(-24 * time.Hour)
+	mock.ExpectQuery("SELECT count").
+		WithArgs(before, time.Hour).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(42)))
+
+	r := config.Retention{
+		BatchSize: 10,
+		Tables: map[string]config.TablePolicy{
+			"nowcast": {Keep: 24 * time.Hour, Downsample: time.Hour},
+		},
+	}
+	reports, err := Run(context.Background(), r, now, true)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(reports) != 1 || reports[0].Rows != 42 || reports[0].Downsample != "1h0m0s" {
+		t.Fatalf("reports = %+v", reports)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRunRollsUpBySpan(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mock.Close()
+	db.SetDBDriver(&db.Driver{ConnPool: mock})
+
+	now := time.Date(2024, 6, 20, 12, 40, 0, 0, time.UTC)
+	// The cutoff moves back to the start of its hour.
+	before := time.Date(2024, 6, 19, 12, 0, 0, 0, time.UTC)
+	start := time.Date(2024, 6, 18, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT min\\(date_bin").
+		WithArgs(before, time.Hour).
+		WillReturnRows(pgxmock.NewRows([]string{"min"}).AddRow(&start))
+	for i, span := range [][2]time.Time{{start, start.Add(24 * time.Hour)}, {start.Add(24 * time.Hour), before}} {
+		mock.ExpectBegin()
+		mock.ExpectExec("CREATE TEMP TABLE rollup_range ON COMMIT DROP AS SELECT id, captured_at AS bucket, 0::bigint AS n, pop, mm_per_hr FROM nowcast WITH NO DATA").
+			WillReturnResult(pgxmock.NewResult("CREATE TABLE", 0))
+		mock.ExpectExec("INSERT INTO rollup_range").
+			WithArgs(span[0], span[1], time.Hour).
+			WillReturnResult(pgxmock.NewResult("INSERT", 10))
+		mock.ExpectExec("DELETE FROM nowcast t USING rollup_range r").
+			WillReturnResult(pgxmock.NewResult("DELETE", int64(4+i)))
+		mock.ExpectExec("UPDATE nowcast t SET captured_at = r.bucket, pop = r.pop, mm_per_hr = r.mm_per_hr").
+			WillReturnResult(pgxmock.NewResult("UPDATE", 5))
+		mock.ExpectCommit()
+	}
+
+	r := config.Retention{
+		BatchSize: 10,
+		Tables: map[string]config.TablePolicy{
+			"nowcast": {Keep: 24 * time.Hour, Downsample: time.Hour},
+		},
+	}
+	reports, err := Run(context.Background(), r, now, false)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(reports) != 1 || reports[0].Rows != 9 || !reports[0].Before.Equal(before) {
+		t.Fatalf("reports = %+v", reports)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRunSweepsOrphanBlobs(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mock.Close()
+	db.SetDBDriver(&db.Driver{ConnPool: mock})
+
+	ctx := context.Background()
+	store := storage.NewLocal(t.TempDir())
+	saved := blobStore
+	t.Cleanup(func() { blobStore = saved })
+	blobStore = func() (storage.BlobStore, error) { return store, nil }
+
+	orphan := storage.NewBlob("bulletin", ".pdf", "application/pdf", []byte("orphan"))
+	if err := storage.PutContent(ctx, store, orphan, []byte("orphan")); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC)
+	before := now.Add(-24 * time.Hour)
+	mock.ExpectBegin()
+	mock.ExpectQuery("DELETE FROM blob WHERE key IN .* NOT EXISTS \\(SELECT 1 FROM bulletin_raw r WHERE r.blob_key = b.key\\)").
+		WithArgs(before, 10).
+		WillReturnRows(pgxmock.NewRows([]string{"key"}).AddRow(orphan.Key))
+	mock.ExpectCommit()
+
+	r := config.Retention{
+		BatchSize: 10,
+		Tables:    map[string]config.TablePolicy{"blob": {Keep: 24 * time.Hour}},
+	}
+	reports, err := Run(ctx, r, now, false)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(reports) != 1 || reports[0].Rows != 1 {
+		t.Fatalf("reports = %+v", reports)
+	}
+	if ok, err := store.Exists(ctx, orphan.Key); err != nil || ok {
+		t.Fatalf("orphan still stored: %v, %v", ok, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
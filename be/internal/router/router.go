@@ -8,10 +8,48 @@ func RegisterRoutes() {
 	App.Get("/health", handlers.Health)
 
 	v1 := App.Group("/v1")
+
+	// Batch risk. Without `locs`, /risk falls through to the coordinate
+	// route below.
+	v1.Get("/risk", handlers.GetRisks)
+	v1.Get("/risk/all", handlers.GetRiskAll)
+
+	// The same routes addressed by `lat` and `lon` instead of a slug. They
+	// are registered first so `/risk/history` is not read as a slug.
+	v1.Get("/risk", handlers.ResolveLocation, handlers.GetRisk)
+	v1.Get("/risk/history", handlers.ResolveLocation, handlers.GetRiskHistory)
+	v1.Get("/bulletin", handlers.ResolveLocation, handlers.GetBulletin)
+	v1.Get("/nowcast", handlers.ResolveLocation, handlers.GetNowcast)
+	v1.Get("/radar", handlers.ResolveLocation, handlers.GetRadar)
+	v1.Get("/riverbasin", handlers.ResolveLocation, handlers.GetRiverBasin)
+	v1.Get("/awsarg", handlers.ResolveLocation, handlers.GetAWSARG)
+	v1.Get("/awsarg/rainfall", handlers.ResolveLocation, handlers.GetAWSARGRainfall)
+	v1.Get("/awsarg/series", handlers.ResolveLocation, handlers.GetAWSARGSeries)
+	v1.Get("/summary", handlers.ResolveLocation, handlers.GetSummary)
+	v1.Get("/stream", handlers.ResolveLocation, handlers.GetStream)
+
 	v1.Get("/risk/:loc", handlers.GetRisk)
+	v1.Get("/risk/:loc/history", handlers.GetRiskHistory)
 	v1.Get("/bulletin/:loc", handlers.GetBulletin)
 	v1.Get("/nowcast/:loc", handlers.GetNowcast)
 	v1.Get("/radar/:loc", handlers.GetRadar)
 	v1.Get("/riverbasin/:loc", handlers.GetRiverBasin)
 	v1.Get("/awsarg/:loc", handlers.GetAWSARG)
+	v1.Get("/awsarg/:loc/rainfall", handlers.GetAWSARGRainfall)
+	v1.Get("/awsarg/:loc/series", handlers.GetAWSARGSeries)
+	v1.Get("/summary/:loc", handlers.GetSummary)
+	v1.Get("/stream/:loc", handlers.GetStream)
+	v1.Get("/districts/:id", handlers.GetDistrict)
+	v1.Get("/map/warnings", handlers.GetMapWarnings)
+	v1.Get("/map/stations", handlers.GetMapStations)
+
+	admin := v1.Group("/admin", handlers.AdminAuth)
+	admin.Get("/jobs", handlers.GetAdminJobs)
+	admin.Get("/jobs/:name/runs", handlers.GetAdminJobRuns)
+	admin.Post("/jobs/:name/run", handlers.PostAdminJobRun)
+	admin.Get("/locations", handlers.GetAdminLocations)
+	admin.Get("/locations/:slug", handlers.GetAdminLocation)
+	admin.Post("/locations", handlers.PostAdminLocation)
+	admin.Put("/locations/:slug", handlers.PutAdminLocation)
+	admin.Delete("/locations/:slug", handlers.DeleteAdminLocation)
 }
@@ -0,0 +1,45 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+)
+
+// Job is a unit of scheduled work.
+type Job interface {
+	// Name identifies the job in the registry and in `job_run`.
+	Name() string
+	// Spec is the cron spec the job runs on.
+	Spec() string
+	// Run does the work once.
+	Run(ctx context.Context) error
+}
+
+// funcJob adapts a function to Job.
+type funcJob struct {
+	name        string
+	spec        string
+	run         func(ctx context.Context) error
+	jitter      time.Duration
+	timeout     time.Duration
+	updatesRisk bool
+}
+
+func (j funcJob) Name() string                  { return j.name }
+func (j funcJob) Spec() string                  { return j.spec }
+func (j funcJob) Run(ctx context.Context) error { return j.run(ctx) }
+
+// Jitter is the upper bound of the random delay before a scheduled run.
+func (j funcJob) Jitter() time.Duration { return j.jitter }
+
+// Timeout bounds a single run. Zero means no timeout.
+func (j funcJob) Timeout() time.Duration { return j.timeout }
+
+// UpdatesRisk reports whether a successful run lands data that should trigger
+// a risk re-evaluation.
+func (j funcJob) UpdatesRisk() bool { return j.updatesRisk }
+
+// NewJob returns a Job running fn on the cron spec.
+func NewJob(name, spec string, fn func(ctx context.Context) error) Job {
+	return funcJob{name: name, spec: spec, run: fn}
+}
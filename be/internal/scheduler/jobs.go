@@ -2,166 +2,272 @@ package scheduler
 
 import (
 	"context"
-	"math/rand"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/robfig/cron/v3"
 
 	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/dryrun"
 	"github.com/lolwierd/weatherboy/be/internal/fetch"
 	"github.com/lolwierd/weatherboy/be/internal/logger"
+	"github.com/lolwierd/weatherboy/be/internal/rainfall"
 )
 
 var c *cron.Cron
 
-// Start initializes and starts all cron jobs.
-func Start() {
-	if c != nil {
-		return
-	}
-	// Cron in IST
-	loc, _ := time.LoadLocation("Asia/Kolkata")
-	c = cron.New(cron.WithLocation(loc))
+// sourceFuncs maps every configured source to the fetch run against its
+// targets.
+var sourceFuncs = map[string]func(ctx context.Context, targets []string) error{
+	"bulletin": func(ctx context.Context, _ []string) error {
+		return fetch.FetchBulletinOnce(ctx)
+	},
+	"nowcast":          forEachLocation(fetch.FetchIMDNowcast),
+	"district_warning": forEachLocation(fetch.FetchDistrictWarnings),
+	"radar":            forEachLocation(fetch.FetchRadarOnce),
+	"river_basin":      fetchRiverBasins,
+	"aws_arg":          fetchAWSARG,
+}
+
+// sourceOrder is the order jobs are registered and listed in.
+var sourceOrder = []string{"bulletin", "nowcast", "district_warning", "radar", "river_basin", "aws_arg"}
 
-	// Bulletin every day 18:30 IST
-	_, err := c.AddFunc("CRON_TZ=Asia/Kolkata 30 18 * * *", func() {
-		// jitter +/-30s
-		jitter := time.Duration(rand.Intn(60)-30) * time.Second
-		time.Sleep(jitter)
-		logger.Info.Println("cron: bulletin fetch")
-		if err := fetch.FetchBulletinOnce(context.Background()); err != nil {
-			logger.Error.Println("fetch bulletin:", err)
+// forEachLocation runs fn for each target location.
+func forEachLocation(fn func(ctx context.Context, loc config.Location) error) func(ctx context.Context, targets []string) error {
+	return func(ctx context.Context, targets []string) error {
+		var errs []error
+		for _, name := range targets {
+			loc, ok := config.LocationByName(name)
+			if !ok {
+				errs = append(errs, fmt.Errorf("location %s not found", name))
+				continue
+			}
+			if err := fn(ctx, loc); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
 		}
-	})
-	if err != nil {
-		logger.Error.Println("cron add bulletin:", err)
+		return errors.Join(errs...)
 	}
+}
 
-	// Nowcast every 15 minutes with jitter
-	_, err = c.AddFunc("CRON_TZ=Asia/Kolkata */15 * * * *", func() {
-		// jitter +/-30s
-		jitter := time.Duration(rand.Intn(60)-30) * time.Second
-		time.Sleep(jitter)
-		logger.Info.Println("cron: nowcast fetch")
-		if err := fetch.FetchIMDNowcast(context.Background()); err != nil {
-			logger.Error.Println("fetch nowcast:", err)
+func fetchRiverBasins(ctx context.Context, targets []string) error {
+	ids := make([]int, 0, len(targets))
+	for _, t := range targets {
+		id, err := strconv.Atoi(t)
+		if err != nil {
+			return fmt.Errorf("river basin id %q: %w", t, err)
 		}
-	})
-	if err != nil {
-		logger.Error.Println("cron add nowcast:", err)
+		ids = append(ids, id)
 	}
+	return fetch.FetchRiverBasins(ctx, ids)
+}
 
-	// District warnings every day 18:00 IST
-	_, err = c.AddFunc("CRON_TZ=Asia/Kolkata 0 18 * * *", func() {
-		// jitter +/-30s
-		jitter := time.Duration(rand.Intn(60)-30) * time.Second
-		time.Sleep(jitter)
-		logger.Info.Println("cron: district warning fetch")
-		if err := fetch.FetchDistrictWarnings(context.Background()); err != nil {
-			logger.Error.Println("fetch district warning:", err)
-		}
-	})
-	if err != nil {
-		logger.Error.Println("cron add district warning:", err)
+// fetchAWSARG fetches AWS/ARG observations and refreshes the rainfall
+// accumulations derived from them.
+func fetchAWSARG(ctx context.Context, targets []string) error {
+	if err := fetch.FetchAWSARG(ctx, targets); err != nil {
+		return err
+	}
+	if dryrun.Enabled(ctx) {
+		return nil
+	}
+	if err := rainfall.Update(ctx); err != nil {
+		logger.Error.Println("rainfall accumulations:", err)
 	}
+	return nil
+}
 
-	// Radar every 5 minutes with jitter
-	_, err = c.AddFunc("CRON_TZ=Asia/Kolkata */5 * * * *", func() {
-		// jitter +/-30s
-		jitter := time.Duration(rand.Intn(60)-30) * time.Second
-		time.Sleep(jitter)
-		logger.Info.Println("cron: radar fetch")
-		loc, ok := config.LocationByName("vadodara")
-		if !ok {
-			logger.Error.Println("location vadodara not found for radar fetch")
-			return
+// sourceJobs builds a job for every enabled source.
+func sourceJobs(sources map[string]config.Source) []Job {
+	var jobs []Job
+	for _, name := range sourceOrder {
+		s, ok := sources[name]
+		if !ok || !s.Enabled {
+			logger.Info.Println("source", name, "disabled")
+			continue
 		}
-		if err := fetch.FetchRadarOnce(context.Background(), loc); err != nil {
-			logger.Error.Println("fetch radar:", err)
-		}
-	})
-	if err != nil {
-		logger.Error.Println("cron add radar:", err)
+		fn, targets := sourceFuncs[name], s.Targets
+		jobs = append(jobs, funcJob{
+			name:        name,
+			spec:        s.Spec,
+			run:         func(ctx context.Context) error { return fn(ctx, targets) },
+			jitter:      s.Jitter,
+			timeout:     s.Timeout,
+			updatesRisk: true,
+		})
 	}
+	return jobs
+}
 
-	// River basin every day 19:00 IST
-	_, err = c.AddFunc("CRON_TZ=Asia/Kolkata 0 19 * * *", func() {
-		// jitter +/-30s
-		jitter := time.Duration(rand.Intn(60)-30) * time.Second
-		time.Sleep(jitter)
-		logger.Info.Println("cron: river basin fetch")
-		if err := fetch.FetchRiverBasinOnce(context.Background(), 1); err != nil {
-			logger.Error.Println("fetch river basin:", err)
-		}
-	})
+// loadJobs reads the sources config file and builds the jobs it enables.
+func loadJobs() ([]Job, error) {
+	path := config.SourcesFile()
+	sources, err := config.LoadSources(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := config.LoadRetention(path)
 	if err != nil {
-		logger.Error.Println("cron add river basin:", err)
+		return nil, err
 	}
 
-	// AWS/ARG every 30 minutes with jitter
-	_, err = c.AddFunc("CRON_TZ=Asia/Kolkata */30 * * * *", func() {
-		// jitter +/-30s
-		jitter := time.Duration(rand.Intn(60)-30) * time.Second
-		time.Sleep(jitter)
-		logger.Info.Println("cron: aws/arg fetch")
-		if err := fetch.FetchAWSARGOnce(context.Background(), "NDL"); err != nil {
-			logger.Error.Println("fetch aws/arg:", err)
-		}
-	})
+	jobs := sourceJobs(sources)
+	if r.Enabled {
+		jobs = append(jobs, retentionJob(r))
+	} else {
+		logger.Info.Println("retention disabled")
+	}
+	return jobs, nil
+}
+
+// Load reads the location table, validates the sources config and registers
+// a job for every enabled source and for retention without running any. The
+// config is reloaded on SIGHUP and when the location table changes.
+// API-only replicas load the registry so the admin API can list the jobs.
+func Load() {
+	if c != nil {
+		return
+	}
+	if err := RefreshLocations(context.Background()); err != nil {
+		logger.Warn.Println("load locations, serving the defaults:", err)
+	}
+	jobs, err := loadJobs()
 	if err != nil {
-		logger.Error.Println("cron add aws/arg:", err)
+		logger.Error.Fatalln("load sources config:", err)
 	}
 
-	c.Start()
-	for _, e := range c.Entries() {
-		logger.Info.Printf("cron next run %s\n", e.Next.Format(time.RFC3339))
+	// Cron in IST
+	loc, _ := time.LoadLocation("Asia/Kolkata")
+	c = cron.New(cron.WithLocation(loc))
+
+	if err := Replace(jobs); err != nil {
+		logger.Error.Println(err)
 	}
 
-	// run all jobs once at startup
+	var ctx context.Context
+	ctx, cancelWatch = context.WithCancel(context.Background())
+	watchers.Add(2)
 	go func() {
-		logger.Info.Println("initial bulletin fetch")
-		if err := fetch.FetchBulletinOnce(context.Background()); err != nil {
-			logger.Error.Println("fetch bulletin:", err)
-		}
+		defer watchers.Done()
+		reloadOnHangup(ctx)
 	}()
-
 	go func() {
-		logger.Info.Println("initial nowcast fetch")
-		if err := fetch.FetchIMDNowcast(context.Background()); err != nil {
-			logger.Error.Println("fetch nowcast:", err)
-		}
+		defer watchers.Done()
+		watchLocations(ctx)
 	}()
+}
 
-	go func() {
-		logger.Info.Println("initial district warning fetch")
-		if err := fetch.FetchDistrictWarnings(context.Background()); err != nil {
-			logger.Error.Println("fetch district warning:", err)
-		}
-	}()
+// Start loads the jobs, starts the cron and campaigns for leadership. Only the
+// leader replica runs jobs; it runs each once when elected.
+func Start() {
+	Load()
+	if cancelElection != nil {
+		return
+	}
+	var ctx context.Context
+	ctx, cancelElection = context.WithCancel(context.Background())
 
-	go func() {
-		logger.Info.Println("initial radar fetch")
-		loc, ok := config.LocationByName("vadodara")
+	c.Start()
+	logNextRuns()
+
+	go elect(ctx, runAll)
+}
+
+// Stop stops watching for config changes, stops scheduling, waits for running
+// jobs and gives up leadership.
+func Stop() {
+	if c == nil {
+		return
+	}
+	cancelWatch()
+	watchers.Wait()
+	if cancelElection == nil {
+		return
+	}
+	<-c.Stop().Done()
+	initialMu.Lock()
+	stopped = true
+	initialMu.Unlock()
+	initialRuns.Wait()
+	cancelElection()
+}
+
+var (
+	cancelElection context.CancelFunc
+
+	// cancelWatch ends the SIGHUP and location table watchers Load starts.
+	cancelWatch context.CancelFunc
+	watchers    sync.WaitGroup
+
+	// initialRuns tracks the runs started on election, which cron does not
+	// wait for. No run starts once stopped is set.
+	initialMu   sync.Mutex
+	initialRuns sync.WaitGroup
+	stopped     bool
+)
+
+// runAll runs every registered job once in the background.
+func runAll() {
+	initialMu.Lock()
+	defer initialMu.Unlock()
+	if stopped {
+		return
+	}
+	for _, info := range Jobs() {
+		j, ok := Lookup(info.Name)
 		if !ok {
-			logger.Error.Println("location vadodara not found for initial radar fetch")
-			return
+			continue
 		}
-		if err := fetch.FetchRadarOnce(context.Background(), loc); err != nil {
-			logger.Error.Println("fetch radar:", err)
-		}
-	}()
+		initialRuns.Add(1)
+		go func() {
+			defer initialRuns.Done()
+			logger.Info.Println("initial", j.Name(), "run")
+			runJob(context.Background(), j)
+		}()
+	}
+}
 
-	go func() {
-		logger.Info.Println("initial river basin fetch")
-		if err := fetch.FetchRiverBasinOnce(context.Background(), 1); err != nil {
-			logger.Error.Println("fetch river basin:", err)
-		}
-	}()
+// Reload re-reads the sources config and re-registers every job. The running
+// jobs are left untouched when the config is invalid.
+func Reload() error {
+	jobs, err := loadJobs()
+	if err != nil {
+		return err
+	}
+	if err := Replace(jobs); err != nil {
+		return err
+	}
+	logNextRuns()
+	return nil
+}
 
-	go func() {
-		logger.Info.Println("initial aws/arg fetch")
-		if err := fetch.FetchAWSARGOnce(context.Background(), "NDL"); err != nil {
-			logger.Error.Println("fetch aws/arg:", err)
+// reloadOnHangup reloads the sources config on every SIGHUP until ctx is
+// done.
+func reloadOnHangup(ctx context.Context) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	defer signal.Stop(sig)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sig:
+			logger.Info.Println("SIGHUP: reloading sources config")
+			if err := Reload(); err != nil {
+				logger.Error.Println("reload sources config:", err)
+			}
 		}
-	}()
+	}
+}
+
+func logNextRuns() {
+	for _, j := range Jobs() {
+		logger.Info.Printf("cron %s next run %s\n", j.Name, j.NextRun.Format(time.RFC3339))
+	}
 }
@@ -0,0 +1,97 @@
+package scheduler
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/lolwierd/weatherboy/be/internal/db"
+	"github.com/lolwierd/weatherboy/be/internal/logger"
+)
+
+// leaderLockKey is the Postgres advisory lock held by the replica that runs
+// the scheduled jobs.
+const leaderLockKey int64 = 0x77656174686572 // "weather"
+
+// electionInterval is how often a follower retries the lock and the leader
+// checks it still holds its connection.
+const electionInterval = 15 * time.Second
+
+var leader atomic.Bool
+
+// IsLeader reports whether this replica currently runs the scheduled jobs.
+func IsLeader() bool {
+	return leader.Load()
+}
+
+// elect campaigns for leadership until ctx is done. The advisory lock is tied
+// to the session, so a leader that crashes or loses its connection releases
+// it to the next replica. onElected runs each time this replica becomes
+// leader.
+func elect(ctx context.Context, onElected func()) {
+	for {
+		conn, err := tryLead(ctx)
+		if err != nil {
+			logger.Error.Println("leader election:", err)
+		}
+		if conn != nil {
+			logger.Info.Println("elected scheduler leader")
+			leader.Store(true)
+			onElected()
+			hold(ctx, conn)
+			leader.Store(false)
+			logger.Warn.Println("lost scheduler leadership")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(electionInterval):
+		}
+	}
+}
+
+// tryLead returns a connection holding the leader lock, or nil when another
+// replica holds it.
+func tryLead(ctx context.Context) (*pgxpool.Conn, error) {
+	conn, err := db.GetDBDriver().ConnPool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ok bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", leaderLockKey).Scan(&ok); err != nil {
+		conn.Release()
+		return nil, err
+	}
+	if !ok {
+		conn.Release()
+		return nil, nil
+	}
+	return conn, nil
+}
+
+// hold keeps the leader connection alive until ctx is done or the connection
+// fails, then releases the lock.
+func hold(ctx context.Context, conn *pgxpool.Conn) {
+	defer func() {
+		// The lock is tied to the session, so a broken connection has already
+		// dropped it; destroy rather than return it to the pool.
+		conn.Hijack().Close(context.Background())
+	}()
+
+	t := time.NewTicker(electionInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := conn.Ping(ctx); err != nil {
+				logger.Error.Println("leader connection:", err)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/logger"
+	"github.com/lolwierd/weatherboy/be/internal/repository"
+)
+
+// locationPollInterval is how often every replica re-reads the location
+// table, so locations changed through another replica's admin API are picked
+// up without a redeploy.
+const locationPollInterval = time.Minute
+
+// RefreshLocations reads the location table and, when it changed, serves its
+// locations and reloads the jobs whose targets derive from them.
+func RefreshLocations(ctx context.Context) error {
+	ls, err := repository.ListLocations(ctx)
+	if err != nil {
+		return err
+	}
+	if !config.SetLocations(ls) {
+		return nil
+	}
+	logger.Info.Printf("locations changed: serving %d locations", len(ls))
+	if c == nil {
+		return nil
+	}
+	return Reload()
+}
+
+// watchLocations refreshes the locations every locationPollInterval until
+// ctx is done.
+func watchLocations(ctx context.Context) {
+	t := time.NewTicker(locationPollInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			refreshCtx, cancel := context.WithTimeout(ctx, locationPollInterval)
+			if err := RefreshLocations(refreshCtx); err != nil {
+				logger.Error.Println("refresh locations:", err)
+			}
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// JobInfo describes a registered job.
+type JobInfo struct {
+	Name    string
+	Spec    string
+	NextRun time.Time
+}
+
+type entry struct {
+	job Job
+	id  cron.EntryID
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]*entry{}
+	order    []string
+
+	// running holds the jobs with a run in flight on this replica. It is kept
+	// apart from the registry so a reload does not let a job start twice.
+	runningMu sync.Mutex
+	running   = map[string]bool{}
+)
+
+// startRun marks the job name as running and reports whether it was idle.
+func startRun(name string) bool {
+	runningMu.Lock()
+	defer runningMu.Unlock()
+	if running[name] {
+		return false
+	}
+	running[name] = true
+	return true
+}
+
+// endRun marks the job name as idle again.
+func endRun(name string) {
+	runningMu.Lock()
+	defer runningMu.Unlock()
+	delete(running, name)
+}
+
+// Register adds a job to the registry and schedules it on the running cron.
+func Register(j Job) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := registry[j.Name()]; ok {
+		return fmt.Errorf("job %s already registered", j.Name())
+	}
+	id, err := c.AddFunc(j.Spec(), func() { runScheduled(j) })
+	if err != nil {
+		return fmt.Errorf("cron add %s: %w", j.Name(), err)
+	}
+	registry[j.Name()] = &entry{job: j, id: id}
+	order = append(order, j.Name())
+	return nil
+}
+
+// Replace unregisters every job and registers jobs in their place. Runs that
+// are in flight finish normally. Nothing changes when a job's spec does not
+// parse.
+func Replace(jobs []Job) error {
+	for _, j := range jobs {
+		if _, err := cron.ParseStandard(j.Spec()); err != nil {
+			return fmt.Errorf("job %s: %w", j.Name(), err)
+		}
+	}
+
+	mu.Lock()
+	for _, e := range registry {
+		c.Remove(e.id)
+	}
+	registry = map[string]*entry{}
+	order = nil
+	mu.Unlock()
+
+	var errs []error
+	for _, j := range jobs {
+		if err := Register(j); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Lookup returns the registered job with the given name.
+func Lookup(name string) (Job, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	e, ok := registry[name]
+	if !ok {
+		return nil, false
+	}
+	return e.job, true
+}
+
+// Jobs lists the registered jobs in registration order.
+func Jobs() []JobInfo {
+	mu.RLock()
+	defer mu.RUnlock()
+	list := make([]JobInfo, 0, len(order))
+	for _, name := range order {
+		e := registry[name]
+		info := JobInfo{Name: name, Spec: e.job.Spec()}
+		// The cron of an API-only replica never starts, so fall back
+		// to the schedule when the entry has no next run.
+		if ce := c.Entry(e.id); !ce.Next.IsZero() {
+			info.NextRun = ce.Next
+		} else if ce.Schedule != nil {
+			info.NextRun = ce.Schedule.Next(time.Now())
+		}
+		list = append(list, info)
+	}
+	return list
+}
@@ -0,0 +1,33 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/dryrun"
+	"github.com/lolwierd/weatherboy/be/internal/logger"
+	"github.com/lolwierd/weatherboy/be/internal/retention"
+)
+
+// retentionJob prunes the tables with a retention policy. Under a dry run it
+// only reports what it would remove.
+func retentionJob(r config.Retention) Job {
+	return funcJob{
+		name:    "retention",
+		spec:    r.Spec,
+		timeout: r.Timeout,
+		run: func(ctx context.Context) error {
+			dryRun := dryrun.Enabled(ctx)
+			reports, err := retention.Run(ctx, r, time.Now(), dryRun)
+			for _, rep := range reports {
+				if dryRun {
+					dryrun.Collect(ctx, rep)
+					continue
+				}
+				logger.Info.Printf("retention %s: removed %d rows before %s", rep.Table, rep.Rows, rep.Before.Format(time.RFC3339))
+			}
+			return err
+		},
+	}
+}
@@ -0,0 +1,30 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+
+	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/logger"
+	"github.com/lolwierd/weatherboy/be/internal/score"
+)
+
+// riskMu serialises risk evaluation so concurrent fetches do not record the
+// same level transition twice.
+var riskMu sync.Mutex
+
+// recordRisk evaluates and stores the risk level for every location. It is
+// run after each successful fetch so the history tracks new data as it lands.
+func recordRisk(ctx context.Context) {
+	riskMu.Lock()
+	defer riskMu.Unlock()
+
+	for _, l := range config.Locations() {
+		res, err := score.RecordRiskLevel(ctx, l.Name)
+		if err != nil {
+			logger.Error.Println("record risk", l.Name+":", err)
+			continue
+		}
+		logger.Info.Printf("risk %s level=%s score=%.2f", l.Name, res.Level, res.Score)
+	}
+}
@@ -0,0 +1,137 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"time"
+
+	"github.com/lolwierd/weatherboy/be/internal/dryrun"
+	"github.com/lolwierd/weatherboy/be/internal/jobstats"
+	"github.com/lolwierd/weatherboy/be/internal/logger"
+	"github.com/lolwierd/weatherboy/be/internal/model"
+	"github.com/lolwierd/weatherboy/be/internal/repository"
+)
+
+// runScheduled runs a job fired by cron after a random delay of up to the
+// job's jitter. Followers skip the run; the leader replica runs it.
+func runScheduled(j Job) {
+	if !IsLeader() {
+		return
+	}
+	if t, ok := j.(interface{ Jitter() time.Duration }); ok && t.Jitter() > 0 {
+		time.Sleep(time.Duration(rand.Int63n(int64(t.Jitter()))))
+	}
+	logger.Info.Println("cron:", j.Name())
+	runJob(context.Background(), j)
+}
+
+// tryLock takes a Postgres advisory lock; tests replace it.
+var tryLock = repository.TryLock
+
+// jobLockKey is the advisory lock a replica holds while running the job name.
+func jobLockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("job:" + name))
+	return int64(h.Sum64())
+}
+
+// runJob runs a job once, records the run in `job_run` and re-evaluates risk
+// when the job landed new data. It returns ErrJobRunning without running the
+// job when a run of it is already in flight on any replica.
+func runJob(ctx context.Context, j Job) (model.JobRun, error) {
+	if !startRun(j.Name()) {
+		logger.Warn.Println("job", j.Name(), "already running, skipped")
+		return model.JobRun{}, fmt.Errorf("%w: %s", ErrJobRunning, j.Name())
+	}
+	defer endRun(j.Name())
+	// Manual runs skip leader election, so the job's lock keeps them from
+	// overlapping a run on another replica.
+	unlock, ok, err := tryLock(ctx, jobLockKey(j.Name()))
+	if err != nil {
+		logger.Error.Println("lock job", j.Name()+":", err)
+		return model.JobRun{}, fmt.Errorf("lock job %s: %w", j.Name(), err)
+	}
+	if !ok {
+		logger.Warn.Println("job", j.Name(), "running on another replica, skipped")
+		return model.JobRun{}, fmt.Errorf("%w: %s", ErrJobRunning, j.Name())
+	}
+	defer unlock()
+
+	run := model.JobRun{JobName: j.Name(), StartedAt: time.Now(), Status: model.JobRunRunning}
+	if err := repository.InsertJobRun(ctx, &run); err != nil {
+		logger.Error.Println("insert job run", j.Name()+":", err)
+	}
+
+	runCtx, stats := jobstats.NewContext(ctx)
+	runCtx, cancel := withJobTimeout(runCtx, j)
+	defer cancel()
+	err = j.Run(runCtx)
+
+	finished := time.Now()
+	run.FinishedAt = &finished
+	run.Bytes = stats.Bytes()
+	run.RowsWritten = stats.Rows()
+	run.Status = model.JobRunSuccess
+	if err != nil {
+		logger.Error.Println("job", j.Name()+":", err)
+		msg := err.Error()
+		run.Status = model.JobRunFailure
+		run.Error = &msg
+	}
+	if run.ID != 0 {
+		if err := repository.FinishJobRun(ctx, &run); err != nil {
+			logger.Error.Println("finish job run", j.Name()+":", err)
+		}
+	}
+
+	if r, ok := j.(interface{ UpdatesRisk() bool }); err == nil && ok && r.UpdatesRisk() {
+		recordRisk(ctx)
+	}
+	return run, err
+}
+
+var (
+	// ErrUnknownJob is returned when no job is registered under a name.
+	ErrUnknownJob = errors.New("unknown job")
+	// ErrJobRunning is returned when a job is started while a run of it is in
+	// flight.
+	ErrJobRunning = errors.New("job already running")
+)
+
+// RunNow runs a registered job once outside its schedule and records the run
+// like a scheduled one. It fails with ErrJobRunning while a scheduled or manual
+// run of the job is in flight on any replica.
+func RunNow(ctx context.Context, name string) (model.JobRun, error) {
+	j, ok := Lookup(name)
+	if !ok {
+		return model.JobRun{}, fmt.Errorf("%w %s", ErrUnknownJob, name)
+	}
+	logger.Info.Println("manual run:", name)
+	return runJob(ctx, j)
+}
+
+// DryRun runs a registered job once without storing anything and returns the
+// rows it parsed.
+func DryRun(ctx context.Context, name string) ([]any, error) {
+	j, ok := Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrUnknownJob, name)
+	}
+	logger.Info.Println("dry run:", name)
+	ctx, d := dryrun.Start(ctx)
+	ctx, cancel := withJobTimeout(ctx, j)
+	defer cancel()
+	err := j.Run(ctx)
+	return d.Results(), err
+}
+
+// withJobTimeout bounds ctx by the job's timeout, if it has one.
+func withJobTimeout(ctx context.Context, j Job) (context.Context, context.CancelFunc) {
+	if t, ok := j.(interface{ Timeout() time.Duration }); ok && t.Timeout() > 0 {
+		return context.WithTimeout(ctx, t.Timeout())
+	}
+	return context.WithCancel(ctx)
+}
@@ -0,0 +1,170 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	pgxmock "github.com/pashagolub/pgxmock/v4"
+	"github.com/robfig/cron/v3"
+
+	"github.com/lolwierd/weatherboy/be/internal/db"
+	"github.com/lolwierd/weatherboy/be/internal/dryrun"
+)
+
+// register replaces the registry with jobs on a cron that never starts.
+func register(t *testing.T, jobs ...Job) {
+	t.Helper()
+	c = cron.New()
+	if err := Replace(jobs); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// stubLocks replaces the advisory locks with locks held in memory. A test
+// plays another replica by taking a job's lock itself.
+func stubLocks(t *testing.T) {
+	saved := tryLock
+	t.Cleanup(func() { tryLock = saved })
+	var mu sync.Mutex
+	held := map[int64]bool{}
+	tryLock = func(ctx context.Context, key int64) (func(), bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if held[key] {
+			return nil, false, nil
+		}
+		held[key] = true
+		return func() {
+			mu.Lock()
+			defer mu.Unlock()
+			delete(held, key)
+		}, true, nil
+	}
+}
+
+func TestRunNowWhileRunning(t *testing.T) {
+	stubLocks(t)
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mock.Close()
+	db.SetDBDriver(&db.Driver{ConnPool: mock})
+	mock.ExpectQuery("INSERT INTO job_run").
+		WithArgs("slow", pgxmock.AnyArg(), "running").
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("UPDATE job_run").
+		WithArgs(1, pgxmock.AnyArg(), "success", pgxmock.AnyArg(), int64(0), int64(0)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	started, release := make(chan struct{}), make(chan struct{})
+	register(t, NewJob("slow", "@hourly", func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	}))
+
+	done := make(chan error)
+	go func() {
+		_, err := RunNow(context.Background(), "slow")
+		done <- err
+	}()
+	<-started
+	if _, err := RunNow(context.Background(), "slow"); !errors.Is(err, ErrJobRunning) {
+		t.Fatalf("second run: got %v, want ErrJobRunning", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRunNowLockedByAnotherReplica(t *testing.T) {
+	stubLocks(t)
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mock.Close()
+	db.SetDBDriver(&db.Driver{ConnPool: mock})
+
+	runs := 0
+	register(t, NewJob("fetch", "@hourly", func(ctx context.Context) error {
+		runs++
+		return nil
+	}))
+
+	unlock, ok, err := tryLock(context.Background(), jobLockKey("fetch"))
+	if err != nil || !ok {
+		t.Fatalf("lock: %v, %v", ok, err)
+	}
+	if _, err := RunNow(context.Background(), "fetch"); !errors.Is(err, ErrJobRunning) {
+		t.Fatalf("run while locked: got %v, want ErrJobRunning", err)
+	}
+	if runs != 0 {
+		t.Fatal("job ran while another replica held its lock")
+	}
+
+	// Once the other replica is done, the job runs and gives the lock back.
+	unlock()
+	mock.ExpectQuery("INSERT INTO job_run").
+		WithArgs("fetch", pgxmock.AnyArg(), "running").
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("UPDATE job_run").
+		WithArgs(1, pgxmock.AnyArg(), "success", pgxmock.AnyArg(), int64(0), int64(0)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	if _, err := RunNow(context.Background(), "fetch"); err != nil || runs != 1 {
+		t.Fatalf("run after unlock: %v, %d runs", err, runs)
+	}
+	if unlock, ok, _ := tryLock(context.Background(), jobLockKey("fetch")); !ok {
+		t.Error("job lock still held after the run")
+	} else {
+		unlock()
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestDryRunCollects(t *testing.T) {
+	register(t, NewJob("parse", "@hourly", func(ctx context.Context) error {
+		dryrun.Collect(ctx, "row 1")
+		dryrun.Collect(ctx, "row 2")
+		return nil
+	}))
+
+	results, err := DryRun(context.Background(), "parse")
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if len(results) != 2 || results[0] != "row 1" || results[1] != "row 2" {
+		t.Fatalf("results = %v", results)
+	}
+	if _, err := DryRun(context.Background(), "missing"); !errors.Is(err, ErrUnknownJob) {
+		t.Fatalf("unknown job: got %v, want ErrUnknownJob", err)
+	}
+}
+
+func TestWatchersEndWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		reloadOnHangup(ctx)
+		watchLocations(ctx)
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("watchers still running after their context ended")
+	}
+}
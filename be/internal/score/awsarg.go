@@ -0,0 +1,131 @@
+package score
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/lolwierd/weatherboy/be/internal/model"
+)
+
+// Aggregation selects how observations from several AWS/ARG stations are
+// combined into one.
+type Aggregation string
+
+const (
+	AggMax  Aggregation = "max"
+	AggMean Aggregation = "mean"
+	// AggIDW weights each station by the inverse square of its distance.
+	AggIDW Aggregation = "idw"
+)
+
+// minIDWDistanceKM keeps a station sitting on the location from taking all
+// of the inverse-distance weight.
+const minIDWDistanceKM = 0.5
+
+// ParseAggregation validates an aggregation name.
+func ParseAggregation(s string) (Aggregation, error) {
+	switch a := Aggregation(s); a {
+	case AggMax, AggMean, AggIDW:
+		return a, nil
+	default:
+		return "", fmt.Errorf("unknown aggregation %q", s)
+	}
+}
+
+// AggregateAWSARG combines station observations into a single observation.
+// Identifying fields, wind direction and the observation time come from the
+// closest station; numeric readings are combined with agg over the stations
+// that reported them. QC flags stay on the individual observations.
+func AggregateAWSARG(obs []model.AWSStationObservation, agg Aggregation) (*model.AWSARG, error) {
+	if len(obs) == 0 {
+		return nil, fmt.Errorf("no observations to aggregate")
+	}
+	nearest := obs[0]
+	for _, o := range obs[1:] {
+		if o.DistanceKM < nearest.DistanceKM {
+			nearest = o
+		}
+	}
+	out := nearest.Observation
+	out.QC = nil
+
+	fields := []func(a *model.AWSARG) **float64{
+		func(a *model.AWSARG) **float64 { return &a.CurrentTemp },
+		func(a *model.AWSARG) **float64 { return &a.DewPointTemp },
+		func(a *model.AWSARG) **float64 { return &a.RH },
+		func(a *model.AWSARG) **float64 { return &a.WindSpeed },
+		func(a *model.AWSARG) **float64 { return &a.MSLP },
+		func(a *model.AWSARG) **float64 { return &a.MinTemp },
+		func(a *model.AWSARG) **float64 { return &a.MaxTemp },
+		func(a *model.AWSARG) **float64 { return &a.Nebulosity },
+		func(a *model.AWSARG) **float64 { return &a.FeelLike },
+		func(a *model.AWSARG) **float64 { return &a.Rainfall },
+	}
+	for _, f := range fields {
+		*f(&out) = combine(obs, f, agg)
+	}
+	return &out, nil
+}
+
+// AggregateRainfall combines station rainfall accumulations with agg.
+func AggregateRainfall(rs []model.AWSStationRainfall, agg Aggregation) (float64, error) {
+	vals := make([]*float64, len(rs))
+	dists := make([]float64, len(rs))
+	for i := range rs {
+		vals[i] = &rs[i].Rainfall.MM
+		dists[i] = rs[i].DistanceKM
+	}
+	v := combineValues(vals, dists, agg)
+	if v == nil {
+		return 0, fmt.Errorf("no rainfall to aggregate")
+	}
+	return *v, nil
+}
+
+// combine aggregates one field over the observations that reported it and
+// returns nil when none did.
+func combine(obs []model.AWSStationObservation, get func(a *model.AWSARG) **float64, agg Aggregation) *float64 {
+	vals := make([]*float64, len(obs))
+	dists := make([]float64, len(obs))
+	for i := range obs {
+		vals[i] = *get(&obs[i].Observation)
+		dists[i] = obs[i].DistanceKM
+	}
+	return combineValues(vals, dists, agg)
+}
+
+// combineValues aggregates the non-nil values, each at the matching distance,
+// and returns nil when there are none.
+func combineValues(vals []*float64, dists []float64, agg Aggregation) *float64 {
+	var v, wsum float64
+	n := 0
+	for i, p := range vals {
+		if p == nil {
+			continue
+		}
+		switch agg {
+		case AggMax:
+			if n == 0 || *p > v {
+				v = *p
+			}
+		case AggIDW:
+			d := math.Max(dists[i], minIDWDistanceKM)
+			w := 1 / (d * d)
+			v += w * *p
+			wsum += w
+		default:
+			v += *p
+		}
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	switch agg {
+	case AggIDW:
+		v /= wsum
+	case AggMean:
+		v /= float64(n)
+	}
+	return &v
+}
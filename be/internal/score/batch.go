@@ -0,0 +1,60 @@
+package score
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/repository"
+)
+
+// batchConcurrency bounds the locations scored at once by RiskLevels.
+const batchConcurrency = 8
+
+// RiskLevels computes the risk level of each location concurrently. Lookups
+// shared between locations run once. A location that cannot be scored is
+// reported in errs instead of failing the others.
+func RiskLevels(ctx context.Context, locs []string) (results map[string]Result, errs map[string]error) {
+	return riskLevels(repository.WithCache(ctx), repo, locs)
+}
+
+func riskLevels(ctx context.Context, r Repo, locs []string) (map[string]Result, map[string]error) {
+	results := make(map[string]Result, len(locs))
+	errs := make(map[string]error)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, batchConcurrency)
+
+	seen := make(map[string]bool, len(locs))
+	for _, loc := range locs {
+		if seen[loc] {
+			continue
+		}
+		seen[loc] = true
+		if _, ok := config.LocationByName(loc); !ok {
+			errs[loc] = fmt.Errorf("%w: %s", repository.ErrUnknownLocation, loc)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			res, err := riskLevel(ctx, r, loc)
+			if err == nil {
+				err = ctx.Err()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[loc] = err
+				return
+			}
+			results[loc] = res
+		}()
+	}
+	wg.Wait()
+	return results, errs
+}
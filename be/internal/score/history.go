@@ -0,0 +1,67 @@
+package score
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/lolwierd/weatherboy/be/internal/events"
+	"github.com/lolwierd/weatherboy/be/internal/model"
+	"github.com/lolwierd/weatherboy/be/internal/repository"
+)
+
+// RecordRiskLevel computes the risk level for a location, stores it in the
+// risk history and records a transition when the level differs from the
+// previously stored one.
+func RecordRiskLevel(ctx context.Context, loc string) (Result, error) {
+	res, err := RiskLevel(ctx, loc)
+	if err != nil {
+		return res, err
+	}
+
+	prev, err := repository.LatestRiskHistory(ctx, loc)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return res, err
+	}
+
+	cur := model.RiskHistory{
+		Location:   loc,
+		Level:      res.Level,
+		Score:      res.Score,
+		Breakdown:  res.Breakdown,
+		ComputedAt: time.Now(),
+	}
+	if err := repository.InsertRiskHistory(ctx, &cur); err != nil {
+		return res, err
+	}
+
+	if t := transition(prev, cur); t != nil {
+		if err := repository.InsertRiskTransition(ctx, t); err != nil {
+			return res, err
+		}
+		events.Publish(ctx, events.Event{
+			Type:     events.TypeRisk,
+			Location: loc,
+			At:       t.TransitionedAt,
+			Data:     map[string]any{"from_level": t.FromLevel, "level": t.ToLevel, "score": t.Score},
+		})
+	}
+	return res, nil
+}
+
+// transition returns the transition from prev to cur, or nil when the level
+// is unchanged or there is no previous level to compare against.
+func transition(prev *model.RiskHistory, cur model.RiskHistory) *model.RiskTransition {
+	if prev == nil || prev.Level == cur.Level {
+		return nil
+	}
+	return &model.RiskTransition{
+		Location:       cur.Location,
+		FromLevel:      prev.Level,
+		ToLevel:        cur.Level,
+		Score:          cur.Score,
+		TransitionedAt: cur.ComputedAt,
+	}
+}
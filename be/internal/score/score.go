@@ -4,8 +4,10 @@ import (
 	"context"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/lolwierd/weatherboy/be/internal/model"
+	"github.com/lolwierd/weatherboy/be/internal/rainfall"
 	"github.com/lolwierd/weatherboy/be/internal/repository"
 )
 
@@ -13,11 +15,13 @@ import (
 type Repo interface {
 	LatestBulletin(ctx context.Context, loc string) (*model.Bulletin, error)
 	LatestRadarSnapshot(ctx context.Context, loc string) (*model.RadarSnapshot, error)
-	NowcastPOP1H(ctx context.Context, loc string) (float64, error)
-	LatestNowcastCategories(ctx context.Context, loc string) (map[int]int16, error)
+	NowcastPOP1H(ctx context.Context, loc string) (float64, time.Time, error)
+	LatestNowcastCategories(ctx context.Context, loc string) (map[int]int16, time.Time, error)
 	LatestDistrictWarning(ctx context.Context, loc string) (*model.DistrictWarning, error)
 	LatestRiverBasinQPF(ctx context.Context, loc string) (*model.RiverBasinQPF, error)
-	LatestAWSARG(ctx context.Context, loc string) (*model.AWSARG, error)
+	// AWSRainfall returns the rain over window near loc and the end of the
+	// oldest accumulation it was taken from.
+	AWSRainfall(ctx context.Context, loc string, window rainfall.Window) (float64, time.Time, error)
 }
 
 // repo is the default backing repo used in production.
@@ -34,20 +38,35 @@ func (dbRepo) LatestBulletin(ctx context.Context, loc string) (*model.Bulletin,
 func (dbRepo) LatestRadarSnapshot(ctx context.Context, loc string) (*model.RadarSnapshot, error) {
 	return repository.LatestRadarSnapshot(ctx, loc)
 }
-func (dbRepo) NowcastPOP1H(ctx context.Context, loc string) (float64, error) {
+func (dbRepo) NowcastPOP1H(ctx context.Context, loc string) (float64, time.Time, error) {
 	return repository.NowcastPOP1H(ctx, loc)
 }
-func (dbRepo) LatestNowcastCategories(ctx context.Context, loc string) (map[int]int16, error) {
+func (dbRepo) LatestNowcastCategories(ctx context.Context, loc string) (map[int]int16, time.Time, error) {
 	return repository.LatestNowcastCategories(ctx, loc)
 }
 func (dbRepo) LatestDistrictWarning(ctx context.Context, loc string) (*model.DistrictWarning, error) {
 	return repository.LatestDistrictWarning(ctx, loc)
 }
 func (dbRepo) LatestRiverBasinQPF(ctx context.Context, loc string) (*model.RiverBasinQPF, error) {
-	return repository.LatestRiverBasinQPF(ctx, loc)
+	return repository.LatestRiverBasinQPFForLocation(ctx, loc)
 }
-func (dbRepo) LatestAWSARG(ctx context.Context, loc string) (*model.AWSARG, error) {
-	return repository.LatestAWSARG(ctx, loc)
+func (dbRepo) AWSRainfall(ctx context.Context, loc string, window rainfall.Window) (float64, time.Time, error) {
+	rs, err := repository.AWSRainfallForLocation(ctx, loc, string(window))
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	// Take the wettest nearby station so a local downpour is not averaged away.
+	mm, err := AggregateRainfall(rs, AggMax)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	endsAt := rs[0].Rainfall.EndsAt
+	for _, r := range rs[1:] {
+		if r.Rainfall.EndsAt.Before(endsAt) {
+			endsAt = r.Rainfall.EndsAt
+		}
+	}
+	return mm, endsAt, nil
 }
 
 // Result is the risk score output.
@@ -55,6 +74,16 @@ type Result struct {
 	Level     string             `json:"level"`
 	Score     float64            `json:"score"`
 	Breakdown map[string]float64 `json:"breakdown"`
+	// AsOf is the time of the oldest input the score was computed from,
+	// zero when no input was available.
+	AsOf time.Time `json:"-"`
+}
+
+// used records that the score was computed from an input of time t.
+func (r *Result) used(t time.Time) {
+	if r.AsOf.IsZero() || t.Before(r.AsOf) {
+		r.AsOf = t
+	}
 }
 
 // RiskLevel computes the risk level for a location.
@@ -66,6 +95,7 @@ func riskLevel(ctx context.Context, r Repo, loc string) (Result, error) {
 	res := Result{Breakdown: map[string]float64{}}
 
 	if b, err := r.LatestBulletin(ctx, loc); err == nil {
+		res.used(b.IssuedAt)
 		txt := strings.ToLower(b.Text)
 		if strings.Contains(txt, "heavy") {
 			res.Score += 0.4
@@ -74,20 +104,23 @@ func riskLevel(ctx context.Context, r Repo, loc string) (Result, error) {
 	}
 
 	if rad, err := r.LatestRadarSnapshot(ctx, loc); err == nil {
+		res.used(rad.CapturedAt)
 		if rad.MaxDBZ >= 45 {
 			res.Score += 0.4
 			res.Breakdown["radar"] = 0.4
 		}
 	}
 
-	if pop, err := r.NowcastPOP1H(ctx, loc); err == nil {
+	if pop, capturedAt, err := r.NowcastPOP1H(ctx, loc); err == nil {
+		res.used(capturedAt)
 		if pop >= 0.7 {
 			res.Score += 0.2
 			res.Breakdown["nowcast"] = 0.2
 		}
 	}
 
-	if cats, err := r.LatestNowcastCategories(ctx, loc); err == nil {
+	if cats, capturedAt, err := r.LatestNowcastCategories(ctx, loc); err == nil {
+		res.used(capturedAt)
 		catScore := 0.0
 		severeMap := map[int]float64{2: 0.1, 3: 0.1}
 		alertCats := []int{13, 14, 19}
@@ -114,6 +147,7 @@ func riskLevel(ctx context.Context, r Repo, loc string) (Result, error) {
 	}
 
 	if dw, err := r.LatestDistrictWarning(ctx, loc); err == nil {
+		res.used(dw.IssuedAt)
 		color := strings.ToLower(dw.Day1Color)
 		switch color {
 		case "red":
@@ -130,6 +164,7 @@ func riskLevel(ctx context.Context, r Repo, loc string) (Result, error) {
 	}
 
 	if qpf, err := r.LatestRiverBasinQPF(ctx, loc); err == nil {
+		res.used(qpf.FetchedAt)
 		day1, err := strconv.ParseFloat(qpf.Day1, 64)
 		if err == nil && day1 > 0 {
 			res.Score += 0.1
@@ -137,8 +172,9 @@ func riskLevel(ctx context.Context, r Repo, loc string) (Result, error) {
 		}
 	}
 
-	if aws, err := r.LatestAWSARG(ctx, loc); err == nil {
-		if aws.Rainfall > 5 {
+	if mm, endsAt, err := r.AWSRainfall(ctx, loc, rainfall.Window3H); err == nil {
+		res.used(endsAt)
+		if mm > 5 {
 			res.Score += 0.1
 			res.Breakdown["aws_arg_rainfall"] = 0.1
 		}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"testing"
 	"fmt"
+	"time"
 
 	"github.com/lolwierd/weatherboy/be/internal/model"
+	"github.com/lolwierd/weatherboy/be/internal/rainfall"
 )
 
 type stubRepo struct {
@@ -31,17 +33,17 @@ func (s stubRepo) LatestRadarSnapshot(ctx context.Context, loc string) (*model.R
 	}
 	return &model.RadarSnapshot{MaxDBZ: s.dbz, RangeKM: &s.rng}, nil
 }
-func (s stubRepo) NowcastPOP1H(ctx context.Context, loc string) (float64, error) {
+func (s stubRepo) NowcastPOP1H(ctx context.Context, loc string) (float64, time.Time, error) {
 	if s.pop == 0 {
-		return 0, context.Canceled
+		return 0, time.Time{}, context.Canceled
 	}
-	return s.pop, nil
+	return s.pop, time.Time{}, nil
 }
-func (s stubRepo) LatestNowcastCategories(ctx context.Context, loc string) (map[int]int16, error) {
+func (s stubRepo) LatestNowcastCategories(ctx context.Context, loc string) (map[int]int16, time.Time, error) {
 	if s.cats == nil {
-		return nil, context.Canceled
+		return nil, time.Time{}, context.Canceled
 	}
-	return s.cats, nil
+	return s.cats, time.Time{}, nil
 }
 func (s stubRepo) LatestDistrictWarning(ctx context.Context, loc string) (*model.DistrictWarning, error) {
 	if s.warn == "" {
@@ -55,11 +57,11 @@ func (s stubRepo) LatestRiverBasinQPF(ctx context.Context, loc string) (*model.R
 	}
 	return &model.RiverBasinQPF{Day1: fmt.Sprintf("%.2f", s.qpf)}, nil
 }
-func (s stubRepo) LatestAWSARG(ctx context.Context, loc string) (*model.AWSARG, error) {
+func (s stubRepo) AWSRainfall(ctx context.Context, loc string, window rainfall.Window) (float64, time.Time, error) {
 	if s.rainfall == 0 {
-		return nil, context.Canceled
+		return 0, time.Time{}, context.Canceled
 	}
-	return &model.AWSARG{Rainfall: s.rainfall}, nil
+	return s.rainfall, time.Time{}, nil
 }
 
 func TestRiskLevels(t *testing.T) {
@@ -85,3 +87,97 @@ func TestRiskLevels(t *testing.T) {
 		}
 	}
 }
+
+// datedRepo serves a nowcast, district warning and AWS/ARG rainfall of known
+// times, and nothing else.
+type datedRepo struct {
+	stubRepo
+	nowcast, warning, rain time.Time
+}
+
+func (d datedRepo) NowcastPOP1H(ctx context.Context, loc string) (float64, time.Time, error) {
+	return 0.8, d.nowcast, nil
+}
+func (d datedRepo) LatestDistrictWarning(ctx context.Context, loc string) (*model.DistrictWarning, error) {
+	return &model.DistrictWarning{IssuedAt: d.warning, Day1Color: "Yellow"}, nil
+}
+func (d datedRepo) AWSRainfall(ctx context.Context, loc string, window rainfall.Window) (float64, time.Time, error) {
+	return 1, d.rain, nil
+}
+
+func TestRiskLevelAsOf(t *testing.T) {
+	now := time.Date(2024, 6, 20, 12, 0, 0, 0, time.UTC)
+	r := datedRepo{nowcast: now.Add(-15 * time.Minute), warning: now.Add(-5 * time.Hour), rain: now.Add(-30 * time.Minute)}
+	got, err := riskLevel(context.Background(), r, "vadodara")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.AsOf.Equal(r.warning) {
+		t.Errorf("as of %v, want the district warning's %v", got.AsOf, r.warning)
+	}
+
+	got, _ = riskLevel(context.Background(), stubRepo{}, "vadodara")
+	if !got.AsOf.IsZero() {
+		t.Errorf("as of %v without inputs, want zero", got.AsOf)
+	}
+}
+
+func TestTransition(t *testing.T) {
+	cur := model.RiskHistory{Location: "vadodara", Level: "ORANGE", Score: 0.5}
+	if tr := transition(nil, cur); tr != nil {
+		t.Fatalf("expected no transition without previous level, got %+v", tr)
+	}
+	if tr := transition(&model.RiskHistory{Level: "ORANGE"}, cur); tr != nil {
+		t.Fatalf("expected no transition for same level, got %+v", tr)
+	}
+	tr := transition(&model.RiskHistory{Level: "YELLOW"}, cur)
+	if tr == nil || tr.FromLevel != "YELLOW" || tr.ToLevel != "ORANGE" {
+		t.Fatalf("unexpected transition: %+v", tr)
+	}
+}
+
+func TestAggregateAWSARG(t *testing.T) {
+	far, near := 10.0, 2.0
+	obs := []model.AWSStationObservation{
+		{Observation: model.AWSARG{StationID: "far", Rainfall: &far}, DistanceKM: 40},
+		{Observation: model.AWSARG{StationID: "near", Rainfall: &near}, DistanceKM: 5},
+		{Observation: model.AWSARG{StationID: "silent"}, DistanceKM: 1},
+	}
+	cases := []struct {
+		agg  Aggregation
+		want float64
+	}{
+		{AggMax, 10},
+		{AggMean, 6},
+		{AggIDW, (2.0/25 + 10.0/1600) / (1.0/25 + 1.0/1600)},
+	}
+	for _, tc := range cases {
+		got, err := AggregateAWSARG(obs, tc.agg)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.agg, err)
+		}
+		if got.StationID != "silent" {
+			t.Errorf("%s: expected identity of nearest station, got %s", tc.agg, got.StationID)
+		}
+		if got.Rainfall == nil {
+			t.Fatalf("%s: expected rainfall from reporting stations", tc.agg)
+		}
+		if diff := *got.Rainfall - tc.want; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("%s: want rainfall %f got %f", tc.agg, tc.want, *got.Rainfall)
+		}
+		if got.CurrentTemp != nil {
+			t.Errorf("%s: expected no temperature when no station reported it", tc.agg)
+		}
+	}
+}
+
+func TestBatchRiskLevels(t *testing.T) {
+	r := stubRepo{"", 0, 0, 0, nil, "Orange", 0, 0}
+	results, errs := riskLevels(context.Background(), r, []string{"vadodara", "pune", "vadodara", "atlantis"})
+	if len(results) != 2 || results["vadodara"].Level != "ORANGE" || results["pune"].Level != "ORANGE" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if len(errs) != 1 || errs["atlantis"] == nil {
+		t.Fatalf("expected only atlantis to fail, got %v", errs)
+	}
+}
@@ -10,15 +10,26 @@ import (
 	"time"
 
 	"github.com/lolwierd/weatherboy/be/internal/db"
+	"github.com/lolwierd/weatherboy/be/internal/events"
 	"github.com/lolwierd/weatherboy/be/internal/healthcheck"
 	"github.com/lolwierd/weatherboy/be/internal/logger"
 	"github.com/lolwierd/weatherboy/be/internal/opentelemetry"
 	"github.com/lolwierd/weatherboy/be/internal/router"
+	"github.com/lolwierd/weatherboy/be/internal/scheduler"
 )
 
 var WG sync.WaitGroup
 var IsShuttingDown bool
 
+var ctx, cancel = context.WithCancel(context.Background())
+
+// Context is cancelled once GracefulStop has drained traffic, so background
+// work started with it, e.g. listening for events, stops before the database
+// pool is closed.
+func Context() context.Context {
+	return ctx
+}
+
 // This function will handle graceful shutdown of the application in a proper order and blocks until SIGTERM
 func GracefulStop() {
 	sigChan := make(chan os.Signal, 1)
@@ -30,8 +41,18 @@ func GracefulStop() {
 	healthcheck.IsHealthy = false
 	time.Sleep(5 * time.Second)
 
+	//Stop background work and end event streams, which would otherwise keep
+	//their connections open
+	cancel()
+	events.Close()
+
 	//Close fiber connections
-	router.App.Shutdown()
+	if router.App != nil {
+		router.App.Shutdown()
+	}
+
+	//Stop scheduling and give up scheduler leadership
+	scheduler.Stop()
 
 	logger.Info.Println("Waiting for all goroutines to finish")
 	// Wait for all goroutines to complete.
@@ -0,0 +1,85 @@
+// Package storage keeps raw downloads such as bulletin PDFs in a blob store
+// shared by every replica, under content-addressed keys.
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/lolwierd/weatherboy/be/internal/config"
+	"github.com/lolwierd/weatherboy/be/internal/model"
+)
+
+// ErrNotFound is returned when no blob is stored under a key.
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore stores blobs under slash-separated keys.
+type BlobStore interface {
+	// Put stores data under key, replacing any blob stored there.
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get returns the blob stored under key, or ErrNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Exists reports whether a blob is stored under key.
+	Exists(ctx context.Context, key string) (bool, error)
+	// Delete removes the blob stored under key. Deleting a missing blob is
+	// not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Key returns the content-addressed key of data under prefix, along with its
+// hex SHA-256 checksum. The first two hex digits shard the keys so no
+// directory or listing grows unbounded.
+func Key(prefix string, data []byte, ext string) (key, sum string) {
+	h := sha256.Sum256(data)
+	sum = hex.EncodeToString(h[:])
+	return fmt.Sprintf("%s/%s/%s%s", prefix, sum[:2], sum, ext), sum
+}
+
+// NewBlob returns the record of data kept under its content-addressed key.
+func NewBlob(prefix, ext, contentType string, data []byte) model.Blob {
+	key, sum := Key(prefix, data, ext)
+	return model.Blob{Key: key, SHA256: sum, Size: int64(len(data)), ContentType: contentType}
+}
+
+// PutContent stores data under the key of its record b. Identical content
+// maps to the same key, so data already stored is not uploaded again.
+func PutContent(ctx context.Context, s BlobStore, b model.Blob, data []byte) error {
+	exists, err := s.Exists(ctx, b.Key)
+	if err != nil || exists {
+		return err
+	}
+	return s.Put(ctx, b.Key, data, b.ContentType)
+}
+
+var (
+	defaultOnce  sync.Once
+	defaultStore BlobStore
+	defaultErr   error
+)
+
+// Default returns the blob store selected by BLOB_STORE: "local" (the
+// default) keeps blobs under BLOB_DIR, "s3" in the S3_BUCKET of an
+// S3-compatible service.
+func Default() (BlobStore, error) {
+	defaultOnce.Do(func() {
+		config.LoadEnv()
+		switch config.BlobStore {
+		case "local":
+			dir := config.BlobDir
+			if dir == "" {
+				dir = filepath.Join(config.DataDir, "blobs")
+			}
+			defaultStore = NewLocal(dir)
+		case "s3":
+			defaultStore, defaultErr = NewS3(config.S3Endpoint, config.S3Region, config.S3Bucket, config.S3AccessKeyID, config.S3SecretAccessKey)
+		default:
+			defaultErr = fmt.Errorf("unknown BLOB_STORE %q, want local or s3", config.BlobStore)
+		}
+	})
+	return defaultStore, defaultErr
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestKey(t *testing.T) {
+	key, sum := Key("bulletin", []byte("hello"), ".pdf")
+	const want = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	if sum != want {
+		t.Fatalf("sum = %s, want %s", sum, want)
+	}
+	if key != "bulletin/2c/"+want+".pdf" {
+		t.Fatalf("key = %s", key)
+	}
+}
+
+// testStore puts, reads, checks and deletes a blob through s.
+func testStore(t *testing.T, s BlobStore) {
+	t.Helper()
+	ctx := context.Background()
+	data := []byte("%PDF-1.4 bulletin")
+
+	b := NewBlob("bulletin", ".pdf", "application/pdf", data)
+	if b.Size != int64(len(data)) || !strings.HasSuffix(b.Key, b.SHA256+".pdf") {
+		t.Fatalf("blob = %+v", b)
+	}
+	if err := PutContent(ctx, s, b, data); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if err := PutContent(ctx, s, b, data); err != nil {
+		t.Fatalf("put again: %v", err)
+	}
+
+	got, err := s.Get(ctx, b.Key)
+	if err != nil || string(got) != string(data) {
+		t.Fatalf("get = %q, %v", got, err)
+	}
+	if ok, err := s.Exists(ctx, b.Key); err != nil || !ok {
+		t.Fatalf("exists = %v, %v", ok, err)
+	}
+
+	if err := s.Delete(ctx, b.Key); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := s.Get(ctx, b.Key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("get deleted: %v, want ErrNotFound", err)
+	}
+	if ok, err := s.Exists(ctx, b.Key); err != nil || ok {
+		t.Fatalf("exists deleted = %v, %v", ok, err)
+	}
+	if err := s.Delete(ctx, b.Key); err != nil {
+		t.Fatalf("delete missing: %v", err)
+	}
+}
+
+func TestLocal(t *testing.T) {
+	testStore(t, NewLocal(t.TempDir()))
+}
+
+func TestLocalRejectsEscapingKeys(t *testing.T) {
+	l := NewLocal(t.TempDir())
+	for _, key := range []string{"", "..", "../etc/passwd", "/etc/passwd", "a/../../b", "a//b"} {
+		if err := l.Put(context.Background(), key, []byte("x"), ""); err == nil {
+			t.Errorf("put %q: want an error", key)
+		}
+	}
+}
@@ -0,0 +1,2 @@
+DROP TABLE risk_transition;
+DROP TABLE risk_history;
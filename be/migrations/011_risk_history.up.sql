@@ -0,0 +1,21 @@
+CREATE TABLE risk_history (
+    id SERIAL PRIMARY KEY,
+    location VARCHAR(255) NOT NULL,
+    level VARCHAR(16) NOT NULL,
+    score NUMERIC NOT NULL,
+    breakdown JSONB NOT NULL,
+    computed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX risk_history_location_computed_at_idx ON risk_history (location, computed_at);
+
+CREATE TABLE risk_transition (
+    id SERIAL PRIMARY KEY,
+    location VARCHAR(255) NOT NULL,
+    from_level VARCHAR(16) NOT NULL,
+    to_level VARCHAR(16) NOT NULL,
+    score NUMERIC NOT NULL,
+    transitioned_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX risk_transition_location_transitioned_at_idx ON risk_transition (location, transitioned_at);
@@ -0,0 +1,3 @@
+DROP INDEX river_basin_qpf_basin_id_sub_basin_id_idx;
+UPDATE river_basin_qpf SET basin_id = sub_basin_id;
+ALTER TABLE river_basin_qpf DROP COLUMN sub_basin_id;
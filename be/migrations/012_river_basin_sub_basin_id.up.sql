@@ -0,0 +1,9 @@
+ALTER TABLE river_basin_qpf ADD COLUMN sub_basin_id INT;
+
+-- basin_id used to hold the per-row Obj_Id. Move it to sub_basin_id and
+-- restore the queried basin id, which was always 1 until now.
+UPDATE river_basin_qpf SET sub_basin_id = basin_id, basin_id = 1;
+
+ALTER TABLE river_basin_qpf ALTER COLUMN sub_basin_id SET NOT NULL;
+
+CREATE INDEX river_basin_qpf_basin_id_sub_basin_id_idx ON river_basin_qpf (basin_id, sub_basin_id);
@@ -0,0 +1,18 @@
+CREATE TABLE aws_station (
+    station_id VARCHAR(255) PRIMARY KEY,
+    call_sign VARCHAR(255) NOT NULL,
+    station_name VARCHAR(255) NOT NULL,
+    district VARCHAR(255) NOT NULL,
+    state VARCHAR(255) NOT NULL,
+    latitude NUMERIC NOT NULL,
+    longitude NUMERIC NOT NULL,
+    first_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO aws_station (station_id, call_sign, station_name, district, state, latitude, longitude, first_seen_at, last_seen_at)
+SELECT DISTINCT ON (station_id)
+       station_id, call_sign, station_name, district, state, latitude, longitude,
+       MIN(fetched_at) OVER (PARTITION BY station_id), fetched_at
+FROM aws_arg
+ORDER BY station_id, fetched_at DESC;
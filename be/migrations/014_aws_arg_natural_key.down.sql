@@ -0,0 +1 @@
+ALTER TABLE aws_arg DROP CONSTRAINT aws_arg_station_id_date_time_key;
@@ -0,0 +1,9 @@
+-- Keep only the most recently fetched row per station observation.
+DELETE FROM aws_arg a
+USING aws_arg b
+WHERE a.station_id = b.station_id
+  AND a.date = b.date
+  AND a.time = b.time
+  AND (a.fetched_at, a.id) < (b.fetched_at, b.id);
+
+ALTER TABLE aws_arg ADD CONSTRAINT aws_arg_station_id_date_time_key UNIQUE (station_id, date, time);
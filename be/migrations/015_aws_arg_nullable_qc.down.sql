@@ -0,0 +1,27 @@
+ALTER TABLE aws_arg DROP COLUMN qc;
+
+UPDATE aws_arg SET
+    current_temp = COALESCE(current_temp, 0),
+    dew_point_temp = COALESCE(dew_point_temp, 0),
+    rh = COALESCE(rh, 0),
+    wind_direction = COALESCE(wind_direction, 0),
+    wind_speed = COALESCE(wind_speed, 0),
+    mslp = COALESCE(mslp, 0),
+    min_temp = COALESCE(min_temp, 0),
+    max_temp = COALESCE(max_temp, 0),
+    nebulosity = COALESCE(nebulosity, 0),
+    feel_like = COALESCE(feel_like, 0),
+    rainfall = COALESCE(rainfall, 0);
+
+ALTER TABLE aws_arg
+    ALTER COLUMN current_temp SET NOT NULL,
+    ALTER COLUMN dew_point_temp SET NOT NULL,
+    ALTER COLUMN rh SET NOT NULL,
+    ALTER COLUMN wind_direction SET NOT NULL,
+    ALTER COLUMN wind_speed SET NOT NULL,
+    ALTER COLUMN mslp SET NOT NULL,
+    ALTER COLUMN min_temp SET NOT NULL,
+    ALTER COLUMN max_temp SET NOT NULL,
+    ALTER COLUMN nebulosity SET NOT NULL,
+    ALTER COLUMN feel_like SET NOT NULL,
+    ALTER COLUMN rainfall SET NOT NULL;
@@ -0,0 +1,16 @@
+ALTER TABLE aws_arg
+    ALTER COLUMN current_temp DROP NOT NULL,
+    ALTER COLUMN dew_point_temp DROP NOT NULL,
+    ALTER COLUMN rh DROP NOT NULL,
+    ALTER COLUMN wind_direction DROP NOT NULL,
+    ALTER COLUMN wind_speed DROP NOT NULL,
+    ALTER COLUMN mslp DROP NOT NULL,
+    ALTER COLUMN min_temp DROP NOT NULL,
+    ALTER COLUMN max_temp DROP NOT NULL,
+    ALTER COLUMN nebulosity DROP NOT NULL,
+    ALTER COLUMN feel_like DROP NOT NULL,
+    ALTER COLUMN rainfall DROP NOT NULL,
+    ADD COLUMN qc JSONB NOT NULL DEFAULT '{}';
+
+-- feel_like was never parsed and stored as 0.
+UPDATE aws_arg SET feel_like = NULL WHERE feel_like = 0;
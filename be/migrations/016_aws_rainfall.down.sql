@@ -0,0 +1 @@
+DROP TABLE aws_rainfall;
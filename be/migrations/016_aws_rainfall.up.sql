@@ -0,0 +1,10 @@
+CREATE TABLE aws_rainfall (
+    id SERIAL PRIMARY KEY,
+    station_id VARCHAR(255) NOT NULL,
+    "window" VARCHAR(16) NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    mm NUMERIC NOT NULL,
+    readings INT NOT NULL,
+    computed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (station_id, "window", ends_at)
+);
@@ -0,0 +1,12 @@
+CREATE TABLE job_run (
+    id SERIAL PRIMARY KEY,
+    job_name VARCHAR(255) NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ,
+    status VARCHAR(16) NOT NULL,
+    error TEXT,
+    bytes BIGINT NOT NULL DEFAULT 0,
+    rows_written BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX job_run_job_name_started_at_idx ON job_run (job_name, started_at DESC);
@@ -0,0 +1,6 @@
+ALTER TABLE bulletin_parsed DROP CONSTRAINT bulletin_parsed_bulletin_raw_id_location_key;
+ALTER TABLE bulletin_parsed DROP COLUMN parser_version;
+ALTER TABLE district_warning DROP COLUMN parser_version;
+ALTER TABLE district_warning DROP COLUMN district_warning_raw_id;
+ALTER TABLE nowcast DROP COLUMN parser_version;
+ALTER TABLE nowcast DROP COLUMN nowcast_raw_id;
@@ -0,0 +1,46 @@
+-- Link derived rows to the raw payload they were parsed from and record the
+-- parser version, so raw payloads can be reprocessed after a parser fix.
+-- Existing rows keep version 0 (unknown).
+ALTER TABLE nowcast ADD COLUMN nowcast_raw_id INT REFERENCES nowcast_raw(id);
+ALTER TABLE nowcast ADD COLUMN parser_version INT NOT NULL DEFAULT 0;
+ALTER TABLE district_warning ADD COLUMN district_warning_raw_id INT REFERENCES district_warning_raw(id);
+ALTER TABLE district_warning ADD COLUMN parser_version INT NOT NULL DEFAULT 0;
+ALTER TABLE bulletin_parsed ADD COLUMN parser_version INT NOT NULL DEFAULT 0;
+
+-- Fetchers store the raw payload right before the derived row, so link each
+-- existing row to the closest raw payload of its location fetched up to a
+-- minute before it.
+UPDATE nowcast n
+SET nowcast_raw_id = m.raw_id
+FROM (
+    SELECT DISTINCT ON (r.id) r.id AS raw_id, n.id AS nowcast_id
+    FROM nowcast_raw r
+    JOIN nowcast n ON n.location = r.location
+                  AND n.created_at >= r.fetched_at
+                  AND n.created_at < r.fetched_at + INTERVAL '1 minute'
+    ORDER BY r.id, n.created_at
+) m
+WHERE n.id = m.nowcast_id;
+
+UPDATE district_warning d
+SET district_warning_raw_id = m.raw_id
+FROM (
+    SELECT DISTINCT ON (r.id) r.id AS raw_id, d.id AS warning_id
+    FROM district_warning_raw r
+    JOIN district_warning d ON d.location = r.location
+                           AND d.created_at >= r.fetched_at
+                           AND d.created_at < r.fetched_at + INTERVAL '1 minute'
+    ORDER BY r.id, d.created_at
+) m
+WHERE d.id = m.warning_id;
+
+ALTER TABLE nowcast ADD CONSTRAINT nowcast_nowcast_raw_id_key UNIQUE (nowcast_raw_id);
+ALTER TABLE district_warning ADD CONSTRAINT district_warning_district_warning_raw_id_key UNIQUE (district_warning_raw_id);
+
+DELETE FROM bulletin_parsed a
+USING bulletin_parsed b
+WHERE a.bulletin_raw_id = b.bulletin_raw_id
+  AND a.location = b.location
+  AND a.id < b.id;
+
+ALTER TABLE bulletin_parsed ADD CONSTRAINT bulletin_parsed_bulletin_raw_id_location_key UNIQUE (bulletin_raw_id, location);
@@ -0,0 +1,6 @@
+ALTER TABLE bulletin_raw DROP CONSTRAINT bulletin_raw_path_key;
+ALTER TABLE river_basin_qpf DROP CONSTRAINT river_basin_qpf_basin_id_sub_basin_id_date_key;
+CREATE INDEX river_basin_qpf_basin_id_sub_basin_id_idx ON river_basin_qpf (basin_id, sub_basin_id);
+ALTER TABLE district_warning DROP CONSTRAINT district_warning_location_issued_at_key;
+ALTER TABLE nowcast_category DROP CONSTRAINT nowcast_category_nowcast_id_category_key;
+ALTER TABLE nowcast DROP CONSTRAINT nowcast_location_captured_at_key;
@@ -0,0 +1,75 @@
+-- Retries and restarts stored the same observation more than once. Keep the
+-- most recently stored row per natural key, then enforce the key so fetchers
+-- can upsert.
+DELETE FROM nowcast_category c
+USING nowcast a, nowcast b
+WHERE c.nowcast_id = a.id
+  AND a.location = b.location
+  AND a.captured_at = b.captured_at
+  AND (a.created_at, a.id) < (b.created_at, b.id);
+
+DELETE FROM nowcast a
+USING nowcast b
+WHERE a.location = b.location
+  AND a.captured_at = b.captured_at
+  AND (a.created_at, a.id) < (b.created_at, b.id);
+
+ALTER TABLE nowcast ADD CONSTRAINT nowcast_location_captured_at_key UNIQUE (location, captured_at);
+
+DELETE FROM nowcast_category a
+USING nowcast_category b
+WHERE a.nowcast_id = b.nowcast_id
+  AND a.category = b.category
+  AND a.id < b.id;
+
+ALTER TABLE nowcast_category ADD CONSTRAINT nowcast_category_nowcast_id_category_key UNIQUE (nowcast_id, category);
+
+DELETE FROM district_warning a
+USING district_warning b
+WHERE a.location = b.location
+  AND a.issued_at = b.issued_at
+  AND (a.created_at, a.id) < (b.created_at, b.id);
+
+ALTER TABLE district_warning ADD CONSTRAINT district_warning_location_issued_at_key UNIQUE (location, issued_at);
+
+DELETE FROM river_basin_qpf a
+USING river_basin_qpf b
+WHERE a.basin_id = b.basin_id
+  AND a.sub_basin_id = b.sub_basin_id
+  AND a.date = b.date
+  AND (a.fetched_at, a.id) < (b.fetched_at, b.id);
+
+DROP INDEX river_basin_qpf_basin_id_sub_basin_id_idx;
+ALTER TABLE river_basin_qpf ADD CONSTRAINT river_basin_qpf_basin_id_sub_basin_id_date_key UNIQUE (basin_id, sub_basin_id, date);
+
+-- A bulletin re-downloaded on the same day overwrites the same file. Move the
+-- parsed rows to the latest raw row of each path, keeping the latest parsed
+-- row per location, before dropping the older raw rows.
+DELETE FROM bulletin_parsed p
+USING (
+    SELECT bp.id, row_number() OVER (
+        PARTITION BY r.path, bp.location
+        ORDER BY r.fetched_at DESC, r.id DESC, bp.fetched_at DESC, bp.id DESC
+    ) AS n
+    FROM bulletin_parsed bp
+    JOIN bulletin_raw r ON r.id = bp.bulletin_raw_id
+) ranked
+WHERE p.id = ranked.id AND ranked.n > 1;
+
+UPDATE bulletin_parsed p
+SET bulletin_raw_id = latest.id
+FROM bulletin_raw r, (
+    SELECT DISTINCT ON (path) id, path
+    FROM bulletin_raw
+    ORDER BY path, fetched_at DESC, id DESC
+) latest
+WHERE p.bulletin_raw_id = r.id
+  AND r.path = latest.path
+  AND r.id <> latest.id;
+
+DELETE FROM bulletin_raw a
+USING bulletin_raw b
+WHERE a.path = b.path
+  AND (a.fetched_at, a.id) < (b.fetched_at, b.id);
+
+ALTER TABLE bulletin_raw ADD CONSTRAINT bulletin_raw_path_key UNIQUE (path);
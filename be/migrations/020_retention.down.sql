@@ -0,0 +1,19 @@
+DROP INDEX radar_snapshot_captured_at_idx;
+DROP INDEX nowcast_captured_at_idx;
+DROP INDEX aws_arg_fetched_at_idx;
+DROP INDEX job_run_started_at_idx;
+DROP INDEX imd_api_log_requested_at_idx;
+DROP INDEX district_warning_raw_fetched_at_idx;
+DROP INDEX nowcast_raw_fetched_at_idx;
+
+ALTER TABLE district_warning DROP CONSTRAINT district_warning_district_warning_raw_id_fkey;
+ALTER TABLE district_warning ADD CONSTRAINT district_warning_district_warning_raw_id_fkey
+    FOREIGN KEY (district_warning_raw_id) REFERENCES district_warning_raw(id);
+
+ALTER TABLE nowcast DROP CONSTRAINT nowcast_nowcast_raw_id_fkey;
+ALTER TABLE nowcast ADD CONSTRAINT nowcast_nowcast_raw_id_fkey
+    FOREIGN KEY (nowcast_raw_id) REFERENCES nowcast_raw(id);
+
+ALTER TABLE nowcast_category DROP CONSTRAINT nowcast_category_nowcast_id_fkey;
+ALTER TABLE nowcast_category ADD CONSTRAINT nowcast_category_nowcast_id_fkey
+    FOREIGN KEY (nowcast_id) REFERENCES nowcast(id);
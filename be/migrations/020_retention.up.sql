@@ -0,0 +1,21 @@
+-- Let retention delete raw payloads and downsample nowcasts without first
+-- clearing the rows that reference them.
+ALTER TABLE nowcast_category DROP CONSTRAINT nowcast_category_nowcast_id_fkey;
+ALTER TABLE nowcast_category ADD CONSTRAINT nowcast_category_nowcast_id_fkey
+    FOREIGN KEY (nowcast_id) REFERENCES nowcast(id) ON DELETE CASCADE;
+
+ALTER TABLE nowcast DROP CONSTRAINT nowcast_nowcast_raw_id_fkey;
+ALTER TABLE nowcast ADD CONSTRAINT nowcast_nowcast_raw_id_fkey
+    FOREIGN KEY (nowcast_raw_id) REFERENCES nowcast_raw(id) ON DELETE SET NULL;
+
+ALTER TABLE district_warning DROP CONSTRAINT district_warning_district_warning_raw_id_fkey;
+ALTER TABLE district_warning ADD CONSTRAINT district_warning_district_warning_raw_id_fkey
+    FOREIGN KEY (district_warning_raw_id) REFERENCES district_warning_raw(id) ON DELETE SET NULL;
+
+CREATE INDEX nowcast_raw_fetched_at_idx ON nowcast_raw (fetched_at);
+CREATE INDEX district_warning_raw_fetched_at_idx ON district_warning_raw (fetched_at);
+CREATE INDEX imd_api_log_requested_at_idx ON imd_api_log (requested_at);
+CREATE INDEX job_run_started_at_idx ON job_run (started_at);
+CREATE INDEX aws_arg_fetched_at_idx ON aws_arg (fetched_at);
+CREATE INDEX nowcast_captured_at_idx ON nowcast (captured_at);
+CREATE INDEX radar_snapshot_captured_at_idx ON radar_snapshot (captured_at);
@@ -0,0 +1,2 @@
+ALTER TABLE bulletin_raw DROP COLUMN blob_key;
+DROP TABLE blob;
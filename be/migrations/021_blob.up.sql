@@ -0,0 +1,12 @@
+-- Raw downloads live in a blob store shared by every replica instead of the
+-- local data directory. Bulletins fetched before keep a NULL blob_key and
+-- their PDF at path.
+CREATE TABLE blob (
+    key TEXT PRIMARY KEY,
+    sha256 CHAR(64) NOT NULL,
+    size BIGINT NOT NULL,
+    content_type TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE bulletin_raw ADD COLUMN blob_key TEXT REFERENCES blob(key);
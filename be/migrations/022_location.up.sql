@@ -0,0 +1,27 @@
+-- Locations are managed through the admin API instead of being compiled in.
+-- The table is seeded with the locations served so far.
+CREATE TABLE location (
+    slug VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    lat NUMERIC NOT NULL,
+    lon NUMERIC NOT NULL,
+    district_id INT NOT NULL DEFAULT 0,
+    bulletin_product VARCHAR(255) NOT NULL DEFAULT '',
+    radar_codes TEXT[] NOT NULL DEFAULT '{}',
+    river_basin_id INT NOT NULL DEFAULT 0,
+    sub_basin_id INT NOT NULL DEFAULT 0,
+    aws_station_ids TEXT[] NOT NULL DEFAULT '{}',
+    aws_nearest_k INT NOT NULL DEFAULT 0,
+    aws_radius_km NUMERIC NOT NULL DEFAULT 0,
+    aws_state VARCHAR(255) NOT NULL DEFAULT '',
+    aws_district VARCHAR(255) NOT NULL DEFAULT '',
+    timezone VARCHAR(64) NOT NULL DEFAULT 'Asia/Kolkata',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO location (slug, name, lat, lon, district_id, bulletin_product, radar_codes, river_basin_id, aws_station_ids, aws_state) VALUES
+    ('vadodara', 'Vadodara', 22.30, 73.20, 244, 'gujarat.pdf', '{baroda,ahmedabad}', 1, '{NDL}', 'GUJARAT'),
+    ('mumbai', 'Mumbai', 19.08, 72.88, 0, 'maharashtra.pdf', '{mumbai}', 0, '{}', 'MAHARASHTRA'),
+    ('thane', 'Thane', 19.22, 72.97, 0, 'maharashtra.pdf', '{mumbai}', 0, '{}', 'MAHARASHTRA'),
+    ('pune', 'Pune', 18.52, 73.85, 0, 'maharashtra.pdf', '{mumbai}', 0, '{}', 'MAHARASHTRA');
@@ -0,0 +1,4 @@
+ALTER TABLE district_warning_raw DROP COLUMN parser_version;
+ALTER TABLE district_warning_raw DROP COLUMN district_warning_id;
+ALTER TABLE nowcast_raw DROP COLUMN parser_version;
+ALTER TABLE nowcast_raw DROP COLUMN nowcast_id;
@@ -0,0 +1,22 @@
+-- A parsed row links only the first raw payload it was parsed from, so later
+-- payloads merged into the same row looked unparsed. Record on every raw
+-- payload the row it was parsed into and the parser version that did it.
+-- Payloads parsed before this are linked through their parsed row where it
+-- points back at them; the rest are reparsed once by the next reprocess.
+ALTER TABLE nowcast_raw ADD COLUMN nowcast_id INT REFERENCES nowcast(id) ON DELETE SET NULL;
+ALTER TABLE nowcast_raw ADD COLUMN parser_version INT;
+ALTER TABLE district_warning_raw ADD COLUMN district_warning_id INT REFERENCES district_warning(id) ON DELETE SET NULL;
+ALTER TABLE district_warning_raw ADD COLUMN parser_version INT;
+-- Downsampling deletes parsed rows, clearing the links to them.
+CREATE INDEX nowcast_raw_nowcast_id_idx ON nowcast_raw (nowcast_id);
+CREATE INDEX district_warning_raw_district_warning_id_idx ON district_warning_raw (district_warning_id);
+
+UPDATE nowcast_raw r
+SET nowcast_id = n.id, parser_version = n.parser_version
+FROM nowcast n
+WHERE n.nowcast_raw_id = r.id;
+
+UPDATE district_warning_raw r
+SET district_warning_id = d.id, parser_version = d.parser_version
+FROM district_warning d
+WHERE d.district_warning_raw_id = r.id;
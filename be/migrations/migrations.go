@@ -0,0 +1,10 @@
+// Package migrations embeds the numbered SQL migrations so the binary can
+// apply them itself.
+package migrations
+
+import "embed"
+
+// FS holds every `NNN_name.up.sql` and `NNN_name.down.sql` file.
+//
+//go:embed *.sql
+var FS embed.FS